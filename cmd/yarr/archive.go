@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nkanaev/yarr/src/server/archive"
+	"github.com/nkanaev/yarr/src/storage"
+)
+
+const archiveUsage = `Usage of archive:
+  archive export [-user USERNAME] -out FILE
+  archive import [-user USERNAME] FILE`
+
+// runArchive exports or imports the account archive of a user.
+func runArchive(db storage.Storage, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", archiveUsage)
+	}
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("archive "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	username := flags.String("user", "", "`username` of the account (defaults to the -auth user)")
+	var path string
+	if command == "export" {
+		flags.StringVar(&path, "out", "", "archive file `path`")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username != "" {
+		user, err := db.GetUserByName(*username)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("user %q not found", *username)
+		}
+		db = storage.ForUser(db, user.Id)
+	}
+
+	switch command {
+	case "export":
+		if path == "" {
+			return fmt.Errorf("archive path missing")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		if err = archive.Export(db, w); err == nil {
+			err = w.Flush()
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		fmt.Fprintf(out, "account exported to %s\n", path)
+	case "import":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("archive path missing")
+		}
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		result, err := archive.Import(db, f, info.Size())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(
+			out, "imported %d folders, %d feeds, %d items (%d updated)\n",
+			result.Folders, result.Feeds, result.Items, result.UpdatedItems,
+		)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, archiveUsage)
+	}
+	return nil
+}
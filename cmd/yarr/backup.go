@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+func backupConfig(dir, keep, interval string) (worker.BackupConfig, error) {
+	var cfg worker.BackupConfig
+	n, err := strconv.Atoi(keep)
+	if err != nil || n < 0 {
+		return cfg, fmt.Errorf("invalid number of backups to keep: %q", keep)
+	}
+	every, err := time.ParseDuration(interval)
+	if err != nil || every <= 0 {
+		return cfg, fmt.Errorf("invalid interval: %q", interval)
+	}
+	cfg.Dir = dir
+	cfg.Keep = n
+	cfg.Interval = every
+	return cfg, nil
+}
+
+// runBackup writes a backup of the database to a file, or to a
+// directory under a name with the current time.
+func runBackup(db storage.Storage, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(out)
+	path := flags.String("out", ".", "backup file or directory `path`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if info, err := os.Stat(*path); err == nil && info.IsDir() {
+		*path = filepath.Join(*path, storage.BackupName(db, time.Now()))
+	}
+	if err := storage.Backup(db, *path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "database backed up to %s\n", *path)
+	return nil
+}
+
+// runRestore replaces the content of the database with a backup.
+func runRestore(db storage.Storage, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(out)
+	force := flags.Bool("force", false, "replace the existing data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: restore [-force] FILE")
+	}
+
+	counts, err := storage.Restore(db, flags.Arg(0), *force)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tROWS")
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", c.Table, c.Rows)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "backup restored")
+	return nil
+}
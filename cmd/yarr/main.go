@@ -8,7 +8,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nkanaev/yarr/src/platform"
 	"github.com/nkanaev/yarr/src/server"
@@ -44,10 +46,40 @@ func parseAuthfile(authfile io.Reader) (username, password string, err error) {
 	return username, password, nil
 }
 
+func mediaConfig(dir, maxSize, maxFiles, retention string) (worker.MediaConfig, error) {
+	var cfg worker.MediaConfig
+	megabytes, err := strconv.ParseInt(maxSize, 10, 64)
+	if err != nil || megabytes < 0 {
+		return cfg, fmt.Errorf("invalid max size: %q", maxSize)
+	}
+	files, err := strconv.Atoi(maxFiles)
+	if err != nil || files < 0 {
+		return cfg, fmt.Errorf("invalid max files: %q", maxFiles)
+	}
+	days, err := strconv.Atoi(retention)
+	if err != nil || days < 0 {
+		return cfg, fmt.Errorf("invalid retention: %q", retention)
+	}
+	if dir == "" {
+		configPath, err := os.UserConfigDir()
+		if err != nil {
+			return cfg, err
+		}
+		dir = filepath.Join(configPath, "yarr", "media")
+	}
+	cfg.Dir = dir
+	cfg.MaxSize = megabytes << 20
+	cfg.MaxFiles = files
+	cfg.Retention = time.Duration(days) * 24 * time.Hour
+	return cfg, nil
+}
+
 func main() {
 	platform.FixConsoleIfNeeded()
 
-	var addr, db, authfile, auth, certfile, keyfile, basepath, logfile string
+	var addr, db, authfile, auth, certfile, keyfile, basepath, publicurl, logfile string
+	var mediadir, mediamaxsize, mediamaxfiles, mediaretention string
+	var backupdir, backupkeep, backupinterval string
 	var ver, open bool
 
 	flag.CommandLine.SetOutput(os.Stdout)
@@ -56,18 +88,32 @@ func main() {
 		out := flag.CommandLine.Output()
 		fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
 		flag.PrintDefaults()
+		fmt.Fprintln(out, "\nCommands:")
+		fmt.Fprintln(out, "  token       manage API tokens (see `token` without arguments)")
+		fmt.Fprintln(out, "  migrate-db  copy all the data to another database: migrate-db -from PATH|URL -to PATH|URL")
+		fmt.Fprintln(out, "  backup      back up the database: backup [-out PATH]")
+		fmt.Fprintln(out, "  restore     load a backup into the database: restore [-force] FILE")
+		fmt.Fprintln(out, "  archive     export or import the account of a user (see `archive` without arguments)")
 		fmt.Fprintln(out, "\nThe environmental variables, if present, will be used to provide\nthe default values for the params above:")
 		fmt.Fprintln(out, " ", strings.Join(OptList, ", "))
 	}
 
 	flag.StringVar(&addr, "addr", opt("YARR_ADDR", "127.0.0.1:7070"), "address to run server on")
 	flag.StringVar(&basepath, "base", opt("YARR_BASE", ""), "base path of the service url")
+	flag.StringVar(&publicurl, "public-url", opt("YARR_PUBLIC_URL", ""), "public `url` of the service (including the base path), enables WebSub push subscriptions")
 	flag.StringVar(&authfile, "auth-file", opt("YARR_AUTHFILE", ""), "`path` to a file containing username:password. Takes precedence over --auth (or YARR_AUTH)")
 	flag.StringVar(&auth, "auth", opt("YARR_AUTH", ""), "string with username and password in the format `username:password`")
 	flag.StringVar(&certfile, "cert-file", opt("YARR_CERTFILE", ""), "`path` to cert file for https")
 	flag.StringVar(&keyfile, "key-file", opt("YARR_KEYFILE", ""), "`path` to key file for https")
 	flag.StringVar(&db, "db", opt("YARR_DB", ""), "storage file `path`")
 	flag.StringVar(&logfile, "log-file", opt("YARR_LOGFILE", ""), "`path` to log file to use instead of stdout")
+	flag.StringVar(&mediadir, "media-dir", opt("YARR_MEDIA_DIR", ""), "`path` to the directory for downloaded enclosures (default: next to the config)")
+	flag.StringVar(&mediamaxsize, "media-max-size", opt("YARR_MEDIA_MAX_SIZE", "0"), "total size of downloaded enclosures in `megabytes` (0 for no limit)")
+	flag.StringVar(&mediamaxfiles, "media-max-files", opt("YARR_MEDIA_MAX_FILES", "0"), "`number` of downloaded enclosures to keep (0 for no limit)")
+	flag.StringVar(&mediaretention, "media-retention", opt("YARR_MEDIA_RETENTION", "0"), "`days` to keep downloaded enclosures for (0 for no limit)")
+	flag.StringVar(&backupdir, "backup-dir", opt("YARR_BACKUP_DIR", ""), "`path` to the directory for periodic backups of the database (disabled if empty)")
+	flag.StringVar(&backupkeep, "backup-keep", opt("YARR_BACKUP_KEEP", "7"), "`number` of periodic backups to keep (0 for all)")
+	flag.StringVar(&backupinterval, "backup-interval", opt("YARR_BACKUP_INTERVAL", "24h"), "`duration` between periodic backups")
 	flag.BoolVar(&ver, "version", false, "print application version")
 	flag.BoolVar(&open, "open", false, "open the server in browser")
 	flag.Parse()
@@ -89,6 +135,13 @@ func main() {
 		log.SetOutput(os.Stdout)
 	}
 
+	if flag.Arg(0) == "migrate-db" {
+		if err := runMigrateDB(flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if open && strings.HasPrefix(addr, "unix:") {
 		log.Fatal("Cannot open ", addr, " in browser")
 	}
@@ -108,8 +161,16 @@ func main() {
 
 	log.Printf("using db file %s", db)
 
+	media, err := mediaConfig(mediadir, mediamaxsize, mediamaxfiles, mediaretention)
+	if err != nil {
+		log.Fatal("Invalid media options: ", err)
+	}
+	backup, err := backupConfig(backupdir, backupkeep, backupinterval)
+	if err != nil {
+		log.Fatal("Invalid backup options: ", err)
+	}
+
 	var username, password string
-	var err error
 	if authfile != "" {
 		f, err := os.Open(authfile)
 		if err != nil {
@@ -136,12 +197,28 @@ func main() {
 		log.Fatal("Failed to initialise database: ", err)
 	}
 
+	commands := map[string]func(storage.Storage, []string, io.Writer) error{
+		"token":   runToken,
+		"backup":  runBackup,
+		"restore": runRestore,
+		"archive": runArchive,
+	}
+	if command, ok := commands[flag.Arg(0)]; ok {
+		if err := command(store, flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	worker.SetVersion(Version)
 	srv := server.NewServer(store, addr)
 
 	if basepath != "" {
 		srv.BasePath = "/" + strings.Trim(basepath, "/")
 	}
+	srv.PublicURL = publicurl
+	srv.Media = media
+	srv.Backup = backup
 
 	if certfile != "" && keyfile != "" {
 		srv.CertFile = certfile
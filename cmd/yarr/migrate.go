@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/nkanaev/yarr/src/storage"
+)
+
+// runMigrateDB copies the whole database to another one, possibly
+// of the other backend (sqlite file path or postgres url).
+func runMigrateDB(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate-db", flag.ContinueOnError)
+	flags.SetOutput(out)
+	from := flags.String("from", "", "storage file `path` or postgres url to copy from")
+	to := flags.String("to", "", "storage file `path` or postgres url to copy to (must be empty)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("both -from and -to are required")
+	}
+	if *from == *to {
+		return errors.New("-from and -to must be different databases")
+	}
+
+	src, err := storage.New(*from)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", *from, err)
+	}
+	defer src.Close()
+	dst, err := storage.New(*to)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", *to, err)
+	}
+	defer dst.Close()
+
+	counts, err := storage.Copy(dst, src)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tROWS")
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", c.Table, c.Rows)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "all rows copied")
+	return nil
+}
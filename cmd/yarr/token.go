@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+const tokenUsage = `Usage of token:
+  token create -name NAME [-user USERNAME] [-read-only] [-expires DURATION]
+  token list [-user USERNAME]
+  token revoke [-user USERNAME] ID`
+
+// runToken manages the API tokens of a user from the command line.
+func runToken(db storage.Storage, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", tokenUsage)
+	}
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("token "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	username := flags.String("user", "", "`username` of the token owner (defaults to the -auth user)")
+	var name string
+	var readOnly bool
+	var expires time.Duration
+	if command == "create" {
+		flags.StringVar(&name, "name", "", "token `name`")
+		flags.BoolVar(&readOnly, "read-only", false, "only allow requests that don't modify anything")
+		flags.DurationVar(&expires, "expires", 0, "token lifetime `duration` (e.g. 720h), never expires if omitted")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username != "" {
+		user, err := db.GetUserByName(*username)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("user %q not found", *username)
+		}
+		db = storage.ForUser(db, user.Id)
+	}
+
+	switch command {
+	case "create":
+		if name == "" {
+			return fmt.Errorf("token name missing")
+		}
+		params := model.CreateAPIKeyParams{Name: name, ReadOnly: readOnly}
+		if expires > 0 {
+			expiresAt := time.Now().Add(expires)
+			params.ExpiresAt = &expiresAt
+		}
+		token, hash := auth.NewToken()
+		params.TokenHash = hash
+		key, err := db.CreateAPIKey(params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created token %d (%s):\n%s\n", key.Id, key.Name, token)
+	case "list":
+		keys, err := db.ListAPIKeys()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPE\tEXPIRES\tLAST USED")
+		for _, key := range keys {
+			scope := "full"
+			if key.ReadOnly {
+				scope = "read-only"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", key.Id, key.Name, scope, formatTime(key.ExpiresAt), formatTime(key.LastUsedAt))
+		}
+		return w.Flush()
+	case "revoke":
+		id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid token id %q", flags.Arg(0))
+		}
+		if !db.DeleteAPIKey(id) {
+			return fmt.Errorf("token %d not found", id)
+		}
+		fmt.Fprintf(out, "revoked token %d\n", id)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, tokenUsage)
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -1,13 +1,17 @@
 package htmlutil
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
 )
 
-var nodeNameRegex = regexp.MustCompile(`\w+|\*`)
+// compound selector, e.g. `a#top.title[href][rel="next"]`
+var compoundRegex = regexp.MustCompile(`^(\*|[\w-]+)?((?:#[\w-]+|\.[\w-]+|\[[\w-]+(?:~?=(?:"[^"]*"|'[^']*'|[^\]]*))?\])*)$`)
+var simpleRegex = regexp.MustCompile(`#([\w-]+)|\.([\w-]+)|\[([\w-]+)(?:(~?=)("[^"]*"|'[^']*'|[^\]]*))?\]`)
 
 func FindNodes(node *html.Node, match func(*html.Node) bool) []*html.Node {
 	nodes := make([]*html.Node, 0)
@@ -43,17 +47,114 @@ func Closest(node *html.Node, sel string) *html.Node {
 }
 
 func NewMatcher(sel string) Matcher {
+	matcher, err := ParseSelector(sel)
+	if err != nil {
+		panic(err)
+	}
+	return matcher
+}
+
+// ParseSelector compiles a subset of CSS selectors: element names, `*`,
+// `#id`, `.class`, `[attr]`, `[attr=value]`, `[attr~=value]`, combined
+// with the descendant (` `) and child (`>`) combinators, and grouped with `,`.
+func ParseSelector(sel string) (Matcher, error) {
 	multi := MultiMatch{}
-	parts := strings.SplitSeq(sel, ",")
-	for part := range parts {
-		part := strings.TrimSpace(part)
-		if nodeNameRegex.MatchString(part) {
-			multi.Add(ElementMatch{Name: part})
-		} else {
-			panic("unsupported selector: " + part)
+	for _, tokens := range splitSelector(sel) {
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("unsupported selector: %q", sel)
 		}
+		var matcher Matcher
+		child := false
+		for i, token := range tokens {
+			if token == ">" {
+				if i == 0 || i == len(tokens)-1 || child {
+					return nil, fmt.Errorf("unsupported selector: %q", sel)
+				}
+				child = true
+				continue
+			}
+			compound, err := parseCompound(token)
+			if err != nil {
+				return nil, err
+			}
+			if matcher == nil {
+				matcher = compound
+			} else {
+				matcher = DescendantMatch{Ancestor: matcher, Matcher: compound, Child: child}
+			}
+			child = false
+		}
+		multi.Add(matcher)
 	}
-	return multi
+	return multi, nil
+}
+
+// splitSelector splits the selector group into lists of compound
+// selectors and `>` combinators.
+func splitSelector(sel string) [][]string {
+	parts := [][]string{{}}
+	token := strings.Builder{}
+	quote := rune(0)
+	flush := func() {
+		if token.Len() > 0 {
+			parts[len(parts)-1] = append(parts[len(parts)-1], token.String())
+			token.Reset()
+		}
+	}
+	for _, c := range sel {
+		switch {
+		case quote != 0:
+			token.WriteRune(c)
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			token.WriteRune(c)
+			quote = c
+		case c == ',':
+			flush()
+			parts = append(parts, []string{})
+		case c == '>':
+			flush()
+			parts[len(parts)-1] = append(parts[len(parts)-1], ">")
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		default:
+			token.WriteRune(c)
+		}
+	}
+	flush()
+	return parts
+}
+
+func parseCompound(sel string) (Matcher, error) {
+	m := compoundRegex.FindStringSubmatch(sel)
+	if m == nil || sel == "" {
+		return nil, fmt.Errorf("unsupported selector: %q", sel)
+	}
+	name := m[1]
+	if name == "" {
+		name = "*"
+	}
+	if m[2] == "" {
+		return ElementMatch{Name: name}, nil
+	}
+	compound := CompoundMatch{ElementMatch{Name: name}}
+	for _, s := range simpleRegex.FindAllStringSubmatch(m[2], -1) {
+		switch {
+		case s[1] != "":
+			compound = append(compound, AttrMatch{Key: "id", Op: "=", Value: s[1]})
+		case s[2] != "":
+			compound = append(compound, AttrMatch{Key: "class", Op: "~=", Value: s[2]})
+		default:
+			value := s[5]
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+				value = value[1 : len(value)-1]
+			}
+			compound = append(compound, AttrMatch{Key: s[3], Op: s[4], Value: value})
+		}
+	}
+	return compound, nil
 }
 
 type Matcher interface {
@@ -68,6 +169,68 @@ func (m ElementMatch) Match(n *html.Node) bool {
 	return n.Type == html.ElementNode && (n.Data == m.Name || m.Name == "*")
 }
 
+// AttrMatch matches elements having the attribute (empty Op), the attribute
+// equal to Value ("=") or containing Value as a whitespace-separated word ("~=").
+type AttrMatch struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+func (m AttrMatch) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, a := range n.Attr {
+		if a.Key != m.Key {
+			continue
+		}
+		switch m.Op {
+		case "=":
+			return a.Val == m.Value
+		case "~=":
+			return slices.Contains(strings.Fields(a.Val), m.Value)
+		}
+		return true
+	}
+	return false
+}
+
+// CompoundMatch matches elements satisfying all of its matchers.
+type CompoundMatch []Matcher
+
+func (m CompoundMatch) Match(n *html.Node) bool {
+	for _, matcher := range m {
+		if !matcher.Match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// DescendantMatch matches elements that have an ancestor (or the parent,
+// if Child is set) matching Ancestor.
+type DescendantMatch struct {
+	Ancestor Matcher
+	Matcher  Matcher
+	Child    bool
+}
+
+func (m DescendantMatch) Match(n *html.Node) bool {
+	if !m.Matcher.Match(n) {
+		return false
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if m.Ancestor.Match(p) {
+			return true
+		}
+		if m.Child {
+			break
+		}
+	}
+	return false
+}
+
 type MultiMatch struct {
 	matchers []Matcher
 }
@@ -85,3 +85,45 @@ func TestClosest(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestQuerySelectors(t *testing.T) {
+	node, _ := html.Parse(strings.NewReader(`
+		<ul id="log">
+			<li class="entry new"><a href="/1" rel="bookmark">one</a></li>
+			<li class="entry"><span><a href="/2">two</a></span></li>
+		</ul>
+		<a class="entry" href="/3">three</a>
+	`))
+	// nodes come in breadth-first order
+	tests := map[string]string{
+		"li.entry":               "one two",
+		".entry.new":             "one",
+		"#log a":                 "one two",
+		"#log > li > a":          "one",
+		"li a[rel=bookmark]":     "one",
+		`a[href="/3"], li.new a`: "three one",
+		"[class~=entry]":         "three one two",
+		"ul li span > a[href]":   "two",
+		"div a":                  "",
+	}
+	for sel, want := range tests {
+		matcher, err := ParseSelector(sel)
+		if err != nil {
+			t.Errorf("%s: %s", sel, err)
+			continue
+		}
+		texts := make([]string, 0)
+		for _, n := range FindNodes(node, matcher.Match) {
+			texts = append(texts, Text(n))
+		}
+		if have := strings.Join(texts, " "); have != want {
+			t.Errorf("%s: want %q, have %q", sel, want, have)
+		}
+	}
+
+	for _, sel := range []string{"", "a >", "> a", "a > > b", "a:first-child", "a,"} {
+		if _, err := ParseSelector(sel); err == nil {
+			t.Errorf("expected error for %q", sel)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package events is an in-process bus notifying clients of changes
+// (refreshes, new items, item status updates) as they happen.
+package events
+
+import "sync"
+
+const (
+	RefreshStarted  = "refresh_started"
+	RefreshFinished = "refresh_finished"
+	FeedError       = "feed_error"
+	ItemsCreated    = "items_created"
+	ItemStatus      = "item_status"
+	ItemsRead       = "items_read"
+)
+
+type Event struct {
+	Type string
+	// The user the event concerns, 0 for everyone.
+	UserID int64
+	// The feed the event concerns, if any. Only its owner gets the event.
+	FeedID int64
+	Data   any
+}
+
+// size of the per-subscriber queue; events are dropped for subscribers lagging behind
+const queueSize = 64
+
+type Bus struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish delivers the event to all subscribers without blocking.
+// Publishing to a nil bus is a no-op.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		select {
+		case sub <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving published events,
+// and a function to cancel the subscription.
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	sub := make(chan Event, queueSize)
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			close(sub)
+		})
+	}
+}
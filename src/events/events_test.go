@@ -0,0 +1,34 @@
+package events
+
+import "testing"
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	first, cancelFirst := bus.Subscribe()
+	second, cancelSecond := bus.Subscribe()
+	defer cancelSecond()
+
+	bus.Publish(Event{Type: ItemsCreated, FeedID: 1})
+	for _, sub := range []<-chan Event{first, second} {
+		if event := <-sub; event.Type != ItemsCreated || event.FeedID != 1 {
+			t.Errorf("unexpected event %#v", event)
+		}
+	}
+
+	cancelFirst()
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Error("expected cancelled subscription to be closed")
+	}
+
+	// slow subscribers don't block publishers
+	for range queueSize + 1 {
+		bus.Publish(Event{Type: ItemStatus})
+	}
+	if len(second) != queueSize {
+		t.Errorf("expected %d queued events, got %d", queueSize, len(second))
+	}
+
+	var nilBus *Bus
+	nilBus.Publish(Event{Type: ItemStatus})
+}
@@ -4,6 +4,33 @@ export interface MediaLink {
   url: string;
   type: string;
   description?: string;
+  mime_type?: string;
+  size?: number;
+  duration?: number;
+}
+
+export interface PodcastLink {
+  url: string;
+  type?: string;
+  language?: string;
+  rel?: string;
+}
+
+export interface PodcastPerson {
+  name: string;
+  role?: string;
+  group?: string;
+  image?: string;
+  href?: string;
+}
+
+export interface Podcast {
+  image?: string;
+  season?: number;
+  episode?: number;
+  chapters?: PodcastLink;
+  transcripts?: PodcastLink[];
+  persons?: PodcastPerson[];
 }
 
 export interface Feed {
@@ -14,6 +41,8 @@ export interface Feed {
   link: string;
   feed_link: string;
   icon?: string | null;
+  fetch_content?: boolean;
+  download_media?: boolean;
 }
 
 export interface Folder {
@@ -29,9 +58,14 @@ export interface Item {
   title: string;
   link: string;
   content?: string;
+  full_content?: string;
   date: string;
   status: ItemStatus;
   media_links: MediaLink[];
+  author?: string;
+  categories?: string[];
+  comments_url?: string;
+  podcast?: Podcast;
 }
 
 export interface Settings {
@@ -44,6 +78,7 @@ export interface Settings {
   theme_font: string;
   theme_size: number;
   refresh_rate: number;
+  refresh_rate_locked?: boolean;
   language: string;
 }
 
@@ -53,6 +88,14 @@ export interface FeedStat {
   starred: number;
 }
 
+export interface FeedError {
+  error: string;
+  failure_count: number;
+  first_failure: string | null;
+  next_refresh: string | null;
+  disabled: boolean;
+}
+
 export interface StatusResponse {
   running: number;
   stats: FeedStat[];
@@ -6,6 +6,7 @@ import type {
   StatusResponse,
   ItemListResponse,
   FeedCreateResponse,
+  FeedError,
   CrawlResponse,
   FeedCreateData,
   FeedUpdateData,
@@ -107,8 +108,11 @@ export default {
     refresh(): Promise<Response> {
       return api("post", "./api/feeds/refresh");
     },
-    list_errors(): Promise<Record<number, string>> {
-      return api("get", "./api/feeds/errors").then(json<Record<number, string>>);
+    list_errors(): Promise<Record<number, FeedError>> {
+      return api("get", "./api/feeds/errors").then(json<Record<number, FeedError>>);
+    },
+    enable(id: number): Promise<Response> {
+      return api("post", `./api/feeds/${id}/enable`);
     },
   },
   folders: {
@@ -9,12 +9,16 @@ import (
 	"github.com/nkanaev/yarr/src/content/htmlutil"
 )
 
+const atomNS = "http://www.w3.org/2005/Atom"
+
 type atomFeed struct {
-	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
-	ID      string      `xml:"id"`
-	Title   atomText    `xml:"title"`
-	Links   atomLinks   `xml:"link"`
-	Entries []atomEntry `xml:"entry"`
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   atomText     `xml:"title"`
+	Lang    string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Links   atomLinks    `xml:"link"`
+	Authors []atomPerson `xml:"author"`
+	Entries []atomEntry  `xml:"entry"`
 }
 
 type atomEntry struct {
@@ -27,9 +31,29 @@ type atomEntry struct {
 	Content   atomText  `xml:"http://www.w3.org/2005/Atom content"`
 	OrigLink  string    `xml:"http://rssnamespace.org/feedburner/ext/1.0 origLink"`
 
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+
 	media
 }
 
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+func atomAuthors(persons []atomPerson) string {
+	names := make([]string, len(persons))
+	for i, p := range persons {
+		names[i] = p.Name
+	}
+	return joinAuthors(names...)
+}
+
 type atomText struct {
 	Type string `xml:"type,attr"`
 	Data string `xml:",chardata"`
@@ -37,8 +61,10 @@ type atomText struct {
 }
 
 type atomLink struct {
-	Href string `xml:"href,attr"`
-	Rel  string `xml:"rel,attr"`
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
 }
 
 type atomLinks []atomLink
@@ -69,6 +95,25 @@ func (links atomLinks) First(rel string) string {
 	return ""
 }
 
+// enclosures returns the media links of the enclosure links,
+// keeping the first audio only, like for RSS.
+func (links atomLinks) enclosures() []MediaLink {
+	var result []MediaLink
+	hasAudio := false
+	for _, l := range links {
+		if l.Rel != "enclosure" {
+			continue
+		}
+		link, ok := enclosureLink(l.Href, l.Type, l.Length)
+		if !ok || (link.Type == "audio" && hasAudio) {
+			continue
+		}
+		hasAudio = hasAudio || link.Type == "audio"
+		result = append(result, link)
+	}
+	return result
+}
+
 func ParseAtom(r io.Reader) (*Feed, error) {
 	srcfeed := atomFeed{}
 
@@ -78,8 +123,11 @@ func ParseAtom(r io.Reader) (*Feed, error) {
 	}
 
 	dstfeed := &Feed{
-		Title:   srcfeed.Title.String(),
-		SiteURL: firstNonEmpty(srcfeed.Links.First("alternate"), srcfeed.Links.First("")),
+		Title:    srcfeed.Title.String(),
+		SiteURL:  firstNonEmpty(srcfeed.Links.First("alternate"), srcfeed.Links.First("")),
+		HubURL:   srcfeed.Links.First("hub"),
+		SelfURL:  srcfeed.Links.First("self"),
+		Language: srcfeed.Lang,
 	}
 	for _, srcitem := range srcfeed.Entries {
 		linkFromID := ""
@@ -89,7 +137,13 @@ func ParseAtom(r io.Reader) (*Feed, error) {
 			guidFromID = srcitem.ID + "::" + srcitem.Updated
 		}
 
-		mediaLinks := srcitem.mediaLinks()
+		mediaLinks := append(srcitem.mediaLinks(), srcitem.Links.enclosures()...)
+
+		categories := make([]string, len(srcitem.Categories))
+		for i, c := range srcitem.Categories {
+			categories[i] = firstNonEmpty(c.Label, c.Term)
+		}
+		categories = uniqueNonEmpty(categories...)
 
 		link := firstNonEmpty(
 			srcitem.OrigLink,
@@ -107,7 +161,10 @@ func ParseAtom(r io.Reader) (*Feed, error) {
 				srcitem.Summary.String(),
 				srcitem.firstMediaDescription(),
 			),
-			MediaLinks: mediaLinks,
+			MediaLinks:  mediaLinks,
+			Author:      firstNonEmpty(atomAuthors(srcitem.Authors), atomAuthors(srcfeed.Authors)),
+			Categories:  categories,
+			CommentsURL: srcitem.Links.First("replies"),
 		})
 	}
 	return dstfeed, nil
@@ -1,21 +1,77 @@
-// JSON 1.0 parser
+// JSON Feed 1.0 and 1.1 parser
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"html"
 	"io"
+	"strings"
 )
 
 type jsonFeed struct {
-	Version string     `json:"version"`
-	Title   string     `json:"title"`
-	SiteURL string     `json:"home_page_url"`
-	Items   []jsonItem `json:"items"`
+	Version  string     `json:"version"`
+	Title    string     `json:"title"`
+	SiteURL  string     `json:"home_page_url"`
+	FeedURL  string     `json:"feed_url"`
+	Language string     `json:"language"`
+	Hubs     []jsonHub  `json:"hubs"`
+	Items    []jsonItem `json:"items"`
+
+	Author  *jsonAuthor  `json:"author"`
+	Authors []jsonAuthor `json:"authors"`
+}
+
+// jsonString accepts numbers where the spec requires strings,
+// a common mistake for item ids.
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = jsonString(str)
+		return nil
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*s = jsonString(num)
+	return nil
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// jsonAuthors combines `authors` (1.1) and the deprecated `author` (1.0).
+func jsonAuthors(author *jsonAuthor, authors []jsonAuthor) string {
+	names := make([]string, 0, len(authors)+1)
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	if len(names) == 0 && author != nil {
+		names = append(names, author.Name)
+	}
+	return joinAuthors(names...)
+}
+
+type jsonHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
 }
 
 type jsonItem struct {
-	ID            string           `json:"id"`
+	ID            jsonString       `json:"id"`
 	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
 	Title         string           `json:"title"`
 	Summary       string           `json:"summary"`
 	Text          string           `json:"content_text"`
@@ -23,14 +79,58 @@ type jsonItem struct {
 	DatePublished string           `json:"date_published"`
 	DateModified  string           `json:"date_modified"`
 	Attachments   []jsonAttachment `json:"attachments"`
+	Image         string           `json:"image"`
+	BannerImage   string           `json:"banner_image"`
+	Author        *jsonAuthor      `json:"author"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Tags          []string         `json:"tags"`
 }
 
 type jsonAttachment struct {
-	URL      string `json:"url"`
-	MimeType string `json:"mime_type"`
-	Title    string `json:"title"`
-	Size     int64  `json:"size_in_bytes"`
-	Duration int    `json:"duration_in_seconds"`
+	URL      string  `json:"url"`
+	MimeType string  `json:"mime_type"`
+	Title    string  `json:"title"`
+	Size     float64 `json:"size_in_bytes"`
+	Duration float64 `json:"duration_in_seconds"`
+}
+
+// mediaLinks returns the item's images and its image, audio and video
+// attachments. Other attachments (e.g. documents) are skipped.
+func (item *jsonItem) mediaLinks() []MediaLink {
+	var links []MediaLink
+	add := func(link MediaLink) {
+		for _, l := range links {
+			if l.URL == link.URL {
+				return
+			}
+		}
+		links = append(links, link)
+	}
+	for _, image := range []string{item.Image, item.BannerImage} {
+		if image = strings.TrimSpace(image); image != "" {
+			add(MediaLink{URL: image, Type: "image"})
+		}
+	}
+	for _, a := range item.Attachments {
+		medium, _, _ := strings.Cut(strings.ToLower(a.MimeType), "/")
+		if a.URL == "" || (medium != "image" && medium != "audio" && medium != "video") {
+			continue
+		}
+		add(MediaLink{
+			URL:         a.URL,
+			Type:        medium,
+			Description: a.Title,
+			MimeType:    a.MimeType,
+			Size:        int64(a.Size),
+			Duration:    int(a.Duration),
+		})
+	}
+	return links
+}
+
+// jsonText converts content_text to html.
+func jsonText(text string) string {
+	return plain2html(html.EscapeString(strings.TrimSpace(text)))
 }
 
 func ParseJSON(data io.Reader) (*Feed, error) {
@@ -41,16 +141,32 @@ func ParseJSON(data io.Reader) (*Feed, error) {
 	}
 
 	dstfeed := &Feed{
-		Title:   srcfeed.Title,
-		SiteURL: srcfeed.SiteURL,
+		Title:    srcfeed.Title,
+		SiteURL:  srcfeed.SiteURL,
+		SelfURL:  srcfeed.FeedURL,
+		Language: srcfeed.Language,
+	}
+	for _, hub := range srcfeed.Hubs {
+		if strings.EqualFold(hub.Type, "websub") {
+			dstfeed.HubURL = hub.URL
+			break
+		}
 	}
 	for _, srcitem := range srcfeed.Items {
+		// linkblog posts may only link to the page they're about
+		link := firstNonEmpty(srcitem.URL, srcitem.ExternalURL)
 		dstfeed.Items = append(dstfeed.Items, Item{
-			GUID:    firstNonEmpty(srcitem.ID, srcitem.URL),
-			Date:    dateParse(firstNonEmpty(srcitem.DatePublished, srcitem.DateModified)),
-			URL:     srcitem.URL,
-			Title:   srcitem.Title,
-			Content: firstNonEmpty(srcitem.HTML, srcitem.Text, srcitem.Summary),
+			GUID:       firstNonEmpty(string(srcitem.ID), link),
+			Date:       dateParse(firstNonEmpty(srcitem.DatePublished, srcitem.DateModified)),
+			URL:        link,
+			Title:      srcitem.Title,
+			Content:    firstNonEmpty(srcitem.HTML, jsonText(srcitem.Text), jsonText(srcitem.Summary)),
+			MediaLinks: srcitem.mediaLinks(),
+			Author: firstNonEmpty(
+				jsonAuthors(srcitem.Author, srcitem.Authors),
+				jsonAuthors(srcfeed.Author, srcfeed.Authors),
+			),
+			Categories: uniqueNonEmpty(srcitem.Tags...),
 		})
 	}
 	return dstfeed, nil
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -34,6 +35,24 @@ type mediaDescription struct {
 	Text string `xml:",chardata"`
 }
 
+// enclosureLink returns the media link of an enclosure (or an Atom link
+// with rel="enclosure"), if it's an image, an audio or a video.
+func enclosureLink(url, mimeType, length string) (MediaLink, bool) {
+	var kind string
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		kind = "image"
+	case strings.HasPrefix(mimeType, "audio/"):
+		kind = "audio"
+	case strings.HasPrefix(mimeType, "video/"):
+		kind = "video"
+	default:
+		return MediaLink{}, false
+	}
+	size, _ := strconv.ParseInt(strings.TrimSpace(length), 10, 64)
+	return MediaLink{URL: url, Type: kind, MimeType: mimeType, Size: size}, true
+}
+
 func (m *media) firstMediaDescription() string {
 	for _, d := range m.MediaDescriptions {
 		return plain2html(d.Text)
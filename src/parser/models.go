@@ -6,6 +6,17 @@ type Feed struct {
 	Title   string
 	SiteURL string
 	Items   []Item
+
+	// Publisher's polling hints (<ttl>, sy:updatePeriod, <skipHours>).
+	UpdateInterval time.Duration
+	SkipHours      []int
+
+	// WebSub discovery: the hub to subscribe to and the canonical feed url.
+	HubURL  string
+	SelfURL string
+
+	// Language of the feed, e.g. "en-US".
+	Language string
 }
 
 type Item struct {
@@ -16,10 +27,47 @@ type Item struct {
 
 	Content    string
 	MediaLinks []MediaLink
+
+	Author      string
+	Categories  []string
+	CommentsURL string
+
+	// podcast episode metadata (iTunes and Podcasting 2.0 namespaces)
+	Podcast *Podcast
 }
 
 type MediaLink struct {
 	URL         string
 	Type        string
 	Description string
+
+	// optional details of attachments
+	MimeType string
+	Size     int64 // bytes
+	Duration int   // seconds
+}
+
+type Podcast struct {
+	Image   string
+	Season  int
+	Episode int
+
+	Chapters    *PodcastLink
+	Transcripts []PodcastLink
+	Persons     []PodcastPerson
+}
+
+type PodcastLink struct {
+	URL      string
+	Type     string
+	Language string
+	Rel      string
+}
+
+type PodcastPerson struct {
+	Name  string
+	Role  string
+	Group string
+	Image string
+	Href  string
 }
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// podcastItem holds the iTunes and Podcasting 2.0 extensions of an rss item.
+type podcastItem struct {
+	ITunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesImage    itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesEpisode  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesSeason   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	ITunesSummary  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+
+	Chapters    *podcastLink    `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+	Transcripts []podcastLink   `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+	Persons     []podcastPerson `xml:"https://podcastindex.org/namespace/1.0 person"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type podcastLink struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+	Rel      string `xml:"rel,attr"`
+}
+
+type podcastPerson struct {
+	Name  string `xml:",chardata"`
+	Role  string `xml:"role,attr"`
+	Group string `xml:"group,attr"`
+	Image string `xml:"img,attr"`
+	Href  string `xml:"href,attr"`
+}
+
+// podcast returns the episode's metadata, or nil if there's none.
+func (p *podcastItem) podcast() *Podcast {
+	out := &Podcast{
+		Image:   strings.TrimSpace(p.ITunesImage.Href),
+		Episode: parseNumber(p.ITunesEpisode),
+		Season:  parseNumber(p.ITunesSeason),
+	}
+	if p.Chapters != nil && p.Chapters.URL != "" {
+		out.Chapters = &PodcastLink{URL: p.Chapters.URL, Type: p.Chapters.Type}
+	}
+	for _, t := range p.Transcripts {
+		if t.URL != "" {
+			out.Transcripts = append(out.Transcripts, PodcastLink(t))
+		}
+	}
+	for _, person := range p.Persons {
+		if name := strings.TrimSpace(person.Name); name != "" {
+			person.Name = name
+			out.Persons = append(out.Persons, PodcastPerson(person))
+		}
+	}
+	if out.Image == "" && out.Episode == 0 && out.Season == 0 &&
+		out.Chapters == nil && len(out.Transcripts) == 0 && len(out.Persons) == 0 {
+		return nil
+	}
+	return out
+}
+
+func parseNumber(val string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseDuration parses itunes:duration, either seconds
+// or `[HH:]MM:SS`, into seconds.
+func parseDuration(val string) int {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0
+	}
+	seconds := 0.0
+	for _, part := range strings.Split(val, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return int(math.Round(seconds))
+}
@@ -13,6 +13,9 @@ type rdfFeed struct {
 	Title   string    `xml:"channel>title"`
 	Link    string    `xml:"channel>link"`
 	Items   []rdfItem `xml:"item"`
+
+	UpdatePeriod    string `xml:"channel>updatePeriod"`
+	UpdateFrequency string `xml:"channel>updateFrequency"`
 }
 
 type rdfItem struct {
@@ -20,8 +23,10 @@ type rdfItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 
-	DublinCoreDate string `xml:"http://purl.org/dc/elements/1.1/ date"`
-	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	DublinCoreDate    string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DublinCoreCreator []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DublinCoreSubject []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	ContentEncoded    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 }
 
 func ParseRDF(r io.Reader) (*Feed, error) {
@@ -33,16 +38,19 @@ func ParseRDF(r io.Reader) (*Feed, error) {
 	}
 
 	dstfeed := &Feed{
-		Title:   srcfeed.Title,
-		SiteURL: srcfeed.Link,
+		Title:          srcfeed.Title,
+		SiteURL:        srcfeed.Link,
+		UpdateInterval: updateInterval("", srcfeed.UpdatePeriod, srcfeed.UpdateFrequency),
 	}
 	for _, srcitem := range srcfeed.Items {
 		dstfeed.Items = append(dstfeed.Items, Item{
-			GUID:    srcitem.Link,
-			URL:     srcitem.Link,
-			Date:    dateParse(srcitem.DublinCoreDate),
-			Title:   srcitem.Title,
-			Content: firstNonEmpty(srcitem.ContentEncoded, srcitem.Description),
+			GUID:       srcitem.Link,
+			URL:        srcitem.Link,
+			Date:       dateParse(srcitem.DublinCoreDate),
+			Title:      srcitem.Title,
+			Content:    firstNonEmpty(srcitem.ContentEncoded, srcitem.Description),
+			Author:     joinAuthors(srcitem.DublinCoreCreator...),
+			Categories: uniqueNonEmpty(srcitem.DublinCoreSubject...),
 		})
 	}
 	return dstfeed, nil
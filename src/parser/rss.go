@@ -8,15 +8,22 @@ import (
 	"encoding/xml"
 	"io"
 	"path"
+	"strconv"
 	"strings"
 )
 
 type rssFeed struct {
-	XMLName xml.Name  `xml:"rss"`
-	Version string    `xml:"version,attr"`
-	Title   string    `xml:"channel>title"`
-	Link    string    `xml:"channel>link"`
-	Items   []rssItem `xml:"channel>item"`
+	XMLName  xml.Name  `xml:"rss"`
+	Version  string    `xml:"version,attr"`
+	Title    string    `xml:"channel>title"`
+	Links    rssLinks  `xml:"channel>link"`
+	Language string    `xml:"channel>language"`
+	Items    []rssItem `xml:"channel>item"`
+
+	TTL             string   `xml:"channel>ttl"`
+	SkipHours       []string `xml:"channel>skipHours>hour"`
+	UpdatePeriod    string   `xml:"channel>updatePeriod"`
+	UpdateFrequency string   `xml:"channel>updateFrequency"`
 }
 
 type rssItem struct {
@@ -26,14 +33,19 @@ type rssItem struct {
 	Description string         `xml:"rss description"`
 	PubDate     string         `xml:"rss pubDate"`
 	Enclosures  []rssEnclosure `xml:"rss enclosure"`
+	Author      string         `xml:"rss author"`
+	Categories  []string       `xml:"rss category"`
+	Comments    string         `xml:"rss comments"`
 
-	DublinCoreDate string `xml:"http://purl.org/dc/elements/1.1/ date"`
-	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	DublinCoreDate    string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DublinCoreCreator []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	ContentEncoded    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 
 	OrigLink          string `xml:"http://rssnamespace.org/feedburner/ext/1.0 origLink"`
 	OrigEnclosureLink string `xml:"http://rssnamespace.org/feedburner/ext/1.0 origEnclosureLink"`
 
 	media
+	podcastItem
 }
 
 type rssGuid struct {
@@ -48,6 +60,36 @@ type rssLink struct {
 	Rel     string `xml:"rel,attr"`
 }
 
+// rssLinks holds both the plain channel <link> and atom:link elements.
+type rssLinks []rssLink
+
+func (links rssLinks) Site() string {
+	for _, l := range links {
+		if l.XMLName.Space != atomNS && strings.TrimSpace(l.Data) != "" {
+			return l.Data
+		}
+	}
+	return ""
+}
+
+func (links rssLinks) Atom(rel string) string {
+	for _, l := range links {
+		if l.XMLName.Space == atomNS && l.Rel == rel {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// rssAuthor extracts the name from the `email (Name)` form of <author>.
+func rssAuthor(author string) string {
+	author = strings.TrimSpace(author)
+	if email, name, ok := strings.Cut(author, " ("); ok && strings.Contains(email, "@") && strings.HasSuffix(name, ")") {
+		return strings.TrimSpace(strings.TrimSuffix(name, ")"))
+	}
+	return author
+}
+
 type rssEnclosure struct {
 	URL    string `xml:"url,attr"`
 	Type   string `xml:"type,attr"`
@@ -65,8 +107,13 @@ func ParseRSS(r io.Reader) (*Feed, error) {
 	}
 
 	dstfeed := &Feed{
-		Title:   srcfeed.Title,
-		SiteURL: srcfeed.Link,
+		Title:          srcfeed.Title,
+		SiteURL:        srcfeed.Links.Site(),
+		UpdateInterval: updateInterval(srcfeed.TTL, srcfeed.UpdatePeriod, srcfeed.UpdateFrequency),
+		SkipHours:      skipHours(srcfeed.SkipHours),
+		HubURL:         srcfeed.Links.Atom("hub"),
+		SelfURL:        srcfeed.Links.Atom("self"),
+		Language:       strings.TrimSpace(srcfeed.Language),
 	}
 	for _, srcitem := range srcfeed.Items {
 		mediaLinks := srcitem.mediaLinks()
@@ -77,13 +124,20 @@ func ParseRSS(r io.Reader) (*Feed, error) {
 					strings.Contains(podcastURL, path.Base(srcitem.OrigEnclosureLink)) {
 					podcastURL = srcitem.OrigEnclosureLink
 				}
-				mediaLinks = append(mediaLinks, MediaLink{URL: podcastURL, Type: "audio"})
+				size, _ := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+				mediaLinks = append(mediaLinks, MediaLink{
+					URL:      podcastURL,
+					Type:     "audio",
+					MimeType: e.Type,
+					Size:     size,
+					Duration: parseDuration(srcitem.ITunesDuration),
+				})
 				break
 			}
 		}
 		for _, e := range srcitem.Enclosures {
-			if strings.HasPrefix(e.Type, "image/") {
-				mediaLinks = append(mediaLinks, MediaLink{URL: e.URL, Type: "image"})
+			if link, ok := enclosureLink(e.URL, e.Type, e.Length); ok && link.Type != "audio" {
+				mediaLinks = append(mediaLinks, link)
 			}
 		}
 
@@ -100,9 +154,14 @@ func ParseRSS(r io.Reader) (*Feed, error) {
 			Content: firstNonEmpty(
 				srcitem.ContentEncoded,
 				srcitem.Description,
+				srcitem.ITunesSummary,
 				srcitem.firstMediaDescription(),
 			),
-			MediaLinks: mediaLinks,
+			MediaLinks:  mediaLinks,
+			Author:      firstNonEmpty(joinAuthors(srcitem.DublinCoreCreator...), rssAuthor(srcitem.Author)),
+			Categories:  uniqueNonEmpty(srcitem.Categories...),
+			CommentsURL: strings.TrimSpace(srcitem.Comments),
+			Podcast:     srcitem.podcast(),
 		})
 	}
 	return dstfeed, nil
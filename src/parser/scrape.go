@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/content/htmlutil"
+	"golang.org/x/net/html"
+)
+
+// Selectors describe where the items of a web page without a feed are.
+// Item selects the item containers, the others are evaluated within each
+// of them and are optional.
+type Selectors struct {
+	Item    string
+	Title   string
+	Link    string
+	Date    string
+	Content string
+}
+
+type scrapeMatchers struct {
+	item, title, link, date, content htmlutil.Matcher
+}
+
+func (s Selectors) compile() (*scrapeMatchers, error) {
+	if strings.TrimSpace(s.Item) == "" {
+		return nil, errors.New("item selector is required")
+	}
+	m := &scrapeMatchers{}
+	for _, x := range []struct {
+		sel string
+		dst *htmlutil.Matcher
+	}{
+		{s.Item, &m.item},
+		{s.Title, &m.title},
+		{s.Link, &m.link},
+		{s.Date, &m.date},
+		{s.Content, &m.content},
+	} {
+		if strings.TrimSpace(x.sel) == "" {
+			continue
+		}
+		matcher, err := htmlutil.ParseSelector(x.sel)
+		if err != nil {
+			return nil, err
+		}
+		*x.dst = matcher
+	}
+	return m, nil
+}
+
+// Validate reports whether the selectors are supported.
+func (s Selectors) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// Scrape extracts the items of an html page (already decoded to utf-8).
+//
+// Without a title selector the text of the link is used, without a link
+// selector the first link of the item, and without a content selector the
+// whole item. GUIDs are the item links, or a hash of the item's text when
+// it has no link of its own, so they stay the same between refreshes.
+func Scrape(r io.Reader, pageURL string, sel Selectors) (*Feed, error) {
+	m, err := sel.compile()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page url: %#v", pageURL)
+	}
+
+	feed := &Feed{SiteURL: pageURL}
+	if title := htmlutil.Query(doc, "title"); len(title) > 0 {
+		feed.Title = htmlutil.Text(title[0])
+	}
+
+	seen := make(map[string]bool)
+	for _, node := range htmlutil.FindNodes(doc, m.item.Match) {
+		item := Item{}
+
+		var link *html.Node
+		if m.link != nil {
+			link = first(node, m.link)
+		} else if node.Data == "a" {
+			link = node
+		} else {
+			link = first(node, htmlutil.ElementMatch{Name: "a"})
+		}
+		if link != nil {
+			if href := htmlutil.Attr(link, "href"); href != "" {
+				if u, err := base.Parse(href); err == nil {
+					item.URL = u.String()
+				}
+			}
+		}
+
+		switch {
+		case m.title != nil:
+			if n := first(node, m.title); n != nil {
+				item.Title = htmlutil.Text(n)
+			}
+		case link != nil:
+			item.Title = htmlutil.Text(link)
+		}
+
+		if m.date != nil {
+			if n := first(node, m.date); n != nil {
+				item.Date = dateParse(firstNonEmpty(htmlutil.Attr(n, "datetime"), htmlutil.Text(n)))
+			}
+		}
+
+		content := node
+		if m.content != nil {
+			content = first(node, m.content)
+		}
+		if content != nil {
+			item.Content = htmlutil.InnerHTML(content)
+		}
+
+		if strings.TrimSpace(item.Title) == "" && strings.TrimSpace(item.Content) == "" {
+			continue
+		}
+
+		// several items may point to the same page
+		item.GUID = item.URL
+		if item.GUID == "" || seen[item.GUID] {
+			text := item.Title + ";;" + htmlutil.Text(node)
+			item.GUID = fmt.Sprintf("%x", sha256.Sum256([]byte(text)))
+		}
+		if seen[item.GUID] {
+			continue
+		}
+		seen[item.GUID] = true
+
+		feed.Items = append(feed.Items, item)
+	}
+	feed.cleanup()
+	feed.SetMissingDatesTo(time.Now())
+	return feed, nil
+}
+
+// first returns the first node within the item matching the selector.
+func first(node *html.Node, matcher htmlutil.Matcher) *html.Node {
+	if nodes := htmlutil.FindNodes(node, matcher.Match); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
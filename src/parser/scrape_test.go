@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const scrapePage = `<!DOCTYPE html>
+<html>
+<head><title>Changelog</title></head>
+<body>
+	<nav><a href="/">Home</a></nav>
+	<section class="releases">
+		<article class="release">
+			<h2><a href="/releases/2.0">Version 2.0</a></h2>
+			<time datetime="2024-03-01">March 1</time>
+			<div class="notes"><p>New <b>engine</b>.</p></div>
+		</article>
+		<article class="release">
+			<h2>Version 1.1</h2>
+			<time>2024-01-15</time>
+			<div class="notes"><p>Bug fixes.</p></div>
+		</article>
+		<article class="release"></article>
+	</section>
+</body>
+</html>`
+
+func TestScrape(t *testing.T) {
+	sel := Selectors{
+		Item:    ".releases > article.release",
+		Title:   "h2",
+		Date:    "time",
+		Content: ".notes",
+	}
+	feed, err := Scrape(strings.NewReader(scrapePage), "https://example.com/changelog", sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Changelog" || feed.SiteURL != "https://example.com/changelog" {
+		t.Errorf("unexpected feed: %#v", feed)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %#v", feed.Items)
+	}
+
+	have := feed.Items[0]
+	want := Item{
+		GUID:    "https://example.com/releases/2.0",
+		Date:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		URL:     "https://example.com/releases/2.0",
+		Title:   "Version 2.0",
+		Content: "<p>New <b>engine</b>.</p>",
+	}
+	if have.GUID != want.GUID || !have.Date.Equal(want.Date) || have.URL != want.URL || have.Title != want.Title || have.Content != want.Content {
+		t.Errorf("want %#v\nhave %#v", want, have)
+	}
+
+	// items without a link are identified by their text
+	second := feed.Items[1]
+	if second.URL != "" || second.GUID == "" || second.Title != "Version 1.1" {
+		t.Errorf("unexpected item: %#v", second)
+	}
+	again, _ := Scrape(strings.NewReader(scrapePage), "https://example.com/changelog", sel)
+	if again.Items[1].GUID != second.GUID {
+		t.Error("guid should be stable")
+	}
+}
+
+func TestScrapeDefaults(t *testing.T) {
+	feed, err := Scrape(strings.NewReader(scrapePage), "https://example.com/changelog", Selectors{Item: "h2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %#v", feed.Items)
+	}
+	if item := feed.Items[0]; item.Title != "Version 2.0" || item.URL != "https://example.com/releases/2.0" {
+		t.Errorf("unexpected item: %#v", item)
+	}
+	if item := feed.Items[1]; item.Title != "" || item.Content != "Version 1.1" {
+		t.Errorf("unexpected item: %#v", item)
+	}
+}
+
+func TestSelectorsValidate(t *testing.T) {
+	if err := (Selectors{Item: "li"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, sel := range []Selectors{{}, {Item: "li", Title: "a:first-child"}} {
+		if err := sel.Validate(); err == nil {
+			t.Errorf("expected error for %#v", sel)
+		}
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"encoding/xml"
 	"io"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/html/charset"
 )
@@ -21,6 +25,74 @@ func firstNonEmpty(vals ...string) string {
 	return ""
 }
 
+// uniqueNonEmpty returns the trimmed values without blanks and duplicates.
+func uniqueNonEmpty(vals ...string) []string {
+	var result []string
+	for _, val := range vals {
+		val = strings.TrimSpace(val)
+		if val != "" && !slices.Contains(result, val) {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+// joinAuthors lists multiple authors in a single line.
+func joinAuthors(names ...string) string {
+	return strings.Join(uniqueNonEmpty(names...), ", ")
+}
+
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// updateInterval combines RSS <ttl> (minutes) and the syndication module's
+// sy:updatePeriod/sy:updateFrequency into a single polling hint,
+// picking the longer of the two.
+func updateInterval(ttl, period, frequency string) time.Duration {
+	var interval time.Duration
+	if n, err := strconv.Atoi(strings.TrimSpace(ttl)); err == nil && n > 0 {
+		interval = time.Duration(n) * time.Minute
+	}
+	if p, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(period))]; ok {
+		freq := 1
+		if n, err := strconv.Atoi(strings.TrimSpace(frequency)); err == nil && n > 0 {
+			freq = n
+		}
+		if p/time.Duration(freq) > interval {
+			interval = p / time.Duration(freq)
+		}
+	}
+	return interval
+}
+
+// skipHours returns the sorted, deduplicated list of valid (0-23, GMT)
+// hours from RSS <skipHours>. "24" is treated as midnight.
+func skipHours(vals []string) []int {
+	seen := make(map[int]bool)
+	hours := make([]int, 0)
+	for _, val := range vals {
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n < 0 || n > 24 {
+			continue
+		}
+		n = n % 24
+		if !seen[n] {
+			seen[n] = true
+			hours = append(hours, n)
+		}
+	}
+	if len(hours) == 0 {
+		return nil
+	}
+	sort.Ints(hours)
+	return hours
+}
+
 var linkRe = regexp.MustCompile(`(https?:\/\/\S+)`)
 
 func plain2html(text string) string {
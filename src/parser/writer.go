@@ -0,0 +1,286 @@
+// Writers for Atom 1.0, RSS 2.0 and JSON Feed 1.1,
+// the counterparts of the parsers for feeds published by yarr.
+package parser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	mediaNS      = "http://search.yahoo.com/mrss/"
+	dublinCoreNS = "http://purl.org/dc/elements/1.1/"
+)
+
+// feedUpdated returns the date of the most recent item.
+func feedUpdated(feed *Feed) time.Time {
+	var updated time.Time
+	for _, item := range feed.Items {
+		if item.Date.After(updated) {
+			updated = item.Date
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated.UTC()
+}
+
+type mediaContentOut struct {
+	URL         string `xml:"url,attr"`
+	Medium      string `xml:"medium,attr,omitempty"`
+	Description string `xml:"media:description,omitempty"`
+}
+
+func mediaContents(links []MediaLink) []mediaContentOut {
+	out := make([]mediaContentOut, 0, len(links))
+	for _, link := range links {
+		out = append(out, mediaContentOut{URL: link.URL, Medium: link.Type, Description: link.Description})
+	}
+	return out
+}
+
+type atomFeedOut struct {
+	XMLName xml.Name       `xml:"feed"`
+	NS      string         `xml:"xmlns,attr"`
+	MediaNS string         `xml:"xmlns:media,attr"`
+	ID      string         `xml:"id"`
+	Title   string         `xml:"title"`
+	Updated string         `xml:"updated"`
+	Links   []atomLink     `xml:"link"`
+	Entries []atomEntryOut `xml:"entry"`
+}
+
+type atomEntryOut struct {
+	ID        string            `xml:"id"`
+	Title     string            `xml:"title"`
+	Links     []atomLink        `xml:"link"`
+	Published string            `xml:"published,omitempty"`
+	Updated   string            `xml:"updated"`
+	Content   atomTextOut       `xml:"content"`
+	Media     []mediaContentOut `xml:"media:content"`
+	Author    *atomPerson       `xml:"author"`
+	Category  []atomCategoryOut `xml:"category"`
+}
+
+type atomCategoryOut struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomTextOut struct {
+	Type string `xml:"type,attr"`
+	Data string `xml:",chardata"`
+}
+
+// WriteAtom encodes the feed as Atom 1.0.
+func WriteAtom(w io.Writer, feed *Feed) error {
+	updated := feedUpdated(feed)
+	out := atomFeedOut{
+		NS:      atomNS,
+		MediaNS: mediaNS,
+		ID:      firstNonEmpty(feed.SelfURL, feed.SiteURL),
+		Title:   feed.Title,
+		Updated: updated.Format(time.RFC3339),
+	}
+	if feed.SiteURL != "" {
+		out.Links = append(out.Links, atomLink{Rel: "alternate", Href: feed.SiteURL})
+	}
+	if feed.SelfURL != "" {
+		out.Links = append(out.Links, atomLink{Rel: "self", Href: feed.SelfURL})
+	}
+	for _, item := range feed.Items {
+		entry := atomEntryOut{
+			ID:      item.GUID,
+			Title:   item.Title,
+			Updated: updated.Format(time.RFC3339),
+			Content: atomTextOut{Type: "html", Data: item.Content},
+			Media:   mediaContents(item.MediaLinks),
+		}
+		if !item.Date.IsZero() {
+			entry.Published = item.Date.UTC().Format(time.RFC3339)
+			entry.Updated = entry.Published
+		}
+		if item.URL != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "alternate", Href: item.URL})
+		}
+		if item.CommentsURL != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "replies", Href: item.CommentsURL})
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		for _, category := range item.Categories {
+			entry.Category = append(entry.Category, atomCategoryOut{Term: category})
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return writeXML(w, out)
+}
+
+type rssFeedOut struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	AtomNS  string        `xml:"xmlns:atom,attr"`
+	MediaNS string        `xml:"xmlns:media,attr"`
+	DCNS    string        `xml:"xmlns:dc,attr"`
+	Channel rssChannelOut `xml:"channel"`
+}
+
+type rssChannelOut struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Self          *rssSelfOut  `xml:"atom:link"`
+	Items         []rssItemOut `xml:"item"`
+}
+
+type rssSelfOut struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItemOut struct {
+	Title       string            `xml:"title"`
+	Link        string            `xml:"link,omitempty"`
+	GUID        rssGuid           `xml:"guid"`
+	PubDate     string            `xml:"pubDate,omitempty"`
+	Description string            `xml:"description"`
+	Media       []mediaContentOut `xml:"media:content"`
+	Creator     string            `xml:"dc:creator,omitempty"`
+	Category    []string          `xml:"category"`
+	Comments    string            `xml:"comments,omitempty"`
+}
+
+// WriteRSS encodes the feed as RSS 2.0.
+func WriteRSS(w io.Writer, feed *Feed) error {
+	out := rssFeedOut{
+		Version: "2.0",
+		AtomNS:  atomNS,
+		MediaNS: mediaNS,
+		DCNS:    dublinCoreNS,
+		Channel: rssChannelOut{
+			Title:         feed.Title,
+			Link:          feed.SiteURL,
+			Description:   feed.Title,
+			LastBuildDate: feedUpdated(feed).Format(time.RFC1123Z),
+		},
+	}
+	if feed.SelfURL != "" {
+		out.Channel.Self = &rssSelfOut{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, item := range feed.Items {
+		entry := rssItemOut{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGuid{GUID: item.GUID, IsPermaLink: "false"},
+			Description: item.Content,
+			Media:       mediaContents(item.MediaLinks),
+			Creator:     item.Author,
+			Category:    item.Categories,
+			Comments:    item.CommentsURL,
+		}
+		if !item.Date.IsZero() {
+			entry.PubDate = item.Date.UTC().Format(time.RFC1123Z)
+		}
+		out.Channel.Items = append(out.Channel.Items, entry)
+	}
+	return writeXML(w, out)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(v)
+}
+
+type jsonFeedOut struct {
+	Version  string        `json:"version"`
+	Title    string        `json:"title"`
+	SiteURL  string        `json:"home_page_url,omitempty"`
+	FeedURL  string        `json:"feed_url,omitempty"`
+	Language string        `json:"language,omitempty"`
+	Items    []jsonItemOut `json:"items"`
+}
+
+type jsonItemOut struct {
+	ID            string              `json:"id"`
+	URL           string              `json:"url,omitempty"`
+	Title         string              `json:"title,omitempty"`
+	HTML          string              `json:"content_html"`
+	DatePublished string              `json:"date_published,omitempty"`
+	Attachments   []jsonAttachmentOut `json:"attachments,omitempty"`
+	Authors       []jsonAuthor        `json:"authors,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`
+}
+
+type jsonAttachmentOut struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Title    string `json:"title,omitempty"`
+	Size     int64  `json:"size_in_bytes,omitempty"`
+	Duration int    `json:"duration_in_seconds,omitempty"`
+}
+
+// attachmentType returns the mime type of a media link, guessed from its
+// extension if unknown.
+func attachmentType(link MediaLink) string {
+	if link.MimeType != "" {
+		return link.MimeType
+	}
+	u, _, _ := strings.Cut(link.URL, "?")
+	typ, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(u)), ";")
+	if typ != "" && (link.Type == "" || strings.HasPrefix(typ, link.Type+"/")) {
+		return typ
+	}
+	return "application/octet-stream"
+}
+
+// WriteJSON encodes the feed as JSON Feed 1.1.
+func WriteJSON(w io.Writer, feed *Feed) error {
+	out := jsonFeedOut{
+		Version:  "https://jsonfeed.org/version/1.1",
+		Title:    feed.Title,
+		SiteURL:  feed.SiteURL,
+		FeedURL:  feed.SelfURL,
+		Language: feed.Language,
+		Items:    make([]jsonItemOut, 0, len(feed.Items)),
+	}
+	for _, item := range feed.Items {
+		entry := jsonItemOut{
+			ID:    item.GUID,
+			URL:   item.URL,
+			Title: item.Title,
+			HTML:  item.Content,
+			Tags:  item.Categories,
+		}
+		if item.Author != "" {
+			entry.Authors = []jsonAuthor{{Name: item.Author}}
+		}
+		if !item.Date.IsZero() {
+			entry.DatePublished = item.Date.UTC().Format(time.RFC3339)
+		}
+		for _, link := range item.MediaLinks {
+			entry.Attachments = append(entry.Attachments, jsonAttachmentOut{
+				URL:      link.URL,
+				MimeType: attachmentType(link),
+				Title:    link.Description,
+				Size:     link.Size,
+				Duration: link.Duration,
+			})
+		}
+		out.Items = append(out.Items, entry)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriters(t *testing.T) {
+	date := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	feed := &Feed{
+		Title:   "Starred <items>",
+		SiteURL: "https://yarr.example.com/",
+		SelfURL: "https://yarr.example.com/out/starred.atom?token=abc",
+		Items: []Item{
+			{
+				GUID:    "item-1",
+				Date:    date,
+				URL:     "https://example.com/1",
+				Title:   "First & foremost",
+				Content: `<p>Hello <b>world</b></p>`,
+				MediaLinks: []MediaLink{
+					{URL: "https://example.com/episode.mp3", Type: "audio"},
+				},
+				Author:      "Jane Doe",
+				Categories:  []string{"go", "news"},
+				CommentsURL: "https://example.com/1#comments",
+			},
+			{
+				GUID:    "item-2",
+				Date:    date.Add(-time.Hour),
+				URL:     "https://example.com/2",
+				Title:   "Second",
+				Content: `<p>Bye</p>`,
+			},
+		},
+	}
+
+	writers := map[string]func(io.Writer, *Feed) error{
+		"atom": WriteAtom,
+		"rss":  WriteRSS,
+		"json": WriteJSON,
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf, feed); err != nil {
+				t.Fatal(err)
+			}
+			if probe := sniff(buf.String()); probe.feedType != name {
+				t.Fatalf("expected %s output, got %q", name, probe.feedType)
+			}
+			have, err := Parse(strings.NewReader(buf.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have.Title != feed.Title || have.SiteURL != feed.SiteURL || have.SelfURL != feed.SelfURL {
+				t.Errorf("unexpected feed: %#v", have)
+			}
+			if len(have.Items) != len(feed.Items) {
+				t.Fatalf("expected %d items, got %d", len(feed.Items), len(have.Items))
+			}
+			for i, want := range feed.Items {
+				item := have.Items[i]
+				if item.GUID != want.GUID || item.URL != want.URL || item.Title != want.Title ||
+					item.Content != want.Content || !item.Date.Equal(want.Date) {
+					t.Errorf("item %d:\nwant %#v\nhave %#v", i, want, item)
+				}
+				media := item.MediaLinks
+				if name == "json" {
+					// json attachments always have a mime type
+					for j := range media {
+						media[j].MimeType = ""
+					}
+				}
+				if !reflect.DeepEqual(media, want.MediaLinks) && len(media)+len(want.MediaLinks) > 0 {
+					t.Errorf("item %d: want media %#v, have %#v", i, want.MediaLinks, media)
+				}
+				if item.Author != want.Author || !reflect.DeepEqual(item.Categories, want.Categories) {
+					t.Errorf("item %d: want author %q %v, have %q %v", i, want.Author, want.Categories, item.Author, item.Categories)
+				}
+				// json feed has no comments url
+				if name != "json" && item.CommentsURL != want.CommentsURL {
+					t.Errorf("item %d: want comments %q, have %q", i, want.CommentsURL, item.CommentsURL)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentType(t *testing.T) {
+	testcases := map[MediaLink]string{
+		{URL: "https://example.com/a.mp3?x=1", Type: "audio"}: "audio/mpeg",
+		{URL: "https://example.com/a.png", Type: "image"}:     "image/png",
+		{URL: "https://example.com/a.png", Type: "audio"}:     "application/octet-stream",
+		{URL: "https://example.com/stream", Type: "video"}:    "application/octet-stream",
+	}
+	for link, want := range testcases {
+		if have := attachmentType(link); have != want {
+			t.Errorf("%s: want %q, have %q", link.URL, want, have)
+		}
+	}
+}
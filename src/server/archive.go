@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/archive"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// handleArchive exports the account of the user (GET), or imports
+// an archive uploaded as the `archive` form file (POST).
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		filename := fmt.Sprintf("yarr_%s.zip", time.Now().Format("2006-01-02_15-04-05"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		if err := archive.Export(s.db, w); err != nil {
+			// the response is under way, all that can be done is cutting it short
+			log.Print(err)
+		}
+	case http.MethodPost:
+		file, header, err := r.FormFile("archive")
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		result, err := archive.Import(s.db, file, header.Size)
+		if err != nil {
+			log.Print(err)
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		// the archive may come with another refresh rate, see handleSettings
+		if s.db.UserID() == model.DefaultUserID {
+			s.worker.SetRefreshRate(s.db.GetSettings().RefreshRate)
+		}
+		s.worker.RefreshFeeds(s.db.UserID())
+		writeJSON(w, http.StatusOK, result)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
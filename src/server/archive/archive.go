@@ -0,0 +1,92 @@
+// Package archive reads and writes the account archives used to move
+// the data of a user between instances: a zip of JSON files with the
+// folders, feeds (with their icons and refresh state), items (with their
+// status) and settings.
+//
+// Feeds are identified by their feed link and items by their feed link
+// and guid, so importing the same archive more than once is harmless.
+package archive
+
+import (
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+const (
+	Format  = "yarr-archive"
+	Version = 1
+)
+
+// names of the files within the archive
+const (
+	manifestFile   = "manifest.json"
+	settingsFile   = "settings.json"
+	foldersFile    = "folders.json"
+	feedsFile      = "feeds.json"
+	feedStatesFile = "feed_states.json"
+	itemsFile      = "items.json"
+)
+
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Folder struct {
+	Title      string `json:"title"`
+	IsExpanded bool   `json:"is_expanded"`
+}
+
+type Feed struct {
+	// title of the folder, if any
+	Folder          *string `json:"folder"`
+	Title           string  `json:"title"`
+	Description     string  `json:"description"`
+	Link            string  `json:"link"`
+	FeedLink        string  `json:"feed_link"`
+	Icon            []byte  `json:"icon,omitempty"`
+	RefreshInterval *int64  `json:"refresh_interval"`
+	FetchContent    bool    `json:"fetch_content"`
+	DownloadMedia   bool    `json:"download_media"`
+}
+
+type FeedState struct {
+	FeedLink         string     `json:"feed_link"`
+	LastRefreshed    *time.Time `json:"last_refreshed,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	HTTPLastModified string     `json:"http_last_modified,omitempty"`
+	HTTPEtag         string     `json:"http_etag,omitempty"`
+	NextRefresh      *time.Time `json:"next_refresh,omitempty"`
+	// seconds
+	RefreshInterval int64      `json:"refresh_interval"`
+	SkipHours       []int      `json:"skip_hours,omitempty"`
+	FailureCount    int        `json:"failure_count"`
+	FirstFailure    *time.Time `json:"first_failure,omitempty"`
+	Disabled        bool       `json:"disabled"`
+}
+
+type Item struct {
+	FeedLink    string           `json:"feed_link"`
+	GUID        string           `json:"guid"`
+	Title       string           `json:"title"`
+	Link        string           `json:"link"`
+	Content     string           `json:"content,omitempty"`
+	FullContent string           `json:"full_content,omitempty"`
+	Date        time.Time        `json:"date"`
+	Status      model.ItemStatus `json:"status"`
+	MediaLinks  model.MediaLinks `json:"media_links,omitempty"`
+	Author      string           `json:"author,omitempty"`
+	Categories  []string         `json:"categories,omitempty"`
+	CommentsURL string           `json:"comments_url,omitempty"`
+	Podcast     *model.Podcast   `json:"podcast,omitempty"`
+}
+
+// Result counts what an import added or changed.
+type Result struct {
+	Folders      int `json:"folders"`
+	Feeds        int `json:"feeds"`
+	Items        int `json:"items"`
+	UpdatedItems int `json:"updated_items"`
+}
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestExportImport(t *testing.T) {
+	src, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := src.CreateFolder("News")
+	feed := src.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed", FolderID: &folder.Id})
+	icon := model.Icon("\x89PNG")
+	fetch := true
+	src.UpdateFeed(feed.Id, model.UpdateFeedParams{Icon: model.SetNullable(&icon), FetchContent: &fetch})
+	etag := `"abc"`
+	src.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{HTTPEtag: &etag})
+	date := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	items, _ := src.CreateItems([]model.Item{
+		{GUID: "a", FeedId: feed.Id, Title: "a", Date: date, Content: "<p>alpha</p>", Categories: []string{"go"}},
+		{GUID: "b", FeedId: feed.Id, Title: "b", Date: date.Add(time.Hour)},
+		{GUID: "c", FeedId: feed.Id, Title: "c", Date: date.Add(2 * time.Hour)},
+	})
+	src.UpdateItemStatus(items[0].Id, model.STARRED)
+	src.UpdateItemStatus(items[1].Id, model.READ)
+	full := "<p>full alpha</p>"
+	src.UpdateItem(items[0].Id, model.UpdateItemParams{FullContent: &full})
+	theme := "night"
+	rate := int64(30)
+	src.UpdateSettings(model.UpdateSettingsParams{ThemeName: &theme, RefreshRate: &rate})
+
+	var buf bytes.Buffer
+	if err := Export(src, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// already subscribed on the other instance, with one of the items
+	existing := dst.CreateFeed(model.CreateFeedParams{Title: "mine", FeedLink: "http://example.com/feed"})
+	dst.CreateItems([]model.Item{{GUID: "c", FeedId: existing.Id, Title: "c", Date: date.Add(2 * time.Hour)}})
+
+	result, err := Import(dst, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Result{Folders: 1, Feeds: 0, Items: 2, UpdatedItems: 0}
+	if *result != want {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	have := dst.ListItems(model.ItemFilter{}, 10, false, true)
+	if len(have) != 3 {
+		t.Fatalf("unexpected items: %#v", have)
+	}
+	if have[0].GUID != "a" || have[0].Status != model.STARRED || dst.GetItem(have[0].Id).FullContent != full || !have[0].Date.Equal(date) ||
+		!reflect.DeepEqual(have[0].Categories, []string{"go"}) {
+		t.Errorf("unexpected item: %#v", have[0])
+	}
+	if have[1].Status != model.READ {
+		t.Errorf("unexpected item: %#v", have[1])
+	}
+	if s := dst.GetSettings(); s.ThemeName != theme || s.RefreshRate != rate {
+		t.Errorf("unexpected settings: %#v", s)
+	}
+
+	// the shared refresh rate is left alone in the accounts of other users
+	user, err := dst.CreateUser(model.CreateUserParams{Username: "alice", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := storage.ForUser(dst, user.Id)
+	if _, err := Import(other, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if s := other.GetSettings(); s.ThemeName != theme || s.RefreshRate == rate {
+		t.Errorf("unexpected settings of another user: %#v", s)
+	}
+
+	// importing again only brings back the status
+	dst.UpdateItemStatus(have[0].Id, model.READ)
+	result, err = Import(dst, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Result{UpdatedItems: 1}
+	if *result != want {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if item := dst.GetItem(have[0].Id); item.Status != model.STARRED {
+		t.Errorf("unexpected status: %v", item.Status)
+	}
+
+	// into an empty account, the feeds come with their icons and state
+	empty, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(empty, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+	feeds := empty.ListFeeds()
+	if len(feeds) != 1 || feeds[0].Icon == nil || string(*feeds[0].Icon) != string(icon) || !feeds[0].FetchContent || feeds[0].FolderId == nil {
+		t.Fatalf("unexpected feeds: %#v", feeds)
+	}
+	if state, _ := empty.GetFeedState(feeds[0].Id); state == nil || state.HTTPEtag != etag {
+		t.Errorf("unexpected feed state: %#v", state)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("not a zip")
+	if _, err := Import(db, bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("expected an error")
+	}
+}
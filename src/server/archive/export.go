@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// number of items read from the database at once
+const exportBatchSize = 500
+
+// Export writes the archive of the user the storage is scoped to.
+// The items are written as they are read, so the archive can be
+// sent while it's being made.
+func Export(db storage.Storage, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	manifest := Manifest{Format: Format, Version: Version, CreatedAt: time.Now().UTC()}
+	if err := writeFile(zw, manifestFile, manifest); err != nil {
+		return err
+	}
+	if err := writeFile(zw, settingsFile, db.GetSettings()); err != nil {
+		return err
+	}
+
+	folders := db.ListFolders()
+	folderTitles := make(map[int64]string, len(folders))
+	archivedFolders := make([]Folder, 0, len(folders))
+	for _, f := range folders {
+		folderTitles[f.Id] = f.Title
+		archivedFolders = append(archivedFolders, Folder{Title: f.Title, IsExpanded: f.IsExpanded})
+	}
+	if err := writeFile(zw, foldersFile, archivedFolders); err != nil {
+		return err
+	}
+
+	feeds := db.ListFeeds()
+	feedLinks := make(map[int64]string, len(feeds))
+	archivedFeeds := make([]Feed, 0, len(feeds))
+	for _, f := range feeds {
+		feedLinks[f.Id] = f.FeedLink
+		feed := Feed{
+			Title:           f.Title,
+			Description:     f.Description,
+			Link:            f.Link,
+			FeedLink:        f.FeedLink,
+			RefreshInterval: f.RefreshInterval,
+			FetchContent:    f.FetchContent,
+			DownloadMedia:   f.DownloadMedia,
+		}
+		if f.FolderId != nil {
+			title := folderTitles[*f.FolderId]
+			feed.Folder = &title
+		}
+		if f.Icon != nil {
+			feed.Icon = *f.Icon
+		}
+		archivedFeeds = append(archivedFeeds, feed)
+	}
+	if err := writeFile(zw, feedsFile, archivedFeeds); err != nil {
+		return err
+	}
+
+	states, err := db.ListFeedStates()
+	if err != nil {
+		return err
+	}
+	archivedStates := make([]FeedState, 0, len(states))
+	for _, s := range states {
+		link, ok := feedLinks[s.FeedID]
+		if !ok {
+			continue
+		}
+		state := FeedState{
+			FeedLink:         link,
+			LastError:        s.LastError,
+			HTTPLastModified: s.HTTPLastModified,
+			HTTPEtag:         s.HTTPEtag,
+			NextRefresh:      s.NextRefresh,
+			RefreshInterval:  int64(s.RefreshInterval / time.Second),
+			SkipHours:        s.SkipHours,
+			FailureCount:     s.FailureCount,
+			FirstFailure:     s.FirstFailure,
+			Disabled:         s.Disabled,
+		}
+		if !s.LastRefreshed.IsZero() {
+			state.LastRefreshed = &s.LastRefreshed
+		}
+		archivedStates = append(archivedStates, state)
+	}
+	if err := writeFile(zw, feedStatesFile, archivedStates); err != nil {
+		return err
+	}
+
+	if err := writeItems(zw, db, feedLinks); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// writeItems writes the items as a JSON array, one item per line.
+func writeItems(zw *zip.Writer, db storage.Storage, feedLinks map[int64]string) error {
+	f, err := zw.Create(itemsFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+
+	if _, err := io.WriteString(f, "[\n"); err != nil {
+		return err
+	}
+	first := true
+	var filter model.ItemFilter
+	for {
+		items := db.ListItems(filter, exportBatchSize, false, true)
+		// lists leave out the full content, which is read per batch
+		itemIDs := make([]int64, len(items))
+		for i, item := range items {
+			itemIDs[i] = item.Id
+		}
+		fullContents := db.ListItemFullContents(itemIDs)
+		for _, item := range items {
+			if !first {
+				if _, err := io.WriteString(f, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			err := enc.Encode(Item{
+				FeedLink:    feedLinks[item.FeedId],
+				GUID:        item.GUID,
+				Title:       item.Title,
+				Link:        item.Link,
+				Content:     item.Content,
+				FullContent: fullContents[item.Id],
+				Date:        item.Date,
+				Status:      item.Status,
+				MediaLinks:  item.MediaLinks,
+				Author:      item.Author,
+				Categories:  item.Categories,
+				CommentsURL: item.CommentsURL,
+				Podcast:     item.Podcast,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(items) < exportBatchSize {
+			break
+		}
+		last := items[len(items)-1].Id
+		filter.After = &last
+	}
+	_, err = io.WriteString(f, "]\n")
+	return err
+}
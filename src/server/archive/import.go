@@ -0,0 +1,329 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// number of items written to the database at once
+const importBatchSize = 500
+
+// Import adds the content of the archive to the account the storage is
+// scoped to. Existing folders and feeds are kept as they are, missing
+// ones are created; items are created or get the status of the archive.
+func Import(db storage.Storage, r io.ReaderAt, size int64) (*Result, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+
+	var manifest Manifest
+	if err := readFile(zr, manifestFile, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != Format {
+		return nil, errors.New("invalid archive: unknown format")
+	}
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+
+	result := &Result{}
+	im := &importer{db: db, result: result}
+	steps := []func(*zip.Reader) error{
+		im.settings,
+		im.folders,
+		im.feeds,
+		im.feedStates,
+		im.items,
+	}
+	for _, step := range steps {
+		if err := step(zr); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// readFile decodes a file of the archive; missing files are skipped.
+func readFile(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && name != manifestFile {
+			return nil
+		}
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("invalid archive: %s: %w", name, err)
+	}
+	return nil
+}
+
+type importedItem struct {
+	id     int64
+	status model.ItemStatus
+}
+
+type itemKey struct {
+	feedID int64
+	guid   string
+}
+
+type importer struct {
+	db     storage.Storage
+	result *Result
+
+	folderIDs map[string]int64
+	feedIDs   map[string]int64
+	// feeds created by the import, which get the state of the archive
+	newFeeds map[int64]bool
+	// items of the feeds by guid, loaded when needed
+	feedItems map[int64]map[string]importedItem
+}
+
+func (im *importer) settings(zr *zip.Reader) error {
+	var params model.UpdateSettingsParams
+	if err := readFile(zr, settingsFile, &params); err != nil {
+		return err
+	}
+	// the refresh rate is shared, only the default user sets it
+	if im.db.UserID() != model.DefaultUserID {
+		params.RefreshRate = nil
+	}
+	im.db.UpdateSettings(params)
+	return nil
+}
+
+func (im *importer) folders(zr *zip.Reader) error {
+	var folders []Folder
+	if err := readFile(zr, foldersFile, &folders); err != nil {
+		return err
+	}
+	im.folderIDs = make(map[string]int64)
+	for _, f := range im.db.ListFolders() {
+		im.folderIDs[f.Title] = f.Id
+	}
+	for _, f := range folders {
+		if _, ok := im.folderIDs[f.Title]; ok || f.Title == "" {
+			continue
+		}
+		folder := im.db.CreateFolder(f.Title)
+		if folder == nil {
+			return fmt.Errorf("failed to create folder %q", f.Title)
+		}
+		if !f.IsExpanded {
+			im.db.UpdateFolder(folder.Id, model.UpdateFolderParams{IsExpanded: &f.IsExpanded})
+		}
+		im.folderIDs[f.Title] = folder.Id
+		im.result.Folders++
+	}
+	return nil
+}
+
+func (im *importer) feeds(zr *zip.Reader) error {
+	var feeds []Feed
+	if err := readFile(zr, feedsFile, &feeds); err != nil {
+		return err
+	}
+	im.feedIDs = make(map[string]int64)
+	im.newFeeds = make(map[int64]bool)
+	for _, f := range im.db.ListFeeds() {
+		im.feedIDs[f.FeedLink] = f.Id
+	}
+	for _, f := range feeds {
+		if _, ok := im.feedIDs[f.FeedLink]; ok || f.FeedLink == "" {
+			continue
+		}
+		params := model.CreateFeedParams{
+			Title:       f.Title,
+			Description: f.Description,
+			Link:        f.Link,
+			FeedLink:    f.FeedLink,
+		}
+		if f.Folder != nil {
+			folderID, ok := im.folderIDs[*f.Folder]
+			if !ok {
+				folder := im.db.CreateFolder(*f.Folder)
+				if folder == nil {
+					return fmt.Errorf("failed to create folder %q", *f.Folder)
+				}
+				folderID = folder.Id
+				im.folderIDs[*f.Folder] = folderID
+				im.result.Folders++
+			}
+			params.FolderID = &folderID
+		}
+		feed := im.db.CreateFeed(params)
+		if feed == nil {
+			return fmt.Errorf("failed to create feed %s", f.FeedLink)
+		}
+		update := model.UpdateFeedParams{
+			RefreshInterval: model.SetNullable(f.RefreshInterval),
+			FetchContent:    &f.FetchContent,
+			DownloadMedia:   &f.DownloadMedia,
+		}
+		if len(f.Icon) > 0 {
+			icon := model.Icon(f.Icon)
+			update.Icon = model.SetNullable(&icon)
+		}
+		if _, err := im.db.UpdateFeed(feed.Id, update); err != nil {
+			return err
+		}
+		im.feedIDs[f.FeedLink] = feed.Id
+		im.newFeeds[feed.Id] = true
+		im.result.Feeds++
+	}
+	return nil
+}
+
+func (im *importer) feedStates(zr *zip.Reader) error {
+	var states []FeedState
+	if err := readFile(zr, feedStatesFile, &states); err != nil {
+		return err
+	}
+	for _, s := range states {
+		feedID, ok := im.feedIDs[s.FeedLink]
+		if !ok || !im.newFeeds[feedID] {
+			continue
+		}
+		interval := time.Duration(s.RefreshInterval) * time.Second
+		params := model.UpdateFeedStateParams{
+			LastRefreshed:    s.LastRefreshed,
+			LastError:        &s.LastError,
+			HTTPLastModified: &s.HTTPLastModified,
+			HTTPEtag:         &s.HTTPEtag,
+			NextRefresh:      s.NextRefresh,
+			RefreshInterval:  &interval,
+			SkipHours:        &s.SkipHours,
+			FailureCount:     &s.FailureCount,
+			FirstFailure:     model.SetNullable(s.FirstFailure),
+			Disabled:         &s.Disabled,
+		}
+		if _, err := im.db.UpdateFeedState(feedID, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// items reads the items one at a time, and writes them in batches.
+func (im *importer) items(zr *zip.Reader) error {
+	f, err := zr.Open(itemsFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	defer f.Close()
+
+	im.feedItems = make(map[int64]map[string]importedItem)
+	dec := json.NewDecoder(f)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return fmt.Errorf("invalid archive: %s: expected an array", itemsFile)
+	}
+	batch := make([]Item, 0, importBatchSize)
+	for dec.More() {
+		var item Item
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("invalid archive: %s: %w", itemsFile, err)
+		}
+		batch = append(batch, item)
+		if len(batch) == importBatchSize {
+			if err := im.writeItems(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	return im.writeItems(batch)
+}
+
+func (im *importer) writeItems(batch []Item) error {
+	var items []model.Item
+	fullContent := make(map[itemKey]string)
+	for _, item := range batch {
+		feedID, ok := im.feedIDs[item.FeedLink]
+		if !ok {
+			return fmt.Errorf("invalid archive: unknown feed %s", item.FeedLink)
+		}
+		known := im.existingItems(feedID)
+		if existing, ok := known[item.GUID]; ok {
+			if existing.id != 0 && existing.status != item.Status {
+				im.db.UpdateItemStatus(existing.id, item.Status)
+				known[item.GUID] = importedItem{id: existing.id, status: item.Status}
+				im.result.UpdatedItems++
+			}
+			continue
+		}
+		// marks the guid as seen, in case the archive has duplicates
+		known[item.GUID] = importedItem{status: item.Status}
+		if item.FullContent != "" {
+			fullContent[itemKey{feedID, item.GUID}] = item.FullContent
+		}
+		items = append(items, model.Item{
+			GUID:        item.GUID,
+			FeedId:      feedID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Content:     item.Content,
+			Date:        item.Date,
+			Status:      item.Status,
+			MediaLinks:  item.MediaLinks,
+			Author:      item.Author,
+			Categories:  item.Categories,
+			CommentsURL: item.CommentsURL,
+			Podcast:     item.Podcast,
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	created, ok := im.db.CreateItems(items)
+	if !ok {
+		return errors.New("failed to create items")
+	}
+	for _, item := range created {
+		im.feedItems[item.FeedId][item.GUID] = importedItem{id: item.Id, status: item.Status}
+		if content, ok := fullContent[itemKey{item.FeedId, item.GUID}]; ok {
+			im.db.UpdateItem(item.Id, model.UpdateItemParams{FullContent: &content})
+		}
+	}
+	im.result.Items += len(created)
+	return nil
+}
+
+// existingItems returns the items of the feed by guid.
+func (im *importer) existingItems(feedID int64) map[string]importedItem {
+	if known, ok := im.feedItems[feedID]; ok {
+		return known
+	}
+	known := make(map[string]importedItem)
+	im.feedItems[feedID] = known
+	if im.newFeeds[feedID] {
+		return known
+	}
+	filter := model.ItemFilter{FeedID: &feedID}
+	for {
+		items := im.db.ListItems(filter, importBatchSize, false, false)
+		for _, item := range items {
+			known[item.GUID] = importedItem{id: item.Id, status: item.Status}
+		}
+		if len(items) < importBatchSize {
+			break
+		}
+		last := items[len(items)-1].Id
+		filter.After = &last
+	}
+	return known
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/server/archive"
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestArchive(t *testing.T) {
+	newServer := func() (storage.Storage, func(method, path, body string, header http.Header) *httptest.ResponseRecorder) {
+		db, serve := newTestServer(t, withCredentials("admin", "pass"))
+		token, hash := auth.NewToken()
+		if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+			t.Fatal(err)
+		}
+		return db, func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+			return serve(method, path, body, header, http.Header{"Authorization": {"Bearer " + token}})
+		}
+	}
+
+	srcDB, src := newServer()
+	feed := srcDB.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+	srcDB.CreateItems([]model.Item{{GUID: "a", FeedId: feed.Id, Title: "a", Status: model.STARRED}})
+
+	res := src("GET", "/api/archive", "", nil)
+	if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("unexpected response: %d %s", res.Code, res.Header().Get("Content-Type"))
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("archive", "yarr.zip")
+	part.Write(res.Body.Bytes())
+	form.Close()
+
+	dstDB, dst := newServer()
+	res = dst("POST", "/api/archive", body.String(), http.Header{"Content-Type": {form.FormDataContentType()}})
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body)
+	}
+	var result archive.Result
+	json.NewDecoder(res.Body).Decode(&result)
+	if result.Feeds != 1 || result.Items != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	status := model.STARRED
+	if items := dstDB.ListItems(model.ItemFilter{Status: &status}, 10, false, false); len(items) != 1 {
+		t.Errorf("unexpected items: %#v", items)
+	}
+}
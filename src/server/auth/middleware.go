@@ -1,30 +1,61 @@
 package auth
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"net/http"
 	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
 )
 
-func IsAuthenticated(req *http.Request, username, password string) bool {
+// Lookup returns the id of the user with the given name and the key used
+// to sign their session. ok is false if there's no such user.
+type Lookup func(username string) (userID int64, key string, ok bool)
+
+// TokenLookup returns the id of the user owning the API token and whether
+// the token is limited to reading. ok is false if the token is unknown or expired.
+type TokenLookup func(token string) (userID int64, readOnly bool, ok bool)
+
+type userKey struct{}
+
+// WithUser returns a copy of the request with the authenticated user attached.
+func WithUser(r *http.Request, userID int64) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, userID))
+}
+
+// UserID returns the authenticated user of the request,
+// or the default user if authentication is disabled.
+func UserID(r *http.Request) int64 {
+	if id, ok := r.Context().Value(userKey{}).(int64); ok {
+		return id
+	}
+	return model.DefaultUserID
+}
+
+func IsAuthenticated(req *http.Request, lookup Lookup) (int64, bool) {
 	cookie, _ := req.Cookie("auth")
 	if cookie == nil {
-		return false
+		return 0, false
+	}
+	username, signature, ok := strings.Cut(cookie.Value, ":")
+	if !ok {
+		return 0, false
 	}
-	parts := strings.Split(cookie.Value, ":")
-	if len(parts) != 2 || !StringsEqual(parts[0], username) {
-		return false
+	userID, key, ok := lookup(username)
+	if !ok || !StringsEqual(signature, secret(username, key)) {
+		return 0, false
 	}
-	return StringsEqual(parts[1], secret(username, password))
+	return userID, true
 }
 
-func Authenticate(rw http.ResponseWriter, username, password, basepath string) {
+func Authenticate(rw http.ResponseWriter, username, key, basepath string) {
 	http.SetCookie(rw, &http.Cookie{
 		Name:     "auth",
-		Value:    username + ":" + secret(username, password),
+		Value:    username + ":" + secret(username, key),
 		MaxAge:   604800, // 1 week
 		Path:     basepath,
 		Secure:   true,
@@ -45,6 +76,7 @@ func StringsEqual(p1, p2 string) bool {
 	return subtle.ConstantTimeCompare([]byte(p1), []byte(p2)) == 1
 }
 
+// Secret signs the message with the key.
 func secret(msg, key string) string {
 	mac := hmac.New(sha256.New, []byte(key))
 	mac.Write([]byte(msg))
@@ -52,10 +84,35 @@ func secret(msg, key string) string {
 	return hex.EncodeToString(src)
 }
 
-func Middleware(username, password string, next http.Handler) http.Handler {
+// BearerToken returns the token passed via the `Authorization: Bearer` header.
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// IsReadOnly reports whether the request doesn't modify anything.
+func IsReadOnly(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+func Middleware(lookup Lookup, tokens TokenLookup, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if IsAuthenticated(r, username, password) {
-			next.ServeHTTP(w, r)
+		if token := BearerToken(r); token != "" {
+			userID, readOnly, ok := tokens(token)
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+			} else if readOnly && !IsReadOnly(r) {
+				w.WriteHeader(http.StatusForbidden)
+			} else {
+				next.ServeHTTP(w, WithUser(r, userID))
+			}
+			return
+		}
+		if userID, ok := IsAuthenticated(r, lookup); ok {
+			next.ServeHTTP(w, WithUser(r, userID))
 		} else {
 			w.WriteHeader(http.StatusUnauthorized)
 		}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const passwordIterations = 100000
+
+// HashPassword derives a salted hash of the password suitable for storage.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"pbkdf2-sha256$%d$%s$%s",
+		passwordIterations, hex.EncodeToString(salt), hex.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword reports whether the password matches the hash produced by HashPassword.
+func CheckPassword(password, hash string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, 32)
+	if err != nil {
+		return false
+	}
+	return StringsEqual(hex.EncodeToString(key), parts[3])
+}
+
+// FeverKey is the api key Fever clients derive from the credentials.
+func FeverKey(username, password string) string {
+	return fmt.Sprintf("%x", md5.Sum(fmt.Appendf(nil, "%s:%s", username, password)))
+}
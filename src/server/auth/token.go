@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewToken generates a random API token. Only its hash is meant to be stored.
+func NewToken() (token, hash string) {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	token = hex.EncodeToString(buf)
+	return token, HashToken(token)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestBackup(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	token, hash := auth.NewToken()
+	if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+
+	server := NewServer(db, "127.0.0.1:8000")
+	server.Username = "admin"
+	server.Password = "pass"
+	handler := server.handler()
+
+	req := httptest.NewRequest("GET", "/api/backup", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	if cd := res.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="yarr-backup-`) {
+		t.Errorf("unexpected content disposition: %q", cd)
+	}
+	if !bytes.HasPrefix(res.Body.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Fatalf("expected a sqlite database")
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(path, res.Body.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := storage.New(filepath.Join(t.TempDir(), "restored.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if _, err := storage.Restore(restored, path, false); err != nil {
+		t.Fatal(err)
+	}
+	if f := restored.GetFeed(feed.Id); f == nil || f.Title != "feed" {
+		t.Errorf("unexpected feed: %#v", f)
+	}
+
+	req = httptest.NewRequest("GET", "/api/backup", nil)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", res.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nkanaev/yarr/src/events"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// interval of comments sent to keep idle connections open
+const eventsKeepAlive = 30 * time.Second
+
+// publish announces a change made on behalf of the current user.
+func (s *Server) publish(kind string, data any) {
+	s.events.Publish(events.Event{Type: kind, UserID: s.db.UserID(), Data: data})
+}
+
+func (s *Server) updateItemStatus(id int64, status model.ItemStatus) bool {
+	ok := s.db.UpdateItemStatus(id, status)
+	if ok {
+		s.publish(events.ItemStatus, map[string]any{"id": id, "status": status})
+	}
+	return ok
+}
+
+func (s *Server) markItemsRead(filter model.MarkFilter) bool {
+	ok := s.db.MarkItemsRead(filter)
+	if ok {
+		s.publish(events.ItemsRead, map[string]any{
+			"feed_id":   filter.FeedID,
+			"folder_id": filter.FolderID,
+			"label_id":  filter.Label,
+		})
+	}
+	return ok
+}
+
+func writeEvent(w io.Writer, kind string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, payload)
+	return err
+}
+
+// handleEvents streams the events concerning the current user (server-sent events).
+// The stream starts with a "status" event, same as /api/status.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	sub, cancel := s.events.Subscribe()
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	writeEvent(w, "status", map[string]any{
+		"running": s.worker.FeedsPending(),
+		"stats":   s.db.FeedStats(),
+	})
+	if err := rc.Flush(); err != nil {
+		log.Print(err)
+		return
+	}
+
+	owned := make(map[int64]bool)
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+			if event.UserID != 0 && event.UserID != s.db.UserID() {
+				continue
+			}
+			if event.FeedID != 0 {
+				if _, seen := owned[event.FeedID]; !seen {
+					owned[event.FeedID] = s.db.GetFeed(event.FeedID) != nil
+				}
+				if !owned[event.FeedID] {
+					continue
+				}
+			}
+			if err := writeEvent(w, event.Type, event.Data); err != nil {
+				log.Print(err)
+				continue
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/events"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestEvents(t *testing.T) {
+	db := newTestDB(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+	db.CreateItems([]model.Item{{GUID: "1", FeedId: feed.Id, Title: "item", Date: time.Now(), Status: model.UNREAD}})
+	item := db.ListItems(model.ItemFilter{}, 1, false, false)[0]
+
+	other, err := db.CreateUser(model.CreateUserParams{Username: "bob", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFeed := storage.ForUser(db, other.Id).CreateFeed(model.CreateFeedParams{Title: "bob's", FeedLink: "http://example.com/bob.xml"})
+
+	server := NewServer(db, "127.0.0.1:8000")
+	srv := httptest.NewServer(server.handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+
+	reader := bufio.NewReader(res.Body)
+	next := func() (string, string) {
+		var kind, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "" && kind != "":
+				return kind, data
+			case strings.HasPrefix(line, "event: "):
+				kind = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	if kind, data := next(); kind != "status" || !strings.Contains(data, `"running"`) {
+		t.Fatalf("unexpected initial event: %s %s", kind, data)
+	}
+
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/items/%d", srv.URL, item.Id), strings.NewReader(`{"status": "read"}`))
+	if res, err := http.DefaultClient.Do(req); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("failed to update item: %v %v", err, res)
+	}
+	kind, data := next()
+	var status struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}
+	json.Unmarshal([]byte(data), &status)
+	if kind != events.ItemStatus || status.ID != item.Id || status.Status != "read" {
+		t.Fatalf("unexpected event: %s %s", kind, data)
+	}
+
+	// events of feeds owned by other users are not delivered
+	server.events.Publish(events.Event{Type: events.ItemsCreated, FeedID: otherFeed.Id, Data: "other"})
+	server.events.Publish(events.Event{Type: events.ItemsCreated, UserID: other.Id, Data: "other"})
+	server.events.Publish(events.Event{Type: events.ItemsCreated, FeedID: feed.Id, Data: "own"})
+	if kind, data := next(); kind != events.ItemsCreated || data != `"own"` {
+		t.Fatalf("unexpected event: %s %s", kind, data)
+	}
+}
@@ -1,16 +1,16 @@
 package server
 
 import (
-	"crypto/md5"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/nkanaev/yarr/src/server/auth"
 	"github.com/nkanaev/yarr/src/storage"
 	"github.com/nkanaev/yarr/src/storage/model"
 )
@@ -71,18 +71,22 @@ func getLastRefreshedOnTime(feedStates []model.FeedState) int64 {
 	return lastRefreshed
 }
 
-func (s *Server) feverAuth(r *http.Request) bool {
-	if s.Username != "" && s.Password != "" {
-		apiKey := r.FormValue("api_key")
-		apiKey = strings.ToLower(apiKey)
-		md5HashValue := md5.Sum(fmt.Appendf(nil, "%s:%s", s.Username, s.Password))
-		hexMD5HashValue := fmt.Sprintf("%x", md5HashValue[:])
-		if !auth.StringsEqual(apiKey, hexMD5HashValue) {
-			return false
-		}
-		return true
+// feverAuth returns the user owning the api key.
+// Besides the md5 of the credentials, the api key may be an API token.
+func (s *Server) feverAuth(r *http.Request) (int64, bool) {
+	apiKey := r.FormValue("api_key")
+	apiKey = strings.ToLower(apiKey)
+	if userID, ok := s.checkFeverKey(apiKey); ok {
+		return userID, true
 	}
-	return false
+	if apiKey == "" {
+		return 0, false
+	}
+	userID, readOnly, ok := s.lookupToken(apiKey)
+	if ok && readOnly && (formHasValue(r.Form, "mark") || formHasValue(r.Form, "unread_recently_read")) {
+		return 0, false
+	}
+	return userID, ok
 }
 
 func formHasValue(values url.Values, value string) bool {
@@ -94,7 +98,8 @@ func formHasValue(values url.Values, value string) bool {
 
 func (s *Server) handleFever(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
-	if !s.feverAuth(r) {
+	userID, ok := s.feverAuth(r)
+	if !ok {
 		writeJSON(w, http.StatusOK, map[string]any{
 			"api_version":            3,
 			"auth":                   0,
@@ -102,6 +107,7 @@ func (s *Server) handleFever(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	s = s.forUser(userID)
 
 	switch {
 	case formHasValue(r.Form, "groups"):
@@ -251,14 +257,20 @@ func (s *Server) feverItemsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	items := s.db.ListItems(filter, listLimit, true, true)
+	itemIDs := make([]int64, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.Id
+	}
+	itemLabels := s.db.ListItemLabels(itemIDs)
 
 	feverItems := make([]FeverItem, len(items))
 	for i, item := range items {
 		date := item.Date
 		time := date.Unix()
 
+		// labelled items are exposed as saved, same as starred ones
 		isSaved := 0
-		if item.Status == model.STARRED {
+		if item.Status == model.STARRED || len(itemLabels[item.Id]) > 0 {
 			isSaved = 1
 		}
 		isRead := 0
@@ -269,7 +281,7 @@ func (s *Server) feverItemsHandler(w http.ResponseWriter, r *http.Request) {
 			ID:        item.Id,
 			FeedID:    item.FeedId,
 			Title:     item.Title,
-			Author:    "",
+			Author:    item.Author,
 			HTML:      item.Content,
 			Url:       item.Link,
 			IsSaved:   isSaved,
@@ -278,7 +290,7 @@ func (s *Server) feverItemsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	totalItems := s.db.CountItems()
+	totalItems := s.db.CountItems(model.ItemFilter{})
 
 	states, _ := s.db.ListFeedStates()
 	writeFeverJSON(w, map[string]any{
@@ -334,6 +346,11 @@ func (s *Server) feverSavedItemIDsHandler(w http.ResponseWriter, r *http.Request
 		}
 		itemFilter.After = &items[len(items)-1].Id
 	}
+	for _, itemID := range slices.Sorted(maps.Keys(s.db.ListItemLabels(nil))) {
+		if !slices.Contains(itemIds, itemID) {
+			itemIds = append(itemIds, itemID)
+		}
+	}
 	states, _ := s.db.ListFeedStates()
 	writeFeverJSON(w, map[string]any{
 		"saved_item_ids": joinInts(itemIds),
@@ -363,7 +380,12 @@ func (s *Server) feverMarkHandler(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		s.db.UpdateItemStatus(id, status)
+		s.updateItemStatus(id, status)
+		if r.Form.Get("as") == "unsaved" {
+			for _, labelID := range s.db.ListItemLabels([]int64{id})[id] {
+				s.db.RemoveItemLabel(id, labelID)
+			}
+		}
 	case "feed":
 		if r.Form.Get("as") != "read" {
 			w.WriteHeader(http.StatusBadRequest)
@@ -374,7 +396,7 @@ func (s *Server) feverMarkHandler(w http.ResponseWriter, r *http.Request) {
 			before := time.Unix(x, 0).UTC()
 			markFilter.Before = &before
 		}
-		s.db.MarkItemsRead(markFilter)
+		s.markItemsRead(markFilter)
 	case "group":
 		if r.Form.Get("as") != "read" {
 			w.WriteHeader(http.StatusBadRequest)
@@ -388,7 +410,7 @@ func (s *Server) feverMarkHandler(w http.ResponseWriter, r *http.Request) {
 			before := time.Unix(x, 0).UTC()
 			markFilter.Before = &before
 		}
-		s.db.MarkItemsRead(markFilter)
+		s.markItemsRead(markFilter)
 	default:
 		w.WriteHeader(http.StatusBadRequest)
 		return
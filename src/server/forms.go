@@ -1,6 +1,11 @@
 package server
 
-import "github.com/nkanaev/yarr/src/storage/model"
+import (
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
 
 type ItemUpdateForm struct {
 	Status *model.ItemStatus `json:"status,omitempty"`
@@ -15,8 +20,83 @@ type FolderUpdateForm struct {
 	IsExpanded *bool   `json:"is_expanded,omitempty"`
 }
 
+type LabelForm struct {
+	Title string `json:"title"`
+}
+
+type RuleForm struct {
+	Title      string               `json:"title"`
+	FeedID     *int64               `json:"feed_id"`
+	Conditions model.RuleConditions `json:"conditions"`
+	Action     model.RuleAction     `json:"action"`
+	IsEnabled  *bool                `json:"is_enabled"`
+}
+
+type SavedSearchForm struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// unread, read or starred; all the items if empty
+	Status *string `json:"status"`
+}
+
+type WebhookForm struct {
+	Title    string              `json:"title"`
+	URL      string              `json:"url"`
+	FolderID *int64              `json:"folder_id"`
+	FeedID   *int64              `json:"feed_id"`
+	Keyword  string              `json:"keyword"`
+	Format   model.WebhookFormat `json:"format"`
+	Template string              `json:"template"`
+	// the current secret is kept if missing, removed if empty
+	Secret    *string `json:"secret"`
+	IsEnabled *bool   `json:"is_enabled"`
+}
+
+type OutputFeedForm struct {
+	Source   model.OutputSource `json:"source"`
+	SourceID *int64             `json:"source_id"`
+}
+
 type FeedCreateForm struct {
 	Url           string `json:"url"`
 	TitleOverride string `json:"title_override,omitempty"`
 	FolderID      *int64 `json:"folder_id,omitempty"`
+	// scrape the page at Url instead of looking for its feed
+	Scraper *FeedScraperForm `json:"scraper,omitempty"`
+}
+
+type FeedScraperForm struct {
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Date    string `json:"date"`
+	Content string `json:"content"`
+}
+
+func (f FeedScraperForm) scraper(feedID int64) model.FeedScraper {
+	return model.FeedScraper{
+		FeedID:  feedID,
+		Item:    strings.TrimSpace(f.Item),
+		Title:   strings.TrimSpace(f.Title),
+		Link:    strings.TrimSpace(f.Link),
+		Date:    strings.TrimSpace(f.Date),
+		Content: strings.TrimSpace(f.Content),
+	}
+}
+
+type APIKeyCreateForm struct {
+	Name      string     `json:"name"`
+	ReadOnly  bool       `json:"read_only"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UserCreateForm struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+type UserUpdateForm struct {
+	Password *string `json:"password,omitempty"`
+	IsAdmin  *bool   `json:"is_admin,omitempty"`
 }
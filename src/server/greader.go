@@ -0,0 +1,778 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// Google Reader API, as implemented by FreshRSS, Miniflux, Inoreader & co.
+// Reference: https://feedhq.readthedocs.io/en/latest/api/
+
+const (
+	greaderReadingList = "user/-/state/com.google/reading-list"
+	greaderRead        = "user/-/state/com.google/read"
+	greaderStarred     = "user/-/state/com.google/starred"
+	greaderKeptUnread  = "user/-/state/com.google/kept-unread"
+	greaderLabelPrefix = "user/-/label/"
+	greaderFeedPrefix  = "feed/"
+	greaderItemPrefix  = "tag:google.com,2005:reader/item/"
+)
+
+// for memory pressure reasons, stream requests are capped
+const greaderListLimit = 1000
+
+type GReaderTag struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+type GReaderSubscription struct {
+	ID         string       `json:"id"`
+	Title      string       `json:"title"`
+	Categories []GReaderTag `json:"categories"`
+	Url        string       `json:"url"`
+	HtmlUrl    string       `json:"htmlUrl"`
+	IconUrl    string       `json:"iconUrl"`
+}
+
+type GReaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type GReaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type GReaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HtmlUrl  string `json:"htmlUrl"`
+}
+
+type GReaderItem struct {
+	ID            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Author        string         `json:"author"`
+	Canonical     []GReaderLink  `json:"canonical"`
+	Alternate     []GReaderLink  `json:"alternate"`
+	Categories    []string       `json:"categories"`
+	Origin        GReaderOrigin  `json:"origin"`
+	Summary       GReaderContent `json:"summary"`
+	Enclosure     []GReaderLink  `json:"enclosure,omitempty"`
+}
+
+type GReaderItemRef struct {
+	ID string `json:"id"`
+}
+
+type GReaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int64  `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+func greaderToken(username, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("greader:" + username))
+	return username + "/" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func greaderRequestToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+	return strings.TrimSpace(token)
+}
+
+// greaderAuth returns the user the request's token was issued to.
+func (s *Server) greaderAuth(r *http.Request) (int64, bool) {
+	token := greaderRequestToken(r)
+	pos := strings.LastIndex(token, "/")
+	if pos == -1 {
+		return 0, false
+	}
+	username := token[:pos]
+	userID, key, ok := s.lookupUser(username)
+	if !ok || !auth.StringsEqual(token, greaderToken(username, key)) {
+		return 0, false
+	}
+	return userID, true
+}
+
+func (s *Server) greaderHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reader/api/0/token", s.userHandler((*Server).handleGReaderToken))
+	mux.HandleFunc("/reader/api/0/user-info", s.userHandler((*Server).handleGReaderUserInfo))
+	mux.HandleFunc("/reader/api/0/subscription/list", s.userHandler((*Server).handleGReaderSubscriptionList))
+	mux.HandleFunc("/reader/api/0/subscription/edit", s.userHandler((*Server).handleGReaderSubscriptionEdit))
+	mux.HandleFunc("/reader/api/0/subscription/quickadd", s.userHandler((*Server).handleGReaderQuickAdd))
+	mux.HandleFunc("/reader/api/0/tag/list", s.userHandler((*Server).handleGReaderTagList))
+	mux.HandleFunc("/reader/api/0/rename-tag", s.userHandler((*Server).handleGReaderRenameTag))
+	mux.HandleFunc("/reader/api/0/disable-tag", s.userHandler((*Server).handleGReaderDisableTag))
+	mux.HandleFunc("/reader/api/0/unread-count", s.userHandler((*Server).handleGReaderUnreadCount))
+	mux.HandleFunc("/reader/api/0/stream/items/ids", s.userHandler((*Server).handleGReaderItemIDs))
+	mux.HandleFunc("/reader/api/0/stream/items/contents", s.userHandler((*Server).handleGReaderItemContents))
+	mux.HandleFunc("/reader/api/0/stream/contents/{stream...}", s.userHandler((*Server).handleGReaderStreamContents))
+	mux.HandleFunc("/reader/api/0/edit-tag", s.userHandler((*Server).handleGReaderEditTag))
+	mux.HandleFunc("/reader/api/0/mark-all-as-read", s.userHandler((*Server).handleGReaderMarkAllAsRead))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := s.greaderAuth(r)
+		if !ok {
+			w.Header().Set("Google-Bad-Token", "true")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		mux.ServeHTTP(w, auth.WithUser(r, userID))
+	})
+}
+
+func (s *Server) handleGReaderLogin(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	username := r.Form.Get("Email")
+	password := r.Form.Get("Passwd")
+	if _, ok := s.checkPassword(username, password); !ok {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	_, key, _ := s.lookupUser(username)
+	token := greaderToken(username, key)
+	if r.Form.Get("output") == "json" {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"SID":  token,
+			"LSID": token,
+			"Auth": token,
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+func (s *Server) handleGReaderToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	token := greaderRequestToken(r)
+	// clients expect a short-lived token of 57 characters
+	if len(token) > 57 {
+		token = token[len(token)-57:]
+	}
+	fmt.Fprintln(w, token)
+}
+
+func (s *Server) handleGReaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        strconv.FormatInt(s.db.UserID(), 10),
+		"userName":      s.userName(),
+		"userProfileId": strconv.FormatInt(s.db.UserID(), 10),
+		"userEmail":     "",
+	})
+}
+
+func greaderFeedID(feedID int64) string {
+	return greaderFeedPrefix + strconv.FormatInt(feedID, 10)
+}
+
+func greaderLabel(title string) string {
+	return greaderLabelPrefix + title
+}
+
+func greaderLongItemID(id int64) string {
+	return fmt.Sprintf("%s%016x", greaderItemPrefix, id)
+}
+
+// parseGReaderItemID accepts both long (tag:google.com,...) and short (decimal) item ids.
+func parseGReaderItemID(id string) (int64, error) {
+	if hexid, ok := strings.CutPrefix(id, greaderItemPrefix); ok {
+		value, err := strconv.ParseUint(hexid, 16, 64)
+		return int64(value), err
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+func (s *Server) greaderFindFolder(stream string) *model.Folder {
+	title, ok := strings.CutPrefix(stream, greaderLabelPrefix)
+	if !ok {
+		return nil
+	}
+	for _, folder := range s.db.ListFolders() {
+		if folder.Title == title {
+			return &folder
+		}
+	}
+	return nil
+}
+
+// greaderFindLabel resolves item labels, which share the label namespace with folders.
+func (s *Server) greaderFindLabel(stream string) *model.Label {
+	title, ok := strings.CutPrefix(stream, greaderLabelPrefix)
+	if !ok {
+		return nil
+	}
+	for _, label := range s.db.ListLabels() {
+		if label.Title == title {
+			return &label
+		}
+	}
+	return nil
+}
+
+// greaderFindFeed resolves `feed/<id>` as well as `feed/<url>` stream ids.
+func (s *Server) greaderFindFeed(stream string) *model.Feed {
+	value, ok := strings.CutPrefix(stream, greaderFeedPrefix)
+	if !ok {
+		return nil
+	}
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return s.db.GetFeed(id)
+	}
+	for _, feed := range s.db.ListFeeds() {
+		if feed.FeedLink == value {
+			return &feed
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleGReaderSubscriptionList(w http.ResponseWriter, r *http.Request) {
+	folders := make(map[int64]string)
+	for _, folder := range s.db.ListFolders() {
+		folders[folder.Id] = folder.Title
+	}
+
+	subscriptions := make([]GReaderSubscription, 0)
+	for _, feed := range s.db.ListFeeds() {
+		categories := make([]GReaderTag, 0)
+		if feed.FolderId != nil {
+			title := folders[*feed.FolderId]
+			categories = append(categories, GReaderTag{ID: greaderLabel(title), Label: title})
+		}
+		iconUrl := ""
+		if feed.Icon != nil {
+			iconUrl = feed.Icon.DataURI()
+		}
+		subscriptions = append(subscriptions, GReaderSubscription{
+			ID:         greaderFeedID(feed.Id),
+			Title:      feed.Title,
+			Categories: categories,
+			Url:        feed.FeedLink,
+			HtmlUrl:    feed.Link,
+			IconUrl:    iconUrl,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subscriptions})
+}
+
+func (s *Server) greaderFolderFromLabel(label string) *int64 {
+	title, ok := strings.CutPrefix(label, greaderLabelPrefix)
+	if !ok || title == "" {
+		return nil
+	}
+	folder := s.db.CreateFolder(title)
+	if folder == nil {
+		return nil
+	}
+	return &folder.Id
+}
+
+func (s *Server) handleGReaderSubscriptionEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	for _, stream := range r.Form["s"] {
+		switch r.Form.Get("ac") {
+		case "subscribe":
+			url, ok := strings.CutPrefix(stream, greaderFeedPrefix)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			result, err := worker.DiscoverFeed(url)
+			if err != nil || result.Feed == nil {
+				log.Printf("Failed to discover feed for %s: %v", url, err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.createDiscoveredFeed(result, r.Form.Get("t"), s.greaderFolderFromLabel(r.Form.Get("a")))
+		case "unsubscribe":
+			if feed := s.greaderFindFeed(stream); feed != nil {
+				s.db.DeleteFeed(feed.Id)
+			}
+		case "edit":
+			feed := s.greaderFindFeed(stream)
+			if feed == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			params := model.UpdateFeedParams{}
+			if title := r.Form.Get("t"); title != "" {
+				params.Title = &title
+			}
+			if label := r.Form.Get("a"); label != "" {
+				params.FolderID = model.SetNullable(s.greaderFolderFromLabel(label))
+			} else if label := r.Form.Get("r"); label != "" {
+				params.FolderID = model.SetNullable[int64](nil)
+			}
+			s.db.UpdateFeed(feed.Id, params)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	writeGReaderOK(w)
+}
+
+func (s *Server) handleGReaderQuickAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	url := strings.TrimPrefix(r.Form.Get("quickadd"), greaderFeedPrefix)
+	result, err := worker.DiscoverFeed(url)
+	if err != nil || result.Feed == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"numResults": 0})
+		return
+	}
+	feed := s.createDiscoveredFeed(result, "", nil)
+	if feed == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"numResults": 0})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"numResults": 1,
+		"query":      feed.FeedLink,
+		"streamId":   greaderFeedID(feed.Id),
+		"streamName": feed.Title,
+	})
+}
+
+func (s *Server) handleGReaderTagList(w http.ResponseWriter, r *http.Request) {
+	tags := []GReaderTag{{ID: greaderStarred}}
+	for _, folder := range s.db.ListFolders() {
+		tags = append(tags, GReaderTag{ID: greaderLabel(folder.Title), Type: "folder"})
+	}
+	for _, label := range s.db.ListLabels() {
+		tags = append(tags, GReaderTag{ID: greaderLabel(label.Title), Type: "tag"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (s *Server) handleGReaderRenameTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	title, ok := strings.CutPrefix(r.Form.Get("dest"), greaderLabelPrefix)
+	if !ok || title == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if folder := s.greaderFindFolder(r.Form.Get("s")); folder != nil {
+		s.db.UpdateFolder(folder.Id, model.UpdateFolderParams{Title: &title})
+	} else if label := s.greaderFindLabel(r.Form.Get("s")); label != nil {
+		s.db.UpdateLabel(label.Id, model.UpdateLabelParams{Title: &title})
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeGReaderOK(w)
+}
+
+func (s *Server) handleGReaderDisableTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if folder := s.greaderFindFolder(r.Form.Get("s")); folder != nil {
+		s.db.DeleteFolder(folder.Id)
+	} else if label := s.greaderFindLabel(r.Form.Get("s")); label != nil {
+		s.db.DeleteLabel(label.Id)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeGReaderOK(w)
+}
+
+func (s *Server) handleGReaderUnreadCount(w http.ResponseWriter, r *http.Request) {
+	folders := make(map[int64]string)
+	for _, folder := range s.db.ListFolders() {
+		folders[folder.Id] = folder.Title
+	}
+	feedFolders := make(map[int64]*int64)
+	for _, feed := range s.db.ListFeeds() {
+		feedFolders[feed.Id] = feed.FolderId
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMicro(), 10)
+	var total int64
+	folderCounts := make(map[int64]int64)
+	counts := make([]GReaderUnreadCount, 0)
+	for _, stat := range s.db.FeedStats() {
+		if stat.UnreadCount == 0 {
+			continue
+		}
+		total += stat.UnreadCount
+		if folderID := feedFolders[stat.FeedId]; folderID != nil {
+			folderCounts[*folderID] += stat.UnreadCount
+		}
+		counts = append(counts, GReaderUnreadCount{
+			ID:                      greaderFeedID(stat.FeedId),
+			Count:                   stat.UnreadCount,
+			NewestItemTimestampUsec: now,
+		})
+	}
+	for folderID, count := range folderCounts {
+		counts = append(counts, GReaderUnreadCount{
+			ID:                      greaderLabel(folders[folderID]),
+			Count:                   count,
+			NewestItemTimestampUsec: now,
+		})
+	}
+	counts = append(counts, GReaderUnreadCount{
+		ID:                      greaderReadingList,
+		Count:                   total,
+		NewestItemTimestampUsec: now,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"max":          total,
+		"unreadcounts": counts,
+	})
+}
+
+// greaderStreamFilter translates stream query params into an item filter.
+// Returns false if the stream does not exist.
+func (s *Server) greaderStreamFilter(r *http.Request, stream string) (model.ItemFilter, bool) {
+	filter := model.ItemFilter{}
+	switch {
+	case stream == "" || stream == greaderReadingList:
+	case stream == greaderStarred:
+		status := model.STARRED
+		filter.Status = &status
+	case stream == greaderRead:
+		status := model.READ
+		filter.Status = &status
+	case strings.HasPrefix(stream, greaderLabelPrefix):
+		if folder := s.greaderFindFolder(stream); folder != nil {
+			filter.FolderID = &folder.Id
+		} else if label := s.greaderFindLabel(stream); label != nil {
+			filter.Label = &label.Id
+		} else {
+			return filter, false
+		}
+	case strings.HasPrefix(stream, greaderFeedPrefix):
+		feed := s.greaderFindFeed(stream)
+		if feed == nil {
+			return filter, false
+		}
+		filter.FeedID = &feed.Id
+	default:
+		return filter, false
+	}
+
+	for _, exclude := range r.Form["xt"] {
+		if exclude == greaderRead && filter.Status == nil {
+			status := model.UNREAD
+			filter.Status = &status
+		}
+	}
+	for _, include := range r.Form["it"] {
+		switch include {
+		case greaderStarred:
+			status := model.STARRED
+			filter.Status = &status
+		case greaderKeptUnread:
+			status := model.UNREAD
+			filter.Status = &status
+		}
+	}
+	if ot, err := strconv.ParseInt(r.Form.Get("ot"), 10, 64); err == nil && ot > 0 {
+		since := time.Unix(ot, 0).UTC()
+		filter.Since = &since
+	}
+	if nt, err := strconv.ParseInt(r.Form.Get("nt"), 10, 64); err == nil && nt > 0 {
+		before := time.Unix(nt, 0).UTC()
+		filter.Before = &before
+	}
+	if c, err := strconv.ParseInt(r.Form.Get("c"), 10, 64); err == nil {
+		filter.After = &c
+	}
+	return filter, true
+}
+
+func greaderListParams(r *http.Request) (limit int, newestFirst bool) {
+	limit = 20
+	if n, err := strconv.Atoi(r.Form.Get("n")); err == nil && n > 0 {
+		limit = min(n, greaderListLimit)
+	}
+	newestFirst = r.Form.Get("r") != "o"
+	return limit, newestFirst
+}
+
+func (s *Server) handleGReaderItemIDs(w http.ResponseWriter, r *http.Request) {
+	filter, ok := s.greaderStreamFilter(r, r.Form.Get("s"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	limit, newestFirst := greaderListParams(r)
+	items := s.db.ListItems(filter, limit+1, newestFirst, false)
+
+	result := map[string]any{}
+	if len(items) > limit {
+		items = items[:limit]
+		result["continuation"] = strconv.FormatInt(items[len(items)-1].Id, 10)
+	}
+	refs := make([]GReaderItemRef, len(items))
+	for i, item := range items {
+		refs[i] = GReaderItemRef{ID: strconv.FormatInt(item.Id, 10)}
+	}
+	result["itemRefs"] = refs
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGReaderStreamContents(w http.ResponseWriter, r *http.Request) {
+	stream := r.PathValue("stream")
+	if stream == "" {
+		stream = r.Form.Get("s")
+	}
+	filter, ok := s.greaderStreamFilter(r, stream)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	limit, newestFirst := greaderListParams(r)
+	items := s.db.ListItems(filter, limit+1, newestFirst, true)
+
+	result := map[string]any{
+		"id":      stream,
+		"updated": time.Now().Unix(),
+	}
+	if len(items) > limit {
+		items = items[:limit]
+		result["continuation"] = strconv.FormatInt(items[len(items)-1].Id, 10)
+	}
+	result["items"] = s.greaderItems(items)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGReaderItemContents(w http.ResponseWriter, r *http.Request) {
+	ids := make([]int64, 0)
+	for _, value := range r.Form["i"] {
+		if id, err := parseGReaderItemID(value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	items := make([]model.Item, 0)
+	if len(ids) > 0 {
+		items = s.db.ListItems(model.ItemFilter{IDs: &ids}, len(ids), true, true)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      greaderReadingList,
+		"updated": time.Now().Unix(),
+		"items":   s.greaderItems(items),
+	})
+}
+
+func (s *Server) greaderItems(items []model.Item) []GReaderItem {
+	folders := make(map[int64]string)
+	for _, folder := range s.db.ListFolders() {
+		folders[folder.Id] = folder.Title
+	}
+	feeds := make(map[int64]model.Feed)
+	for _, feed := range s.db.ListFeeds() {
+		feeds[feed.Id] = feed
+	}
+	labels := make(map[int64]string)
+	for _, label := range s.db.ListLabels() {
+		labels[label.Id] = label.Title
+	}
+	itemIDs := make([]int64, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.Id
+	}
+	itemLabels := s.db.ListItemLabels(itemIDs)
+
+	result := make([]GReaderItem, len(items))
+	for i, item := range items {
+		feed := feeds[item.FeedId]
+
+		categories := []string{greaderReadingList}
+		if feed.FolderId != nil {
+			categories = append(categories, greaderLabel(folders[*feed.FolderId]))
+		}
+		if item.Status != model.UNREAD {
+			categories = append(categories, greaderRead)
+		}
+		if item.Status == model.STARRED {
+			categories = append(categories, greaderStarred)
+		}
+		for _, labelID := range itemLabels[item.Id] {
+			categories = append(categories, greaderLabel(labels[labelID]))
+		}
+		// the publisher's categories, as plain strings
+		categories = append(categories, item.Categories...)
+
+		enclosures := make([]GReaderLink, 0)
+		for _, link := range item.MediaLinks {
+			enclosures = append(enclosures, GReaderLink{Href: link.URL, Type: link.Type})
+		}
+
+		result[i] = GReaderItem{
+			ID:            greaderLongItemID(item.Id),
+			CrawlTimeMsec: strconv.FormatInt(item.Date.UnixMilli(), 10),
+			TimestampUsec: strconv.FormatInt(item.Date.UnixMicro(), 10),
+			Published:     item.Date.Unix(),
+			Updated:       item.Date.Unix(),
+			Title:         item.Title,
+			Author:        item.Author,
+			Canonical:     []GReaderLink{{Href: item.Link}},
+			Alternate:     []GReaderLink{{Href: item.Link, Type: "text/html"}},
+			Categories:    categories,
+			Origin: GReaderOrigin{
+				StreamID: greaderFeedID(item.FeedId),
+				Title:    feed.Title,
+				HtmlUrl:  feed.Link,
+			},
+			Summary:   GReaderContent{Direction: "ltr", Content: item.Content},
+			Enclosure: enclosures,
+		}
+	}
+	return result
+}
+
+func (s *Server) handleGReaderEditTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ids := make([]int64, 0)
+	for _, value := range r.Form["i"] {
+		if id, err := parseGReaderItemID(value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	add := make(map[string]bool)
+	for _, tag := range r.Form["a"] {
+		add[tag] = true
+	}
+	remove := make(map[string]bool)
+	for _, tag := range r.Form["r"] {
+		remove[tag] = true
+	}
+
+	// user labels are attached to items, created on first use
+	addLabels := make([]*model.Label, 0)
+	for tag := range add {
+		if title, ok := strings.CutPrefix(tag, greaderLabelPrefix); ok && title != "" {
+			label := s.greaderFindLabel(tag)
+			if label == nil {
+				label = s.db.CreateLabel(title)
+			}
+			if label != nil {
+				addLabels = append(addLabels, label)
+			}
+		}
+	}
+	removeLabels := make([]*model.Label, 0)
+	for tag := range remove {
+		if label := s.greaderFindLabel(tag); label != nil {
+			removeLabels = append(removeLabels, label)
+		}
+	}
+
+	// yarr keeps a single status per item, where starred implies read
+	for _, item := range s.db.ListItems(model.ItemFilter{IDs: &ids}, len(ids), true, false) {
+		status := item.Status
+		switch {
+		case add[greaderStarred]:
+			status = model.STARRED
+		case remove[greaderStarred] && status == model.STARRED:
+			status = model.READ
+		}
+		switch {
+		case add[greaderRead] && status == model.UNREAD:
+			status = model.READ
+		case remove[greaderRead] || add[greaderKeptUnread]:
+			if status == model.READ {
+				status = model.UNREAD
+			}
+		}
+		if status != item.Status {
+			s.updateItemStatus(item.Id, status)
+		}
+		for _, label := range addLabels {
+			s.db.AddItemLabel(item.Id, label.Id)
+		}
+		for _, label := range removeLabels {
+			s.db.RemoveItemLabel(item.Id, label.Id)
+		}
+	}
+	writeGReaderOK(w)
+}
+
+func (s *Server) handleGReaderMarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	filter := model.MarkFilter{}
+	stream := r.Form.Get("s")
+	switch {
+	case stream == "" || stream == greaderReadingList:
+	case strings.HasPrefix(stream, greaderLabelPrefix):
+		if folder := s.greaderFindFolder(stream); folder != nil {
+			filter.FolderID = &folder.Id
+		} else if label := s.greaderFindLabel(stream); label != nil {
+			filter.Label = &label.Id
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case strings.HasPrefix(stream, greaderFeedPrefix):
+		feed := s.greaderFindFeed(stream)
+		if feed == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		filter.FeedID = &feed.Id
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if ts, err := strconv.ParseInt(r.Form.Get("ts"), 10, 64); err == nil && ts > 0 {
+		before := time.UnixMicro(ts).UTC()
+		filter.Before = &before
+	}
+	s.markItemsRead(filter)
+	writeGReaderOK(w)
+}
+
+func writeGReaderOK(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("OK"))
+}
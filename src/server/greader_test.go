@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestGReader(t *testing.T) {
+	db, serve := newTestServer(t, withCredentials("user", "pass"))
+	folder := db.CreateFolder("News")
+	feed := db.CreateFeed(model.CreateFeedParams{
+		Title:    "Feed",
+		FeedLink: "http://example.com/feed.xml",
+		FolderID: &folder.Id,
+	})
+	now := time.Now()
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "one", Date: now.Add(-time.Hour)},
+		{GUID: "2", FeedId: feed.Id, Title: "two", Date: now},
+	})
+
+	var token string
+	request := func(method, path string, form url.Values) *http.Response {
+		header := http.Header{}
+		if form != nil {
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if token != "" {
+			header.Set("Authorization", "GoogleLogin auth="+token)
+		}
+		return serve(method, path, form.Encode(), header).Result()
+	}
+
+	t.Run("login", func(t *testing.T) {
+		res := request("POST", "/accounts/ClientLogin", url.Values{"Email": {"user"}, "Passwd": {"wrong"}})
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", res.StatusCode)
+		}
+		res = request("POST", "/accounts/ClientLogin", url.Values{"Email": {"user"}, "Passwd": {"pass"}})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		body, _ := io.ReadAll(res.Body)
+		for line := range strings.SplitSeq(string(body), "\n") {
+			if value, ok := strings.CutPrefix(line, "Auth="); ok {
+				token = value
+			}
+		}
+		if token == "" {
+			t.Fatalf("no auth token in %q", body)
+		}
+	})
+
+	t.Run("subscription list", func(t *testing.T) {
+		res := request("GET", "/reader/api/0/subscription/list?output=json", nil)
+		var data struct {
+			Subscriptions []GReaderSubscription `json:"subscriptions"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Subscriptions) != 1 {
+			t.Fatalf("expected 1 subscription, got %#v", data)
+		}
+		sub := data.Subscriptions[0]
+		if sub.ID != "feed/1" || len(sub.Categories) != 1 || sub.Categories[0].ID != "user/-/label/News" {
+			t.Errorf("unexpected subscription: %#v", sub)
+		}
+	})
+
+	t.Run("stream contents", func(t *testing.T) {
+		res := request("GET", "/reader/api/0/stream/contents/user/-/label/News?n=1", nil)
+		var data struct {
+			Items        []GReaderItem `json:"items"`
+			Continuation string        `json:"continuation"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 1 || data.Items[0].Title != "two" {
+			t.Fatalf("unexpected items: %#v", data.Items)
+		}
+		if data.Continuation == "" {
+			t.Fatal("expected continuation")
+		}
+		res = request("GET", "/reader/api/0/stream/contents/user/-/label/News?n=1&c="+data.Continuation, nil)
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 1 || data.Items[0].Title != "one" {
+			t.Fatalf("unexpected items: %#v", data.Items)
+		}
+	})
+
+	t.Run("edit tag", func(t *testing.T) {
+		items := db.ListItems(model.ItemFilter{}, 10, true, false)
+		res := request("POST", "/reader/api/0/edit-tag", url.Values{
+			"i": {greaderLongItemID(items[0].Id)},
+			"a": {greaderStarred},
+		})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		if item := db.GetItem(items[0].Id); item.Status != model.STARRED {
+			t.Errorf("expected starred, got %v", item.Status)
+		}
+
+		res = request("GET", "/reader/api/0/stream/items/ids?s=user/-/state/com.google/reading-list&xt=user/-/state/com.google/read", nil)
+		var data struct {
+			ItemRefs []GReaderItemRef `json:"itemRefs"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.ItemRefs) != 1 {
+			t.Errorf("expected 1 unread item, got %#v", data.ItemRefs)
+		}
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		items := db.ListItems(model.ItemFilter{}, 10, true, false)
+		res := request("POST", "/reader/api/0/edit-tag", url.Values{
+			"i": {greaderLongItemID(items[1].Id)},
+			"a": {"user/-/label/later"},
+		})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+
+		res = request("GET", "/reader/api/0/tag/list?output=json", nil)
+		var tags struct {
+			Tags []GReaderTag `json:"tags"`
+		}
+		json.NewDecoder(res.Body).Decode(&tags)
+		found := false
+		for _, tag := range tags.Tags {
+			found = found || (tag.ID == "user/-/label/later" && tag.Type == "tag")
+		}
+		if !found {
+			t.Fatalf("expected label in tag list, got %#v", tags.Tags)
+		}
+
+		res = request("GET", "/reader/api/0/stream/contents/user/-/label/later", nil)
+		var data struct {
+			Items []GReaderItem `json:"items"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 1 || data.Items[0].Title != items[1].Title {
+			t.Fatalf("unexpected items: %#v", data.Items)
+		}
+		if !slices.Contains(data.Items[0].Categories, "user/-/label/later") {
+			t.Errorf("expected label in categories, got %v", data.Items[0].Categories)
+		}
+
+		request("POST", "/reader/api/0/edit-tag", url.Values{
+			"i": {greaderLongItemID(items[1].Id)},
+			"r": {"user/-/label/later"},
+		})
+		res = request("GET", "/reader/api/0/stream/contents/user/-/label/later", nil)
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 0 {
+			t.Errorf("expected no labelled items, got %#v", data.Items)
+		}
+	})
+
+	t.Run("mark all as read", func(t *testing.T) {
+		request("POST", "/reader/api/0/mark-all-as-read", url.Values{"s": {"feed/1"}})
+		unread := model.UNREAD
+		if items := db.ListItems(model.ItemFilter{Status: &unread}, 10, true, false); len(items) != 0 {
+			t.Errorf("expected no unread items, got %d", len(items))
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// handleMedia serves the local copy of an enclosure. Range requests are
+// supported, so players can seek and resume.
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	file, err := s.db.GetMediaFile(id)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if file == nil || s.Media.Dir == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.Media.Dir, file.Path))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Print(err)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	if file.MimeType != "" {
+		w.Header().Set("Content-Type", file.MimeType)
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, file.Path, file.CreatedAt, f)
+}
+
+// localMediaLinks points the media links of the items
+// to their local copies, if there are any.
+func (s *Server) localMediaLinks(items []model.Item) {
+	if s.Media.Dir == "" || len(items) == 0 {
+		return
+	}
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+	files, err := s.db.ListMediaFiles(ids)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	local := make(map[int64]map[string]int64)
+	for _, file := range files {
+		if local[file.ItemID] == nil {
+			local[file.ItemID] = make(map[string]int64)
+		}
+		local[file.ItemID][file.URL] = file.Id
+	}
+	for _, item := range items {
+		for i, link := range item.MediaLinks {
+			if id, ok := local[item.Id][link.URL]; ok {
+				item.MediaLinks[i].URL = fmt.Sprintf("%s/media/%d", s.BasePath, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestMedia(t *testing.T) {
+	dir := t.TempDir()
+	db, serve := newTestServer(t, withCredentials("admin", "pass"), func(s *Server) { s.Media.Dir = dir })
+	token, hash := auth.NewToken()
+	if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "1-0123456789abcdef.mp3"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+	created, _ := db.CreateItems([]model.Item{{
+		GUID: "1", FeedId: feed.Id, Title: "episode",
+		MediaLinks: model.MediaLinks{{URL: "http://example.com/1.mp3", Type: "audio"}},
+	}})
+	file, err := db.CreateMediaFile(model.MediaFile{
+		ItemID: created[0].Id, URL: "http://example.com/1.mp3",
+		Path: "1-0123456789abcdef.mp3", MimeType: "audio/mpeg", Size: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bearer := http.Header{"Authorization": {"Bearer " + token}}
+	request := func(path string, header http.Header) *httptest.ResponseRecorder {
+		return serve("GET", path, "", header, bearer)
+	}
+	mediaURL := "/media/" + strconv.FormatInt(file.Id, 10)
+
+	res := request("/api/items/"+strconv.FormatInt(created[0].Id, 10), nil)
+	var item model.Item
+	if err := json.NewDecoder(res.Body).Decode(&item); err != nil {
+		t.Fatal(err)
+	}
+	if len(item.MediaLinks) != 1 || item.MediaLinks[0].URL != mediaURL {
+		t.Errorf("expected the media link to point to the local copy, got %#v", item.MediaLinks)
+	}
+
+	res = request(mediaURL, http.Header{"Range": {"bytes=2-5"}, "Accept-Encoding": {"gzip"}})
+	if res.Code != http.StatusPartialContent || res.Body.String() != "2345" {
+		t.Errorf("unexpected range response: %d %q", res.Code, res.Body.String())
+	}
+	if ctype := res.Header().Get("Content-Type"); ctype != "audio/mpeg" {
+		t.Errorf("unexpected content type: %q", ctype)
+	}
+
+	if res = request("/media/999", nil); res.Code != http.StatusNotFound {
+		t.Errorf("expected unknown media to be not found, got %d", res.Code)
+	}
+
+	if res = serve("GET", mediaURL, ""); res.Code == http.StatusOK {
+		t.Error("expected unauthenticated requests to be rejected")
+	}
+}
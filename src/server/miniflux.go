@@ -0,0 +1,788 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/server/opml"
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// Miniflux v1 REST API.
+// Reference: https://miniflux.app/docs/api.html
+
+// upper bound for the number of entries returned per request
+const minifluxMaxLimit = 1000
+
+type MinifluxCategory struct {
+	ID           int64  `json:"id"`
+	Title        string `json:"title"`
+	UserID       int64  `json:"user_id"`
+	HideGlobally bool   `json:"hide_globally"`
+	FeedCount    *int   `json:"feed_count,omitempty"`
+	TotalUnread  *int64 `json:"total_unread,omitempty"`
+}
+
+type MinifluxFeedIcon struct {
+	FeedID int64 `json:"feed_id"`
+	IconID int64 `json:"icon_id"`
+}
+
+type MinifluxFeed struct {
+	ID                  int64             `json:"id"`
+	UserID              int64             `json:"user_id"`
+	FeedURL             string            `json:"feed_url"`
+	SiteURL             string            `json:"site_url"`
+	Title               string            `json:"title"`
+	CheckedAt           time.Time         `json:"checked_at"`
+	EtagHeader          string            `json:"etag_header"`
+	LastModifiedHeader  string            `json:"last_modified_header"`
+	ParsingErrorMessage string            `json:"parsing_error_message"`
+	ParsingErrorCount   int               `json:"parsing_error_count"`
+	Disabled            bool              `json:"disabled"`
+	Category            *MinifluxCategory `json:"category"`
+	Icon                *MinifluxFeedIcon `json:"icon"`
+}
+
+type MinifluxEnclosure struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	EntryID  int64  `json:"entry_id"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+}
+
+type MinifluxEntry struct {
+	ID          int64               `json:"id"`
+	UserID      int64               `json:"user_id"`
+	FeedID      int64               `json:"feed_id"`
+	Status      string              `json:"status"`
+	Hash        string              `json:"hash"`
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	CommentsURL string              `json:"comments_url"`
+	PublishedAt time.Time           `json:"published_at"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ChangedAt   time.Time           `json:"changed_at"`
+	Content     string              `json:"content"`
+	Author      string              `json:"author"`
+	ShareCode   string              `json:"share_code"`
+	Starred     bool                `json:"starred"`
+	ReadingTime int                 `json:"reading_time"`
+	Enclosures  []MinifluxEnclosure `json:"enclosures"`
+	Feed        *MinifluxFeed       `json:"feed,omitempty"`
+	Tags        []string            `json:"tags"`
+}
+
+type MinifluxFeedCreateForm struct {
+	FeedURL    string `json:"feed_url"`
+	CategoryID *int64 `json:"category_id"`
+}
+
+type MinifluxFeedUpdateForm struct {
+	Title      *string `json:"title"`
+	FeedURL    *string `json:"feed_url"`
+	CategoryID *int64  `json:"category_id"`
+}
+
+type MinifluxCategoryForm struct {
+	Title string `json:"title"`
+}
+
+type MinifluxEntriesUpdateForm struct {
+	EntryIDs []int64 `json:"entry_ids"`
+	Status   string  `json:"status"`
+}
+
+type MinifluxDiscoverForm struct {
+	URL string `json:"url"`
+}
+
+func writeMinifluxError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error_message": message})
+}
+
+// minifluxAuth returns the user owning the api key or the credentials.
+// Read-only api keys are only accepted for requests that don't modify anything.
+func (s *Server) minifluxAuth(r *http.Request) (int64, bool) {
+	token := r.Header.Get("X-Auth-Token")
+	if token == "" {
+		token = auth.BearerToken(r)
+	}
+	if token != "" {
+		userID, readOnly, ok := s.lookupToken(token)
+		if !ok || (readOnly && !auth.IsReadOnly(r)) {
+			return 0, false
+		}
+		return userID, true
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		return s.checkPassword(username, password)
+	}
+	return 0, false
+}
+
+func (s *Server) minifluxHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/me", s.userHandler((*Server).handleMinifluxMe))
+	mux.HandleFunc("GET /v1/version", s.userHandler((*Server).handleMinifluxVersion))
+	mux.HandleFunc("POST /v1/discover", s.userHandler((*Server).handleMinifluxDiscover))
+
+	mux.HandleFunc("GET /v1/feeds", s.userHandler((*Server).handleMinifluxFeedList))
+	mux.HandleFunc("POST /v1/feeds", s.userHandler((*Server).handleMinifluxFeedCreate))
+	mux.HandleFunc("GET /v1/feeds/counters", s.userHandler((*Server).handleMinifluxFeedCounters))
+	mux.HandleFunc("PUT /v1/feeds/refresh", s.userHandler((*Server).handleMinifluxFeedsRefresh))
+	mux.HandleFunc("GET /v1/feeds/{id}", s.userHandler((*Server).handleMinifluxFeedGet))
+	mux.HandleFunc("PUT /v1/feeds/{id}", s.userHandler((*Server).handleMinifluxFeedUpdate))
+	mux.HandleFunc("DELETE /v1/feeds/{id}", s.userHandler((*Server).handleMinifluxFeedDelete))
+	mux.HandleFunc("PUT /v1/feeds/{id}/refresh", s.userHandler((*Server).handleMinifluxFeedRefresh))
+	mux.HandleFunc("GET /v1/feeds/{id}/icon", s.userHandler((*Server).handleMinifluxFeedIcon))
+	mux.HandleFunc("GET /v1/icons/{id}", s.userHandler((*Server).handleMinifluxFeedIcon))
+	mux.HandleFunc("GET /v1/feeds/{id}/entries", s.userHandler((*Server).handleMinifluxFeedEntries))
+	mux.HandleFunc("GET /v1/feeds/{id}/entries/{entryID}", s.userHandler((*Server).handleMinifluxEntryGet))
+	mux.HandleFunc("PUT /v1/feeds/{id}/mark-all-as-read", s.userHandler((*Server).handleMinifluxFeedMarkRead))
+
+	mux.HandleFunc("GET /v1/categories", s.userHandler((*Server).handleMinifluxCategoryList))
+	mux.HandleFunc("POST /v1/categories", s.userHandler((*Server).handleMinifluxCategoryCreate))
+	mux.HandleFunc("PUT /v1/categories/{id}", s.userHandler((*Server).handleMinifluxCategoryUpdate))
+	mux.HandleFunc("DELETE /v1/categories/{id}", s.userHandler((*Server).handleMinifluxCategoryDelete))
+	mux.HandleFunc("GET /v1/categories/{id}/feeds", s.userHandler((*Server).handleMinifluxCategoryFeeds))
+	mux.HandleFunc("GET /v1/categories/{id}/entries", s.userHandler((*Server).handleMinifluxCategoryEntries))
+	mux.HandleFunc("GET /v1/categories/{id}/entries/{entryID}", s.userHandler((*Server).handleMinifluxEntryGet))
+	mux.HandleFunc("PUT /v1/categories/{id}/mark-all-as-read", s.userHandler((*Server).handleMinifluxCategoryMarkRead))
+
+	mux.HandleFunc("GET /v1/entries", s.userHandler((*Server).handleMinifluxEntries))
+	mux.HandleFunc("PUT /v1/entries", s.userHandler((*Server).handleMinifluxEntriesUpdate))
+	mux.HandleFunc("GET /v1/entries/{entryID}", s.userHandler((*Server).handleMinifluxEntryGet))
+	mux.HandleFunc("PUT /v1/entries/{entryID}/bookmark", s.userHandler((*Server).handleMinifluxEntryBookmark))
+	mux.HandleFunc("PUT /v1/users/{id}/mark-all-as-read", s.userHandler((*Server).handleMinifluxMarkAllRead))
+
+	mux.HandleFunc("GET /v1/export", s.userHandler((*Server).handleMinifluxExport))
+	mux.HandleFunc("POST /v1/import", s.userHandler((*Server).handleMinifluxImport))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := s.minifluxAuth(r)
+		if !ok {
+			writeMinifluxError(w, http.StatusUnauthorized, "Access Unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, auth.WithUser(r, userID))
+	})
+}
+
+func minifluxPathID(r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	return id, err == nil
+}
+
+func (s *Server) handleMinifluxMe(w http.ResponseWriter, r *http.Request) {
+	settings := s.db.GetSettings()
+	direction := "asc"
+	if settings.SortNewestFirst {
+		direction = "desc"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":                      s.db.UserID(),
+		"username":                s.userName(),
+		"is_admin":                s.isAdmin(),
+		"theme":                   settings.ThemeName,
+		"language":                settings.Language,
+		"timezone":                time.Local.String(),
+		"entry_sorting_direction": direction,
+		"entries_per_page":        100,
+	})
+}
+
+func (s *Server) handleMinifluxVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"version": "yarr",
+	})
+}
+
+func (s *Server) handleMinifluxDiscover(w http.ResponseWriter, r *http.Request) {
+	var form MinifluxDiscoverForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.URL == "" {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	result, err := worker.DiscoverFeed(form.URL)
+	if err != nil {
+		writeMinifluxError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	subscriptions := make([]map[string]string, 0)
+	if result.Feed != nil {
+		subscriptions = append(subscriptions, map[string]string{
+			"url":   result.FeedLink,
+			"title": result.Feed.Title,
+			"type":  "rss",
+		})
+	}
+	for _, source := range result.Sources {
+		subscriptions = append(subscriptions, map[string]string{
+			"url":   source.URL,
+			"title": source.Title,
+			"type":  "rss",
+		})
+	}
+	writeJSON(w, http.StatusOK, subscriptions)
+}
+
+func (s *Server) minifluxCategories() map[int64]*MinifluxCategory {
+	categories := make(map[int64]*MinifluxCategory)
+	for _, folder := range s.db.ListFolders() {
+		categories[folder.Id] = &MinifluxCategory{
+			ID:     folder.Id,
+			Title:  folder.Title,
+			UserID: s.db.UserID(),
+		}
+	}
+	return categories
+}
+
+func (s *Server) minifluxFeedStates() map[int64]model.FeedState {
+	result := make(map[int64]model.FeedState)
+	states, err := s.db.ListFeedStates()
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	for _, state := range states {
+		result[state.FeedID] = state
+	}
+	return result
+}
+
+func (s *Server) minifluxFeed(feed model.Feed, categories map[int64]*MinifluxCategory, states map[int64]model.FeedState) MinifluxFeed {
+	result := MinifluxFeed{
+		ID:      feed.Id,
+		UserID:  s.db.UserID(),
+		FeedURL: feed.FeedLink,
+		SiteURL: feed.Link,
+		Title:   feed.Title,
+	}
+	if feed.FolderId != nil {
+		result.Category = categories[*feed.FolderId]
+	}
+	if feed.Icon != nil && len(*feed.Icon) > 0 {
+		result.Icon = &MinifluxFeedIcon{FeedID: feed.Id, IconID: feed.Id}
+	}
+	if state, ok := states[feed.Id]; ok {
+		result.CheckedAt = state.LastRefreshed
+		result.EtagHeader = state.HTTPEtag
+		result.LastModifiedHeader = state.HTTPLastModified
+		result.ParsingErrorMessage = state.LastError
+		result.ParsingErrorCount = state.FailureCount
+		if state.LastError != "" && state.FailureCount == 0 {
+			result.ParsingErrorCount = 1
+		}
+		result.Disabled = state.Disabled
+	}
+	return result
+}
+
+func (s *Server) minifluxFeeds(folderID *int64) []MinifluxFeed {
+	categories := s.minifluxCategories()
+	states := s.minifluxFeedStates()
+	feeds := make([]MinifluxFeed, 0)
+	for _, feed := range s.db.ListFeeds() {
+		if folderID != nil && (feed.FolderId == nil || *feed.FolderId != *folderID) {
+			continue
+		}
+		feeds = append(feeds, s.minifluxFeed(feed, categories, states))
+	}
+	return feeds
+}
+
+func (s *Server) handleMinifluxFeedList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.minifluxFeeds(nil))
+}
+
+func (s *Server) handleMinifluxFeedGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	feed := s.db.GetFeed(id)
+	if feed == nil {
+		writeMinifluxError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.minifluxFeed(*feed, s.minifluxCategories(), s.minifluxFeedStates()))
+}
+
+func (s *Server) handleMinifluxFeedCreate(w http.ResponseWriter, r *http.Request) {
+	var form MinifluxFeedCreateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.FeedURL == "" {
+		writeMinifluxError(w, http.StatusBadRequest, "The feed URL is required")
+		return
+	}
+	if form.CategoryID != nil && *form.CategoryID == 0 {
+		form.CategoryID = nil
+	}
+	result, err := worker.DiscoverFeed(form.FeedURL)
+	if err != nil || result.Feed == nil {
+		writeMinifluxError(w, http.StatusBadRequest, "Unable to find a feed at the given URL")
+		return
+	}
+	feed := s.createDiscoveredFeed(result, "", form.CategoryID)
+	if feed == nil {
+		writeMinifluxError(w, http.StatusInternalServerError, "Unable to create the feed")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]int64{"feed_id": feed.Id})
+}
+
+func (s *Server) handleMinifluxFeedUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	if s.db.GetFeed(id) == nil {
+		writeMinifluxError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+	var form MinifluxFeedUpdateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	params := model.UpdateFeedParams{
+		Title:    form.Title,
+		FeedLink: form.FeedURL,
+	}
+	if form.CategoryID != nil {
+		if *form.CategoryID == 0 {
+			params.FolderID = model.SetNullable[int64](nil)
+		} else {
+			params.FolderID = model.SetNullable(form.CategoryID)
+		}
+	}
+	if _, err := s.db.UpdateFeed(id, params); err != nil {
+		writeMinifluxError(w, http.StatusInternalServerError, "Unable to update the feed")
+		return
+	}
+	feed := s.db.GetFeed(id)
+	writeJSON(w, http.StatusCreated, s.minifluxFeed(*feed, s.minifluxCategories(), s.minifluxFeedStates()))
+}
+
+func (s *Server) handleMinifluxFeedDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	if !s.db.DeleteFeed(id) {
+		writeMinifluxError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxFeedsRefresh(w http.ResponseWriter, r *http.Request) {
+	s.worker.RefreshFeeds(s.db.UserID())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxFeedRefresh(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	feed := s.db.GetFeed(id)
+	if feed == nil {
+		writeMinifluxError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+	s.worker.RefreshFeed(*feed)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxFeedIcon(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	feed := s.db.GetFeed(id)
+	if feed == nil || feed.Icon == nil || len(*feed.Icon) == 0 {
+		writeMinifluxError(w, http.StatusNotFound, "Icon not found")
+		return
+	}
+	mimeType := http.DetectContentType(*feed.Icon)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":        feed.Id,
+		"mime_type": mimeType,
+		"data":      mimeType + ";base64," + base64.StdEncoding.EncodeToString(*feed.Icon),
+	})
+}
+
+func (s *Server) handleMinifluxFeedCounters(w http.ResponseWriter, r *http.Request) {
+	unreads := make(map[string]int64)
+	for _, stat := range s.db.FeedStats() {
+		if stat.UnreadCount > 0 {
+			unreads[strconv.FormatInt(stat.FeedId, 10)] = stat.UnreadCount
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"reads":   map[string]int64{},
+		"unreads": unreads,
+	})
+}
+
+func (s *Server) handleMinifluxFeedMarkRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	s.markItemsRead(model.MarkFilter{FeedID: &id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxFeedEntries(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+	s.writeMinifluxEntries(w, r, model.ItemFilter{FeedID: &id})
+}
+
+func (s *Server) handleMinifluxCategoryList(w http.ResponseWriter, r *http.Request) {
+	withCounts := r.URL.Query().Get("counts") == "true"
+
+	feedCounts := make(map[int64]int)
+	feedFolders := make(map[int64]int64)
+	for _, feed := range s.db.ListFeeds() {
+		if feed.FolderId != nil {
+			feedCounts[*feed.FolderId]++
+			feedFolders[feed.Id] = *feed.FolderId
+		}
+	}
+	unreadCounts := make(map[int64]int64)
+	if withCounts {
+		for _, stat := range s.db.FeedStats() {
+			if folderID, ok := feedFolders[stat.FeedId]; ok {
+				unreadCounts[folderID] += stat.UnreadCount
+			}
+		}
+	}
+
+	categories := make([]MinifluxCategory, 0)
+	for _, folder := range s.db.ListFolders() {
+		category := MinifluxCategory{
+			ID:     folder.Id,
+			Title:  folder.Title,
+			UserID: s.db.UserID(),
+		}
+		if withCounts {
+			feedCount := feedCounts[folder.Id]
+			totalUnread := unreadCounts[folder.Id]
+			category.FeedCount = &feedCount
+			category.TotalUnread = &totalUnread
+		}
+		categories = append(categories, category)
+	}
+	writeJSON(w, http.StatusOK, categories)
+}
+
+func (s *Server) handleMinifluxCategoryCreate(w http.ResponseWriter, r *http.Request) {
+	var form MinifluxCategoryForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.Title == "" {
+		writeMinifluxError(w, http.StatusBadRequest, "The title is required")
+		return
+	}
+	folder := s.db.CreateFolder(form.Title)
+	if folder == nil {
+		writeMinifluxError(w, http.StatusInternalServerError, "Unable to create the category")
+		return
+	}
+	writeJSON(w, http.StatusCreated, MinifluxCategory{
+		ID:     folder.Id,
+		Title:  folder.Title,
+		UserID: s.db.UserID(),
+	})
+}
+
+func (s *Server) handleMinifluxCategoryUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+	var form MinifluxCategoryForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.Title == "" {
+		writeMinifluxError(w, http.StatusBadRequest, "The title is required")
+		return
+	}
+	if _, err := s.db.UpdateFolder(id, model.UpdateFolderParams{Title: &form.Title}); err != nil {
+		writeMinifluxError(w, http.StatusInternalServerError, "Unable to update the category")
+		return
+	}
+	writeJSON(w, http.StatusCreated, MinifluxCategory{
+		ID:     id,
+		Title:  form.Title,
+		UserID: s.db.UserID(),
+	})
+}
+
+func (s *Server) handleMinifluxCategoryDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+	s.db.DeleteFolder(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxCategoryFeeds(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.minifluxFeeds(&id))
+}
+
+func (s *Server) handleMinifluxCategoryEntries(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+	s.writeMinifluxEntries(w, r, model.ItemFilter{FolderID: &id})
+}
+
+func (s *Server) handleMinifluxCategoryMarkRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "id")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+	s.markItemsRead(model.MarkFilter{FolderID: &id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	s.markItemsRead(model.MarkFilter{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxEntries(w http.ResponseWriter, r *http.Request) {
+	filter := model.ItemFilter{}
+	query := r.URL.Query()
+	if feedID, err := strconv.ParseInt(query.Get("feed_id"), 10, 64); err == nil {
+		filter.FeedID = &feedID
+	}
+	if categoryID, err := strconv.ParseInt(query.Get("category_id"), 10, 64); err == nil {
+		filter.FolderID = &categoryID
+	}
+	s.writeMinifluxEntries(w, r, filter)
+}
+
+// minifluxEntryFilter applies the entry query params shared by all entry endpoints.
+// Returns false if the query can never match anything.
+func minifluxEntryFilter(query url.Values, filter *model.ItemFilter) bool {
+	statuses := query["status"]
+	switch {
+	case query.Get("starred") == "true" || query.Get("starred") == "1":
+		status := model.STARRED
+		filter.Status = &status
+	case len(statuses) == 1 && statuses[0] == "unread":
+		status := model.UNREAD
+		filter.Status = &status
+	case len(statuses) == 1 && statuses[0] == "read":
+		status := model.READ
+		filter.Status = &status
+	case len(statuses) == 1 && statuses[0] == "removed":
+		return false
+	}
+	if search := query.Get("search"); search != "" {
+		filter.Search = &search
+	}
+	if before, err := strconv.ParseInt(query.Get("before"), 10, 64); err == nil {
+		date := time.Unix(before, 0).UTC()
+		filter.Before = &date
+	}
+	if after, err := strconv.ParseInt(query.Get("after"), 10, 64); err == nil {
+		date := time.Unix(after, 0).UTC()
+		filter.Since = &date
+	}
+	if beforeID, err := strconv.ParseInt(query.Get("before_entry_id"), 10, 64); err == nil {
+		filter.MaxID = &beforeID
+	}
+	if afterID, err := strconv.ParseInt(query.Get("after_entry_id"), 10, 64); err == nil {
+		filter.SinceID = &afterID
+	}
+	return true
+}
+
+func (s *Server) writeMinifluxEntries(w http.ResponseWriter, r *http.Request, filter model.ItemFilter) {
+	query := r.URL.Query()
+	if !minifluxEntryFilter(query, &filter) {
+		writeJSON(w, http.StatusOK, map[string]any{"total": 0, "entries": []MinifluxEntry{}})
+		return
+	}
+
+	limit := 100
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+		limit = min(n, minifluxMaxLimit)
+	}
+	newestFirst := query.Get("direction") == "desc"
+
+	total := s.db.CountItems(filter)
+	if n, err := strconv.Atoi(query.Get("offset")); err == nil && n > 0 {
+		filter.Offset = n
+	}
+	items := s.db.ListItems(filter, limit, newestFirst, true)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":   total,
+		"entries": s.minifluxEntries(items),
+	})
+}
+
+func (s *Server) minifluxEntries(items []model.Item) []MinifluxEntry {
+	categories := s.minifluxCategories()
+	states := s.minifluxFeedStates()
+	feeds := make(map[int64]MinifluxFeed)
+	for _, feed := range s.db.ListFeeds() {
+		feeds[feed.Id] = s.minifluxFeed(feed, categories, states)
+	}
+
+	entries := make([]MinifluxEntry, len(items))
+	for i, item := range items {
+		var feed *MinifluxFeed
+		if f, ok := feeds[item.FeedId]; ok {
+			feed = &f
+		}
+		entries[i] = s.minifluxEntry(item, feed)
+	}
+	return entries
+}
+
+func (s *Server) minifluxEntry(item model.Item, feed *MinifluxFeed) MinifluxEntry {
+	status := "read"
+	if item.Status == model.UNREAD {
+		status = "unread"
+	}
+	tags := item.Categories
+	if tags == nil {
+		tags = []string{}
+	}
+	enclosures := make([]MinifluxEnclosure, 0)
+	for i, link := range item.MediaLinks {
+		enclosures = append(enclosures, MinifluxEnclosure{
+			ID:       int64(i + 1),
+			UserID:   s.db.UserID(),
+			EntryID:  item.Id,
+			URL:      link.URL,
+			MimeType: link.Type + "/*",
+		})
+	}
+	return MinifluxEntry{
+		ID:          item.Id,
+		UserID:      s.db.UserID(),
+		FeedID:      item.FeedId,
+		Status:      status,
+		Hash:        fmt.Sprintf("%x", sha256.Sum256([]byte(item.GUID))),
+		Title:       item.Title,
+		URL:         item.Link,
+		PublishedAt: item.Date,
+		CreatedAt:   item.Date,
+		ChangedAt:   item.Date,
+		Content:     item.Content,
+		Author:      item.Author,
+		CommentsURL: item.CommentsURL,
+		Starred:     item.Status == model.STARRED,
+		Enclosures:  enclosures,
+		Feed:        feed,
+		Tags:        tags,
+	}
+}
+
+func (s *Server) handleMinifluxEntryGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "entryID")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid entry ID")
+		return
+	}
+	item := s.db.GetItem(id)
+	if item == nil {
+		writeMinifluxError(w, http.StatusNotFound, "Entry not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.minifluxEntries([]model.Item{*item})[0])
+}
+
+func (s *Server) handleMinifluxEntriesUpdate(w http.ResponseWriter, r *http.Request) {
+	var form MinifluxEntriesUpdateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || len(form.EntryIDs) == 0 {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if form.Status != "read" && form.Status != "unread" {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	items := s.db.ListItems(model.ItemFilter{IDs: &form.EntryIDs}, len(form.EntryIDs), true, false)
+	for _, item := range items {
+		switch {
+		case form.Status == "read" && item.Status == model.UNREAD:
+			s.updateItemStatus(item.Id, model.READ)
+		case form.Status == "unread" && item.Status != model.UNREAD:
+			s.updateItemStatus(item.Id, model.UNREAD)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxEntryBookmark(w http.ResponseWriter, r *http.Request) {
+	id, ok := minifluxPathID(r, "entryID")
+	if !ok {
+		writeMinifluxError(w, http.StatusBadRequest, "Invalid entry ID")
+		return
+	}
+	item := s.db.GetItem(id)
+	if item == nil {
+		writeMinifluxError(w, http.StatusNotFound, "Entry not found")
+		return
+	}
+	status := model.STARRED
+	if item.Status == model.STARRED {
+		status = model.READ
+	}
+	s.updateItemStatus(id, status)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMinifluxExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(s.exportOPML().OPML()))
+}
+
+func (s *Server) handleMinifluxImport(w http.ResponseWriter, r *http.Request) {
+	doc, err := opml.Parse(r.Body)
+	if err != nil {
+		writeMinifluxError(w, http.StatusBadRequest, strings.TrimSpace(err.Error()))
+		return
+	}
+	s.importOPML(doc)
+	writeJSON(w, http.StatusCreated, map[string]string{"message": "Feeds imported successfully"})
+}
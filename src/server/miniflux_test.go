@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestMiniflux(t *testing.T) {
+	db, serve := newTestServer(t)
+	folder := db.CreateFolder("News")
+	feed := db.CreateFeed(model.CreateFeedParams{
+		Title:    "Feed",
+		FeedLink: "http://example.com/feed.xml",
+		FolderID: &folder.Id,
+	})
+	now := time.Now()
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "one", Date: now.Add(-time.Hour)},
+		{GUID: "2", FeedId: feed.Id, Title: "two", Date: now},
+		{GUID: "3", FeedId: feed.Id, Title: "three", Date: now.Add(-2 * time.Hour)},
+	})
+
+	token, hash := auth.NewToken()
+	if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, token, body string) *http.Response {
+		header := http.Header{}
+		if token != "" {
+			header.Set("X-Auth-Token", token)
+		}
+		return serve(method, path, body, header).Result()
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		if res := request("GET", "/v1/me", "", ""); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", res.StatusCode)
+		}
+		if res := request("GET", "/v1/me", "wrong", ""); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", res.StatusCode)
+		}
+		if res := request("GET", "/v1/me", token, ""); res.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", res.StatusCode)
+		}
+	})
+
+	t.Run("me", func(t *testing.T) {
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice", PasswordHash: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		aliceToken, hash := auth.NewToken()
+		if _, err := storage.ForUser(db, user.Id).CreateAPIKey(model.CreateAPIKeyParams{Name: "alice", TokenHash: hash}); err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range []struct {
+			token    string
+			username string
+			isAdmin  bool
+		}{
+			{token, "", true},
+			{aliceToken, "alice", false},
+		} {
+			var me struct {
+				Username string `json:"username"`
+				IsAdmin  bool   `json:"is_admin"`
+			}
+			json.NewDecoder(request("GET", "/v1/me", test.token, "").Body).Decode(&me)
+			if me.Username != test.username || me.IsAdmin != test.isAdmin {
+				t.Errorf("unexpected user: %#v", me)
+			}
+		}
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		res := request("GET", "/v1/feeds", token, "")
+		var feeds []MinifluxFeed
+		json.NewDecoder(res.Body).Decode(&feeds)
+		if len(feeds) != 1 || feeds[0].ID != feed.Id || feeds[0].Category == nil || feeds[0].Category.Title != "News" {
+			t.Fatalf("unexpected feeds: %#v", feeds)
+		}
+	})
+
+	t.Run("categories", func(t *testing.T) {
+		res := request("GET", "/v1/categories?counts=true", token, "")
+		var categories []MinifluxCategory
+		json.NewDecoder(res.Body).Decode(&categories)
+		if len(categories) != 1 || *categories[0].FeedCount != 1 || *categories[0].TotalUnread != 3 {
+			t.Fatalf("unexpected categories: %#v", categories)
+		}
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		res := request("GET", "/v1/entries?status=unread&direction=desc&limit=2&offset=1", token, "")
+		var data struct {
+			Total   int             `json:"total"`
+			Entries []MinifluxEntry `json:"entries"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if data.Total != 3 {
+			t.Errorf("expected total 3, got %d", data.Total)
+		}
+		if len(data.Entries) != 2 || data.Entries[0].Title != "one" || data.Entries[1].Title != "three" {
+			t.Fatalf("unexpected entries: %#v", data.Entries)
+		}
+	})
+
+	t.Run("update entries", func(t *testing.T) {
+		items := db.ListItems(model.ItemFilter{}, 10, true, false)
+		res := request("PUT", "/v1/entries/"+strconv.FormatInt(items[0].Id, 10)+"/bookmark", token, "")
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", res.StatusCode)
+		}
+		if item := db.GetItem(items[0].Id); item.Status != model.STARRED {
+			t.Errorf("expected starred, got %v", item.Status)
+		}
+
+		body := `{"entry_ids": [` + strconv.FormatInt(items[0].Id, 10) + `, ` + strconv.FormatInt(items[1].Id, 10) + `], "status": "read"}`
+		res = request("PUT", "/v1/entries", token, body)
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", res.StatusCode)
+		}
+		if item := db.GetItem(items[0].Id); item.Status != model.STARRED {
+			t.Errorf("expected starred item to stay starred, got %v", item.Status)
+		}
+		if item := db.GetItem(items[1].Id); item.Status != model.READ {
+			t.Errorf("expected read, got %v", item.Status)
+		}
+	})
+
+	t.Run("mark feed as read", func(t *testing.T) {
+		request("PUT", "/v1/feeds/"+strconv.FormatInt(feed.Id, 10)+"/mark-all-as-read", token, "")
+		unread := model.UNREAD
+		if items := db.ListItems(model.ItemFilter{Status: &unread}, 10, true, false); len(items) != 0 {
+			t.Errorf("expected no unread items, got %d", len(items))
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		res := request("GET", "/v1/export", token, "")
+		body, _ := io.ReadAll(res.Body)
+		if !strings.Contains(string(body), "http://example.com/feed.xml") {
+			t.Errorf("expected feed in export, got %s", body)
+		}
+	})
+}
@@ -0,0 +1,708 @@
+package server
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// Nextcloud News API v1-2 & v1-3.
+// Reference: https://nextcloud.github.io/news/api/api-v1-3/
+
+var nextcloudVersions = []string{"v1-2", "v1-3"}
+
+// item types used by the `type` query parameter
+const (
+	nextcloudTypeFeed    = 0
+	nextcloudTypeFolder  = 1
+	nextcloudTypeStarred = 2
+	nextcloudTypeAll     = 3
+)
+
+type NextcloudFolder struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type NextcloudFeed struct {
+	ID               int64   `json:"id"`
+	URL              string  `json:"url"`
+	Title            string  `json:"title"`
+	FaviconLink      *string `json:"faviconLink"`
+	Added            int64   `json:"added"`
+	FolderID         int64   `json:"folderId"`
+	UnreadCount      int64   `json:"unreadCount"`
+	Ordering         int     `json:"ordering"`
+	Link             string  `json:"link"`
+	Pinned           bool    `json:"pinned"`
+	UpdateErrorCount int     `json:"updateErrorCount"`
+	LastUpdateError  string  `json:"lastUpdateError"`
+}
+
+type NextcloudItem struct {
+	ID            int64   `json:"id"`
+	GUID          string  `json:"guid"`
+	GUIDHash      string  `json:"guidHash"`
+	URL           string  `json:"url"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author"`
+	PubDate       int64   `json:"pubDate"`
+	UpdatedDate   int64   `json:"updatedDate"`
+	Body          string  `json:"body"`
+	EnclosureMime *string `json:"enclosureMime"`
+	EnclosureLink *string `json:"enclosureLink"`
+	FeedID        int64   `json:"feedId"`
+	Unread        bool    `json:"unread"`
+	Starred       bool    `json:"starred"`
+	LastModified  int64   `json:"lastModified"`
+	RTL           bool    `json:"rtl"`
+	Fingerprint   string  `json:"fingerprint"`
+}
+
+type NextcloudFolderForm struct {
+	Name string `json:"name"`
+}
+
+type NextcloudFeedForm struct {
+	URL      string `json:"url"`
+	FolderID *int64 `json:"folderId"`
+}
+
+type NextcloudFeedRenameForm struct {
+	FeedTitle string `json:"feedTitle"`
+}
+
+type NextcloudMarkForm struct {
+	NewestItemID int64 `json:"newestItemId"`
+}
+
+// NextcloudMultipleForm accepts both the v1-2 (`items`) and the v1-3 (`itemIds`) payloads.
+type NextcloudMultipleForm struct {
+	ItemIDs []int64           `json:"itemIds"`
+	Items   []json.RawMessage `json:"items"`
+}
+
+type nextcloudStarRef struct {
+	FeedID   int64  `json:"feedId"`
+	GUIDHash string `json:"guidHash"`
+}
+
+func (s *Server) nextcloudAuth(r *http.Request) (int64, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return 0, false
+	}
+	return s.checkPassword(username, password)
+}
+
+func (s *Server) nextcloudHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /index.php/apps/news/api", s.handleNextcloudAPILevels)
+	for _, version := range nextcloudVersions {
+		prefix := "/index.php/apps/news/api/" + version
+		route := func(methods, path string, handler func(*Server, http.ResponseWriter, *http.Request)) {
+			for method := range strings.FieldsSeq(methods) {
+				mux.HandleFunc(method+" "+prefix+path, s.userHandler(handler))
+			}
+		}
+		route("GET", "/version", (*Server).handleNextcloudVersion)
+		route("GET", "/status", (*Server).handleNextcloudStatus)
+		route("GET", "/user", (*Server).handleNextcloudUser)
+
+		route("GET", "/folders", (*Server).handleNextcloudFolderList)
+		route("POST", "/folders", (*Server).handleNextcloudFolderCreate)
+		route("PUT", "/folders/{id}", (*Server).handleNextcloudFolderRename)
+		route("DELETE", "/folders/{id}", (*Server).handleNextcloudFolderDelete)
+		route("PUT POST", "/folders/{id}/read", (*Server).handleNextcloudFolderRead)
+
+		route("GET", "/feeds", (*Server).handleNextcloudFeedList)
+		route("POST", "/feeds", (*Server).handleNextcloudFeedCreate)
+		route("DELETE", "/feeds/{id}", (*Server).handleNextcloudFeedDelete)
+		route("PUT POST", "/feeds/{id}/move", (*Server).handleNextcloudFeedMove)
+		route("PUT POST", "/feeds/{id}/rename", (*Server).handleNextcloudFeedRename)
+		route("PUT POST", "/feeds/{id}/read", (*Server).handleNextcloudFeedRead)
+
+		route("GET", "/items", (*Server).handleNextcloudItems)
+		route("GET", "/items/updated", (*Server).handleNextcloudUpdatedItems)
+		route("PUT POST", "/items/read", (*Server).handleNextcloudItemsRead)
+		route("PUT POST", "/items/{action}/multiple", (*Server).handleNextcloudItemsMultiple)
+		route("PUT POST", "/items/{id}/{action}", (*Server).handleNextcloudItem)
+		route("PUT POST", "/items/{feedId}/{guidHash}/{action}", (*Server).handleNextcloudItemByGUID)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := s.nextcloudAuth(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, auth.WithUser(r, userID))
+	})
+}
+
+func (s *Server) handleNextcloudAPILevels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"apiLevels": nextcloudVersions,
+	})
+}
+
+func (s *Server) handleNextcloudVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": "25.0.0"})
+}
+
+func (s *Server) handleNextcloudStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"version": "25.0.0",
+		"warnings": map[string]bool{
+			"improperlyConfiguredCron": false,
+			"incorrectDbCharset":       false,
+		},
+	})
+}
+
+func (s *Server) handleNextcloudUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"userId":             s.userName(),
+		"displayName":        s.userName(),
+		"lastLoginTimestamp": time.Now().Unix(),
+		"avatar":             nil,
+	})
+}
+
+func (s *Server) handleNextcloudFolderList(w http.ResponseWriter, r *http.Request) {
+	folders := make([]NextcloudFolder, 0)
+	for _, folder := range s.db.ListFolders() {
+		folders = append(folders, NextcloudFolder{ID: folder.Id, Name: folder.Title})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"folders": folders})
+}
+
+func (s *Server) handleNextcloudFolderCreate(w http.ResponseWriter, r *http.Request) {
+	var form NextcloudFolderForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.Name == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	for _, folder := range s.db.ListFolders() {
+		if folder.Title == form.Name {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+	folder := s.db.CreateFolder(form.Name)
+	if folder == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"folders": []NextcloudFolder{{ID: folder.Id, Name: folder.Title}},
+	})
+}
+
+func (s *Server) handleNextcloudFolderRename(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var form NextcloudFolderForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.Name == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	ok, err := s.db.UpdateFolder(id, model.UpdateFolderParams{Title: &form.Name})
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleNextcloudFolderDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.db.DeleteFolder(id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// nextcloudMarkFilter parses `newestItemId` from the request body.
+// Items with higher ids were not seen by the client and are left untouched.
+func nextcloudMarkFilter(r *http.Request) (model.MarkFilter, bool) {
+	var form NextcloudMarkForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.NewestItemID <= 0 {
+		return model.MarkFilter{}, false
+	}
+	maxID := form.NewestItemID + 1
+	return model.MarkFilter{MaxID: &maxID}, true
+}
+
+func (s *Server) handleNextcloudFolderRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter, ok := nextcloudMarkFilter(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter.FolderID = &id
+	s.markItemsRead(filter)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) nextcloudNewestItemID() int64 {
+	maxID := int64(math.MaxInt64)
+	items := s.db.ListItems(model.ItemFilter{MaxID: &maxID}, 1, true, false)
+	if len(items) == 0 {
+		return 0
+	}
+	return items[0].Id
+}
+
+func (s *Server) nextcloudFeeds(feeds []model.Feed) []NextcloudFeed {
+	unreads := make(map[int64]int64)
+	for _, stat := range s.db.FeedStats() {
+		unreads[stat.FeedId] = stat.UnreadCount
+	}
+	states := make(map[int64]model.FeedState)
+	if list, err := s.db.ListFeedStates(); err == nil {
+		for _, state := range list {
+			states[state.FeedID] = state
+		}
+	}
+
+	result := make([]NextcloudFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		var folderID int64
+		if feed.FolderId != nil {
+			folderID = *feed.FolderId
+		}
+		nfeed := NextcloudFeed{
+			ID:          feed.Id,
+			URL:         feed.FeedLink,
+			Title:       feed.Title,
+			FolderID:    folderID,
+			UnreadCount: unreads[feed.Id],
+			Link:        feed.Link,
+		}
+		if state, ok := states[feed.Id]; ok && state.LastError != "" {
+			nfeed.UpdateErrorCount = max(state.FailureCount, 1)
+			nfeed.LastUpdateError = state.LastError
+		}
+		result = append(result, nfeed)
+	}
+	return result
+}
+
+func (s *Server) handleNextcloudFeedList(w http.ResponseWriter, r *http.Request) {
+	var starred int64
+	for _, stat := range s.db.FeedStats() {
+		starred += stat.StarredCount
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"feeds":        s.nextcloudFeeds(s.db.ListFeeds()),
+		"starredCount": starred,
+		"newestItemId": s.nextcloudNewestItemID(),
+	})
+}
+
+func (s *Server) handleNextcloudFeedCreate(w http.ResponseWriter, r *http.Request) {
+	var form NextcloudFeedForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.URL == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if form.FolderID != nil && *form.FolderID == 0 {
+		form.FolderID = nil
+	}
+	for _, feed := range s.db.ListFeeds() {
+		if feed.FeedLink == form.URL {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+	result, err := worker.DiscoverFeed(form.URL)
+	if err != nil || result.Feed == nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	feed := s.createDiscoveredFeed(result, "", form.FolderID)
+	if feed == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"feeds":        s.nextcloudFeeds([]model.Feed{*feed}),
+		"newestItemId": s.nextcloudNewestItemID(),
+	})
+}
+
+func (s *Server) handleNextcloudFeedDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.db.DeleteFeed(id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleNextcloudFeedMove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var form NextcloudFeedForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	folderID := model.SetNullable(form.FolderID)
+	if form.FolderID == nil || *form.FolderID == 0 {
+		folderID = model.SetNullable[int64](nil)
+	}
+	ok, err := s.db.UpdateFeed(id, model.UpdateFeedParams{FolderID: folderID})
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleNextcloudFeedRename(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var form NextcloudFeedRenameForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil || form.FeedTitle == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	ok, err := s.db.UpdateFeed(id, model.UpdateFeedParams{Title: &form.FeedTitle})
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleNextcloudFeedRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter, ok := nextcloudMarkFilter(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter.FeedID = &id
+	s.markItemsRead(filter)
+	w.WriteHeader(http.StatusOK)
+}
+
+// nextcloudItemFilter applies the `type`/`id` query parameters to the filter.
+func nextcloudItemFilter(r *http.Request, filter *model.ItemFilter) bool {
+	query := r.URL.Query()
+	itemType := nextcloudTypeAll
+	if value := query.Get("type"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+		itemType = n
+	}
+	id, _ := strconv.ParseInt(query.Get("id"), 10, 64)
+	switch itemType {
+	case nextcloudTypeFeed:
+		filter.FeedID = &id
+	case nextcloudTypeFolder:
+		// folder 0 is the root folder
+		if id != 0 {
+			filter.FolderID = &id
+		}
+	case nextcloudTypeStarred:
+		status := model.STARRED
+		filter.Status = &status
+	case nextcloudTypeAll:
+	default:
+		return false
+	}
+	return true
+}
+
+// listNextcloudItems returns up to `limit` items ordered by id; non-positive
+// limit means all of them.
+func (s *Server) listNextcloudItems(filter model.ItemFilter, limit int, oldestFirst bool) []model.Item {
+	result := make([]model.Item, 0)
+	for limit <= 0 || len(result) < limit {
+		batch := listLimit
+		if limit > 0 {
+			batch = min(limit-len(result), minifluxMaxLimit)
+		}
+		items := s.db.ListItems(filter, batch, !oldestFirst, true)
+		result = append(result, items...)
+		if len(items) < batch {
+			break
+		}
+		last := items[len(items)-1].Id
+		if oldestFirst {
+			filter.SinceID = &last
+		} else {
+			filter.MaxID = &last
+		}
+	}
+	return result
+}
+
+func (s *Server) handleNextcloudItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.ItemFilter{}
+	if !nextcloudItemFilter(r, &filter) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if query.Get("getRead") == "false" && filter.Status == nil {
+		status := model.UNREAD
+		filter.Status = &status
+	}
+
+	// offset is the id of the last item the client has already seen
+	oldestFirst := query.Get("oldestFirst") == "true"
+	offset, _ := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if oldestFirst {
+		filter.SinceID = &offset
+	} else {
+		if offset <= 0 {
+			offset = math.MaxInt64
+		}
+		filter.MaxID = &offset
+	}
+
+	batchSize := -1
+	if value := query.Get("batchSize"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			batchSize = n
+		}
+	}
+	items := s.listNextcloudItems(filter, batchSize, oldestFirst)
+	writeJSON(w, http.StatusOK, map[string]any{"items": nextcloudItems(items)})
+}
+
+func (s *Server) handleNextcloudUpdatedItems(w http.ResponseWriter, r *http.Request) {
+	filter := model.ItemFilter{}
+	if !nextcloudItemFilter(r, &filter) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	lastModified, err := strconv.ParseInt(r.URL.Query().Get("lastModified"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// yarr doesn't track modification times, so this returns the items
+	// published since the given date.
+	since := time.Unix(lastModified, 0).UTC()
+	filter.Since = &since
+	var start int64
+	filter.SinceID = &start
+
+	items := s.listNextcloudItems(filter, -1, true)
+	writeJSON(w, http.StatusOK, map[string]any{"items": nextcloudItems(items)})
+}
+
+func nextcloudItems(items []model.Item) []NextcloudItem {
+	result := make([]NextcloudItem, len(items))
+	for i, item := range items {
+		guidHash := fmt.Sprintf("%x", md5.Sum([]byte(item.GUID)))
+		result[i] = NextcloudItem{
+			ID:           item.Id,
+			GUID:         item.GUID,
+			GUIDHash:     guidHash,
+			URL:          item.Link,
+			Title:        item.Title,
+			Author:       item.Author,
+			PubDate:      item.Date.Unix(),
+			UpdatedDate:  item.Date.Unix(),
+			Body:         item.Content,
+			FeedID:       item.FeedId,
+			Unread:       item.Status == model.UNREAD,
+			Starred:      item.Status == model.STARRED,
+			LastModified: item.Date.Unix(),
+			Fingerprint:  guidHash,
+		}
+		for _, link := range item.MediaLinks {
+			if link.Type == "audio" || link.Type == "video" {
+				mime := link.Type + "/*"
+				url := link.URL
+				result[i].EnclosureMime = &mime
+				result[i].EnclosureLink = &url
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (s *Server) handleNextcloudItemsRead(w http.ResponseWriter, r *http.Request) {
+	filter, ok := nextcloudMarkFilter(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.markItemsRead(filter)
+	w.WriteHeader(http.StatusOK)
+}
+
+// nextcloudSetStatus applies one of the read/unread/star/unstar actions.
+// Starred items are always read in yarr, hence read/unread leave them alone
+// and unstar marks them as read.
+func (s *Server) nextcloudSetStatus(item *model.Item, action string) bool {
+	var status model.ItemStatus
+	switch action {
+	case "read":
+		if item.Status != model.UNREAD {
+			return true
+		}
+		status = model.READ
+	case "unread":
+		if item.Status != model.READ {
+			return true
+		}
+		status = model.UNREAD
+	case "star":
+		status = model.STARRED
+	case "unstar":
+		if item.Status != model.STARRED {
+			return true
+		}
+		status = model.READ
+	default:
+		return false
+	}
+	s.updateItemStatus(item.Id, status)
+	return true
+}
+
+func (s *Server) handleNextcloudItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	item := s.db.GetItem(id)
+	if item == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !s.nextcloudSetStatus(item, r.PathValue("action")) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) findNextcloudItem(feedID int64, guidHash string) *model.Item {
+	filter := model.ItemFilter{FeedID: &feedID}
+	for {
+		items := s.db.ListItems(filter, listLimit, true, false)
+		for _, item := range items {
+			if fmt.Sprintf("%x", md5.Sum([]byte(item.GUID))) == guidHash {
+				return &item
+			}
+		}
+		if len(items) < listLimit {
+			return nil
+		}
+		filter.After = &items[len(items)-1].Id
+	}
+}
+
+func (s *Server) handleNextcloudItemByGUID(w http.ResponseWriter, r *http.Request) {
+	feedID, err := strconv.ParseInt(r.PathValue("feedId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	item := s.findNextcloudItem(feedID, r.PathValue("guidHash"))
+	if item == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !s.nextcloudSetStatus(item, r.PathValue("action")) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleNextcloudItemsMultiple(w http.ResponseWriter, r *http.Request) {
+	action := r.PathValue("action")
+	var form NextcloudMultipleForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	items := make([]*model.Item, 0)
+	for _, id := range form.ItemIDs {
+		if item := s.db.GetItem(id); item != nil {
+			items = append(items, item)
+		}
+	}
+	for _, raw := range form.Items {
+		var id int64
+		var ref nextcloudStarRef
+		switch {
+		case json.Unmarshal(raw, &id) == nil:
+			if item := s.db.GetItem(id); item != nil {
+				items = append(items, item)
+			}
+		case json.Unmarshal(raw, &ref) == nil:
+			if item := s.findNextcloudItem(ref.FeedID, ref.GUIDHash); item != nil {
+				items = append(items, item)
+			}
+		}
+	}
+
+	for _, item := range items {
+		if !s.nextcloudSetStatus(item, action) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestNextcloud(t *testing.T) {
+	db, serve := newTestServer(t, withCredentials("user", "pass"))
+	folder := db.CreateFolder("News")
+	feed := db.CreateFeed(model.CreateFeedParams{
+		Title:    "Feed",
+		FeedLink: "http://example.com/feed.xml",
+		FolderID: &folder.Id,
+	})
+	now := time.Now()
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "one", Date: now.Add(-time.Hour)},
+		{GUID: "2", FeedId: feed.Id, Title: "two", Date: now},
+		{GUID: "3", FeedId: feed.Id, Title: "three", Date: now.Add(-2 * time.Hour)},
+	})
+	ids := make([]int64, 0)
+	for _, item := range db.ListItems(model.ItemFilter{SinceID: new(int64)}, 10, false, false) {
+		ids = append(ids, item.Id)
+	}
+
+	const prefix = "/index.php/apps/news/api/v1-3"
+	basicAuth := func(username, password string) http.Header {
+		return http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))}}
+	}
+	request := func(method, path, body string) *http.Response {
+		return serve(method, prefix+path, body, basicAuth("user", "pass")).Result()
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		if res := serve("GET", prefix+"/version", "", basicAuth("user", "wrong")); res.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", res.Code)
+		}
+		if res := request("GET", "/version", ""); res.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", res.StatusCode)
+		}
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		res := request("GET", "/feeds", "")
+		var data struct {
+			Feeds        []NextcloudFeed `json:"feeds"`
+			NewestItemID int64           `json:"newestItemId"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Feeds) != 1 || data.Feeds[0].FolderID != folder.Id || data.Feeds[0].UnreadCount != 3 {
+			t.Fatalf("unexpected feeds: %#v", data.Feeds)
+		}
+		if data.NewestItemID != ids[2] {
+			t.Errorf("expected newest item %d, got %d", ids[2], data.NewestItemID)
+		}
+	})
+
+	t.Run("items", func(t *testing.T) {
+		res := request("GET", "/items?type=3&batchSize=2&offset=0&getRead=true", "")
+		var data struct {
+			Items []NextcloudItem `json:"items"`
+		}
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 2 || data.Items[0].ID != ids[2] || data.Items[1].ID != ids[1] {
+			t.Fatalf("unexpected items: %#v", data.Items)
+		}
+		offset := strconv.FormatInt(data.Items[1].ID, 10)
+		res = request("GET", "/items?type=1&id="+strconv.FormatInt(folder.Id, 10)+"&batchSize=2&offset="+offset, "")
+		json.NewDecoder(res.Body).Decode(&data)
+		if len(data.Items) != 1 || data.Items[0].ID != ids[0] {
+			t.Fatalf("unexpected items: %#v", data.Items)
+		}
+	})
+
+	t.Run("star and read multiple", func(t *testing.T) {
+		body := `{"itemIds": [` + strconv.FormatInt(ids[0], 10) + `]}`
+		if res := request("PUT", "/items/star/multiple", body); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		body = `{"itemIds": [` + strconv.FormatInt(ids[0], 10) + `, ` + strconv.FormatInt(ids[1], 10) + `]}`
+		if res := request("PUT", "/items/read/multiple", body); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		if item := db.GetItem(ids[0]); item.Status != model.STARRED {
+			t.Errorf("expected starred item to stay starred, got %v", item.Status)
+		}
+		if item := db.GetItem(ids[1]); item.Status != model.READ {
+			t.Errorf("expected read, got %v", item.Status)
+		}
+	})
+
+	t.Run("mark feed read", func(t *testing.T) {
+		request("PUT", "/items/"+strconv.FormatInt(ids[1], 10)+"/unread", "")
+		body := `{"newestItemId": ` + strconv.FormatInt(ids[1], 10) + `}`
+		path := "/feeds/" + strconv.FormatInt(feed.Id, 10) + "/read"
+		if res := request("PUT", path, body); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		if item := db.GetItem(ids[1]); item.Status != model.READ {
+			t.Errorf("expected read, got %v", item.Status)
+		}
+		// items newer than newestItemId stay unread
+		if item := db.GetItem(ids[2]); item.Status != model.UNREAD {
+			t.Errorf("expected unread, got %v", item.Status)
+		}
+	})
+}
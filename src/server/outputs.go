@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/content/sanitizer"
+	"github.com/nkanaev/yarr/src/parser"
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// number of the most recent items in output feeds
+const outputFeedSize = 50
+
+var outputWriters = map[string]struct {
+	contentType string
+	write       func(io.Writer, *parser.Feed) error
+}{
+	"atom": {"application/atom+xml", parser.WriteAtom},
+	"rss":  {"application/rss+xml", parser.WriteRSS},
+	"json": {"application/feed+json", parser.WriteJSON},
+}
+
+func (s *Server) handleOutputFeedList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		feeds, err := s.db.ListOutputFeeds()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, feeds)
+	case http.MethodPost:
+		var body OutputFeedForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch body.Source {
+		case model.OutputStarred:
+			body.SourceID = nil
+		case model.OutputFolder, model.OutputLabel:
+			if body.SourceID == nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "source_id is required"})
+				return
+			}
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown source"})
+			return
+		}
+		token, _ := auth.NewToken()
+		feed, err := s.db.CreateOutputFeed(model.OutputFeed{
+			Source:   body.Source,
+			SourceID: body.SourceID,
+			Token:    token,
+		})
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, feed)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleOutputFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.db.DeleteOutputFeed(id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseOutputPath splits `starred.atom` or `folder/1.json` into the source,
+// its id and the format.
+func parseOutputPath(path string) (source model.OutputSource, id *int64, format string, ok bool) {
+	path, format, ok = strings.Cut(path, ".")
+	if !ok {
+		return
+	}
+	if path == string(model.OutputStarred) {
+		return model.OutputStarred, nil, format, true
+	}
+	name, rawID, ok := strings.Cut(path, "/")
+	if !ok || (name != string(model.OutputFolder) && name != string(model.OutputLabel)) {
+		return "", nil, "", false
+	}
+	num, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return "", nil, "", false
+	}
+	return model.OutputSource(name), &num, format, true
+}
+
+// handleOutput serves output feeds to anyone with their token:
+// `/out/starred.atom`, `/out/folder/{id}.json`, `/out/label/{id}.rss`.
+func (s *Server) handleOutput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	source, sourceID, format, ok := parseOutputPath(r.PathValue("path"))
+	writer, known := outputWriters[format]
+	if !ok || !known {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	out, err := storage.ForUser(s.db, 0).GetOutputFeedByToken(token)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if out == nil || out.Source != source || (sourceID != nil) != (out.SourceID != nil) ||
+		(sourceID != nil && *sourceID != *out.SourceID) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	db := storage.ForUser(s.db, out.UserId)
+	var filter model.ItemFilter
+	title := "Starred"
+	switch out.Source {
+	case model.OutputStarred:
+		starred := model.STARRED
+		filter.Status = &starred
+	case model.OutputFolder:
+		filter.FolderID = out.SourceID
+		for _, folder := range db.ListFolders() {
+			if folder.Id == *out.SourceID {
+				title = folder.Title
+			}
+		}
+	case model.OutputLabel:
+		filter.Label = out.SourceID
+		for _, label := range db.ListLabels() {
+			if label.Id == *out.SourceID {
+				title = label.Title
+			}
+		}
+	}
+
+	base := s.PublicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host + s.BasePath
+	}
+	base = strings.TrimSuffix(base, "/")
+	feed := &parser.Feed{
+		Title:   title,
+		SiteURL: base + "/",
+		SelfURL: base + r.URL.Path + "?" + r.URL.RawQuery,
+	}
+	for _, item := range db.ListItems(filter, outputFeedSize, true, true) {
+		links := make([]parser.MediaLink, 0, len(item.MediaLinks))
+		for _, link := range item.MediaLinks {
+			links = append(links, parser.MediaLink(link))
+		}
+		feed.Items = append(feed.Items, parser.Item{
+			GUID:        item.GUID,
+			Date:        item.Date,
+			URL:         item.Link,
+			Title:       item.Title,
+			Content:     sanitizer.Sanitize(item.Link, item.Content),
+			MediaLinks:  links,
+			Author:      item.Author,
+			Categories:  item.Categories,
+			CommentsURL: item.CommentsURL,
+		})
+	}
+
+	w.Header().Set("Content-Type", writer.contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := writer.write(w, feed); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/parser"
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestOutputFeeds(t *testing.T) {
+	db, serve := newTestServer(t, withCredentials("admin", "pass"))
+	folder := db.CreateFolder("news")
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml", FolderID: &folder.Id})
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "Starred post", Link: "http://example.com/1", Date: time.Now(), Status: model.STARRED,
+			Content: `<p>kept</p><script>alert(1)</script>`},
+		{GUID: "2", FeedId: feed.Id, Title: "Regular post", Link: "http://example.com/2", Date: time.Now()},
+	})
+
+	token, hash := auth.NewToken()
+	if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(method, path, body, http.Header{"Authorization": {"Bearer " + token}})
+	}
+	anonymous := func(path string) *httptest.ResponseRecorder {
+		return serve("GET", path, "")
+	}
+
+	if res := request("POST", "/api/outputs", `{"source": "folder"}`); res.Code != http.StatusBadRequest {
+		t.Errorf("expected folder without id to be rejected, got %d", res.Code)
+	}
+	create := func(body string) model.OutputFeed {
+		res := request("POST", "/api/outputs", body)
+		if res.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", res.Code)
+		}
+		var out model.OutputFeed
+		json.NewDecoder(res.Body).Decode(&out)
+		if len(out.Token) < 32 {
+			t.Fatalf("unexpected output feed: %#v", out)
+		}
+		return out
+	}
+	starred := create(`{"source": "starred"}`)
+	byFolder := create(fmt.Sprintf(`{"source": "folder", "source_id": %d}`, folder.Id))
+
+	res := anonymous("/out/starred.atom?token=" + starred.Token)
+	if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "application/atom+xml; charset=utf-8" {
+		t.Fatalf("unexpected response: %d %s", res.Code, res.Header().Get("Content-Type"))
+	}
+	parsed, err := parser.Parse(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Title != "Starred" || len(parsed.Items) != 1 || parsed.Items[0].Title != "Starred post" {
+		t.Fatalf("unexpected feed: %#v", parsed)
+	}
+	if strings.Contains(parsed.Items[0].Content, "script") {
+		t.Errorf("expected content to be sanitized: %q", parsed.Items[0].Content)
+	}
+
+	res = anonymous(fmt.Sprintf("/out/folder/%d.json?token=%s", folder.Id, byFolder.Token))
+	parsed, err = parser.Parse(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Title != "news" || len(parsed.Items) != 2 {
+		t.Fatalf("unexpected feed: %#v", parsed)
+	}
+
+	for _, path := range []string{
+		"/out/starred.atom",
+		"/out/starred.atom?token=wrong",
+		"/out/starred.xml?token=" + starred.Token,
+		"/out/starred.rss?token=" + byFolder.Token,
+		fmt.Sprintf("/out/folder/%d.rss?token=%s", folder.Id, starred.Token),
+		fmt.Sprintf("/out/folder/%d.rss?token=%s", folder.Id+1, byFolder.Token),
+	} {
+		if res := anonymous(path); res.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, res.Code)
+		}
+	}
+
+	if res := request("DELETE", fmt.Sprintf("/api/outputs/%d", starred.Id), ""); res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.Code)
+	}
+	if res := anonymous("/out/starred.rss?token=" + starred.Token); res.Code != http.StatusNotFound {
+		t.Errorf("expected revoked token to be rejected, got %d", res.Code)
+	}
+}
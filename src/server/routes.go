@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/nkanaev/yarr/src/content/readability"
 	"github.com/nkanaev/yarr/src/content/sanitizer"
 	"github.com/nkanaev/yarr/src/content/silo"
+	"github.com/nkanaev/yarr/src/events"
 	"github.com/nkanaev/yarr/src/server/auth"
 	"github.com/nkanaev/yarr/src/server/gzip"
 	"github.com/nkanaev/yarr/src/server/opml"
@@ -47,27 +49,61 @@ func (s *Server) handler() http.Handler {
 	publicMux.HandleFunc("/login", s.handleLogin)
 	publicMux.HandleFunc("/static/{path...}", http.StripPrefix("/static/", staticFS).ServeHTTP)
 	publicMux.HandleFunc("/fever/", s.handleFever)
+	publicMux.HandleFunc("/accounts/ClientLogin", s.handleGReaderLogin)
+	publicMux.Handle("/reader/api/0/", s.greaderHandler())
+	publicMux.Handle("/v1/", s.minifluxHandler())
+	nextcloud := s.nextcloudHandler()
+	publicMux.Handle("/index.php/apps/news/api", nextcloud)
+	publicMux.Handle("/index.php/apps/news/api/", nextcloud)
 	publicMux.HandleFunc("/manifest.json", s.handleManifest)
+	publicMux.HandleFunc("/websub/{id}", s.handleWebSub)
+	publicMux.HandleFunc("/out/{path...}", s.handleOutput)
 
 	secureMux := http.NewServeMux()
-	secureMux.HandleFunc("/api/status", s.handleStatus)
-	secureMux.HandleFunc("/api/folders", s.handleFolderList)
-	secureMux.HandleFunc("/api/folders/{id}", s.handleFolder)
-	secureMux.HandleFunc("/api/feeds", s.handleFeedList)
-	secureMux.HandleFunc("/api/feeds/refresh", s.handleFeedRefresh)
-	secureMux.HandleFunc("/api/feeds/errors", s.handleFeedErrors)
-	secureMux.HandleFunc("/api/feeds/{id}", s.handleFeed)
-	secureMux.HandleFunc("/api/items", s.handleItemList)
-	secureMux.HandleFunc("/api/items/{id}", s.handleItem)
-	secureMux.HandleFunc("/api/settings", s.handleSettings)
-	secureMux.HandleFunc("/opml/import", s.handleOPMLImport)
-	secureMux.HandleFunc("/opml/export", s.handleOPMLExport)
+	secureMux.HandleFunc("/api/status", s.userHandler((*Server).handleStatus))
+	secureMux.HandleFunc("/api/events", s.userHandler((*Server).handleEvents))
+	secureMux.HandleFunc("/api/folders", s.userHandler((*Server).handleFolderList))
+	secureMux.HandleFunc("/api/folders/{id}", s.userHandler((*Server).handleFolder))
+	secureMux.HandleFunc("/api/feeds", s.userHandler((*Server).handleFeedList))
+	secureMux.HandleFunc("/api/feeds/refresh", s.userHandler((*Server).handleFeedRefresh))
+	secureMux.HandleFunc("/api/feeds/errors", s.userHandler((*Server).handleFeedErrors))
+	secureMux.HandleFunc("/api/feeds/{id}", s.userHandler((*Server).handleFeed))
+	secureMux.HandleFunc("/api/feeds/{id}/enable", s.userHandler((*Server).handleFeedEnable))
+	secureMux.HandleFunc("/api/feeds/{id}/history", s.userHandler((*Server).handleFeedHistory))
+	secureMux.HandleFunc("/api/feeds/{id}/scraper", s.userHandler((*Server).handleFeedScraper))
+	secureMux.HandleFunc("/api/items", s.userHandler((*Server).handleItemList))
+	secureMux.HandleFunc("/api/items/{id}", s.userHandler((*Server).handleItem))
+	secureMux.HandleFunc("/api/items/{id}/labels", s.userHandler((*Server).handleItemLabels))
+	secureMux.HandleFunc("/api/labels", s.userHandler((*Server).handleLabelList))
+	secureMux.HandleFunc("/api/labels/{id}", s.userHandler((*Server).handleLabel))
+	secureMux.HandleFunc("/api/labels/{id}/items/{item_id}", s.userHandler((*Server).handleLabelItem))
+	secureMux.HandleFunc("/api/rules", s.userHandler((*Server).handleRuleList))
+	secureMux.HandleFunc("/api/rules/dry-run", s.userHandler((*Server).handleRuleDryRun))
+	secureMux.HandleFunc("/api/rules/{id}", s.userHandler((*Server).handleRule))
+	secureMux.HandleFunc("/api/searches", s.userHandler((*Server).handleSavedSearchList))
+	secureMux.HandleFunc("/api/searches/{id}", s.userHandler((*Server).handleSavedSearch))
+	secureMux.HandleFunc("/api/webhooks", s.userHandler((*Server).handleWebhookList))
+	secureMux.HandleFunc("/api/webhooks/{id}", s.userHandler((*Server).handleWebhook))
+	secureMux.HandleFunc("/api/webhooks/{id}/deliveries", s.userHandler((*Server).handleWebhookDeliveries))
+	secureMux.HandleFunc("/api/webhooks/{id}/test", s.userHandler((*Server).handleWebhookTest))
+	secureMux.HandleFunc("/api/settings", s.userHandler((*Server).handleSettings))
+	secureMux.HandleFunc("/api/outputs", s.userHandler((*Server).handleOutputFeedList))
+	secureMux.HandleFunc("/api/outputs/{id}", s.userHandler((*Server).handleOutputFeed))
+	secureMux.HandleFunc("/api/apikeys", s.userHandler((*Server).handleAPIKeyList))
+	secureMux.HandleFunc("/api/apikeys/{id}", s.userHandler((*Server).handleAPIKey))
+	secureMux.HandleFunc("/api/users", s.userHandler((*Server).handleUserList))
+	secureMux.HandleFunc("/api/users/{id}", s.userHandler((*Server).handleUser))
+	secureMux.HandleFunc("/api/backup", s.userHandler((*Server).handleBackup))
+	secureMux.HandleFunc("/api/archive", s.userHandler((*Server).handleArchive))
+	secureMux.HandleFunc("/media/{id}", s.userHandler((*Server).handleMedia))
+	secureMux.HandleFunc("/opml/import", s.userHandler((*Server).handleOPMLImport))
+	secureMux.HandleFunc("/opml/export", s.userHandler((*Server).handleOPMLExport))
 	secureMux.HandleFunc("/page", s.handlePageCrawl)
 	secureMux.HandleFunc("/logout", s.handleLogout)
 
 	var protected http.Handler = secureMux
-	if s.Username != "" && s.Password != "" {
-		protected = auth.Middleware(s.Username, s.Password, secureMux)
+	if s.authEnabled() {
+		protected = auth.Middleware(s.lookupUser, s.lookupToken, secureMux)
 	}
 
 	dispatch := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -96,29 +132,42 @@ func (s *Server) handler() http.Handler {
 		})
 	}
 
-	return gzip.Middleware(dispatch)
+	// media files are compressed already, and served in ranges
+	compressed := gzip.Middleware(dispatch)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, s.BasePath+"/media/") {
+			dispatch.ServeHTTP(w, r)
+			return
+		}
+		compressed.ServeHTTP(w, r)
+	})
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	isAuthenticated := false
 	requiresAuth := false
-	if s.Username == "" && s.Password == "" {
+	userID := model.DefaultUserID
+	if !s.authEnabled() {
 		isAuthenticated = true
 	} else {
 		requiresAuth = true
-		isAuthenticated = auth.IsAuthenticated(r, s.Username, s.Password)
+		if id, ok := auth.IsAuthenticated(r, s.lookupUser); ok {
+			userID, isAuthenticated = id, true
+		}
 	}
 
-	settings := s.db.GetSettings()
+	scoped := s.forUser(userID)
+	settings := scoped.userSettings()
 	if !isAuthenticated {
+		public := scoped.db.GetSettings()
 		settings = model.Settings{
-			Language:  settings.Language,
-			ThemeName: settings.ThemeName,
-		}
+			Language:  public.Language,
+			ThemeName: public.ThemeName,
+		}.Map()
 	}
 
 	writeHTML(w, http.StatusOK, assets.Templates().Lookup("index.html"), map[string]any{
-		"settings":      settings.Map(),
+		"settings":      settings,
 		"authenticated": isAuthenticated,
 		"requiresAuth":  requiresAuth,
 	})
@@ -143,9 +192,15 @@ func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	searchStats, err := s.db.SavedSearchStats()
+	if err != nil {
+		log.Print(err)
+		searchStats = []model.SavedSearchStat{}
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"running": s.worker.FeedsPending(),
-		"stats":   s.db.FeedStats(),
+		"running":      s.worker.FeedsPending(),
+		"stats":        s.db.FeedStats(),
+		"search_stats": searchStats,
 	})
 }
 
@@ -197,29 +252,200 @@ func (s *Server) handleFolder(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleLabelList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.db.ListLabels())
+	case http.MethodPost:
+		var body LabelForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(body.Title) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Label title missing."})
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.db.CreateLabel(body.Title))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleLabel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var body LabelForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(body.Title) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Label title missing."})
+			return
+		}
+		s.db.UpdateLabel(id, model.UpdateLabelParams{Title: &body.Title})
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		s.db.DeleteLabel(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleLabelItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.ParseInt(r.PathValue("item_id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if !s.db.AddItemLabel(itemID, id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !s.db.RemoveItemLabel(itemID, id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleItemLabels(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	labelIDs := s.db.ListItemLabels([]int64{id})[id]
+	labels := make([]model.Label, 0, len(labelIDs))
+	for _, label := range s.db.ListLabels() {
+		if slices.Contains(labelIDs, label.Id) {
+			labels = append(labels, label)
+		}
+	}
+	writeJSON(w, http.StatusOK, labels)
+}
+
 func (s *Server) handleFeedRefresh(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		s.worker.RefreshFeeds()
+		s.worker.RefreshFeeds(s.db.UserID())
 		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
+type FeedError struct {
+	Error        string     `json:"error"`
+	FailureCount int        `json:"failure_count"`
+	FirstFailure *time.Time `json:"first_failure"`
+	NextRefresh  *time.Time `json:"next_refresh"`
+	Disabled     bool       `json:"disabled"`
+}
+
 func (s *Server) handleFeedErrors(w http.ResponseWriter, r *http.Request) {
-	errors := make(map[int64]string)
+	errors := make(map[int64]FeedError)
 	states, err := s.db.ListFeedStates()
 	if err == nil {
 		for _, state := range states {
-			if state.LastError != "" {
-				errors[state.FeedID] = state.LastError
+			if state.LastError != "" || state.Disabled {
+				errors[state.FeedID] = FeedError{
+					Error:        state.LastError,
+					FailureCount: state.FailureCount,
+					FirstFailure: state.FirstFailure,
+					NextRefresh:  state.NextRefresh,
+					Disabled:     state.Disabled,
+				}
 			}
 		}
 	}
 	writeJSON(w, http.StatusOK, errors)
 }
 
+// handleFeedEnable resumes polling of a disabled (or backing off) feed
+// and refreshes it right away.
+func (s *Server) handleFeedEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	feed := s.db.GetFeed(id)
+	if feed == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	now := time.Now()
+	failures, disabled, empty := 0, false, ""
+	_, err = s.db.UpdateFeedState(id, model.UpdateFeedStateParams{
+		LastError:    &empty,
+		NextRefresh:  &now,
+		FailureCount: &failures,
+		FirstFailure: model.SetNullable[time.Time](nil),
+		Disabled:     &disabled,
+	})
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.worker.RefreshFeed(*feed)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleFeedHistory lists the urls the feed was moved from.
+func (s *Server) handleFeedHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if s.db.GetFeed(id) == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	changes, err := s.db.ListFeedLinkChanges(id)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
 func (s *Server) handleFeedList(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -231,6 +457,10 @@ func (s *Server) handleFeedList(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		if form.Scraper != nil {
+			s.createScrapedFeed(w, form)
+			return
+		}
 
 		result, err := worker.DiscoverFeed(form.Url)
 		switch {
@@ -244,22 +474,7 @@ func (s *Server) handleFeedList(w http.ResponseWriter, r *http.Request) {
 				map[string]any{"status": "multiple", "choice": result.Sources},
 			)
 		case result.Feed != nil:
-			title := result.Feed.Title
-			if form.TitleOverride != "" {
-				title = form.TitleOverride
-			}
-			feed := s.db.CreateFeed(model.CreateFeedParams{
-				Title:    title,
-				Link:     result.Feed.SiteURL,
-				FeedLink: result.FeedLink,
-				FolderID: form.FolderID,
-			})
-			items := worker.ConvertItems(result.Feed.Items, *feed)
-			if len(items) > 0 {
-				s.db.CreateItems(items)
-			}
-			s.worker.FindFeedFavicon(*feed)
-
+			feed := s.createDiscoveredFeed(result, form.TitleOverride, form.FolderID)
 			writeJSON(w, http.StatusOK, map[string]any{
 				"status": "success",
 				"feed":   feed,
@@ -272,6 +487,33 @@ func (s *Server) handleFeedList(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// createDiscoveredFeed stores a feed found by worker.DiscoverFeed together
+// with its current items and kicks off the favicon lookup.
+func (s *Server) createDiscoveredFeed(result *worker.DiscoverResult, titleOverride string, folderID *int64) *model.Feed {
+	title := result.Feed.Title
+	if titleOverride != "" {
+		title = titleOverride
+	}
+	feed := s.db.CreateFeed(model.CreateFeedParams{
+		Title:    title,
+		Link:     result.Feed.SiteURL,
+		FeedLink: result.FeedLink,
+		FolderID: folderID,
+	})
+	if feed == nil {
+		return nil
+	}
+	items := worker.ConvertItems(result.Feed.Items, *feed)
+	items = worker.ApplyFeedRules(s.db, *feed, items)
+	if len(items) > 0 {
+		if created, _ := s.db.CreateItems(items); len(created) > 0 {
+			s.publish(events.ItemsCreated, map[string]any{"feed_id": feed.Id, "count": len(created)})
+		}
+	}
+	s.worker.FindFeedFavicon(*feed)
+	return feed
+}
+
 func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
@@ -312,7 +554,28 @@ func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
 				params.FeedLink = &l
 			}
 		}
-		s.db.UpdateFeed(id, params)
+		if interval, ok := body["refresh_interval"]; ok {
+			if interval == nil {
+				params.RefreshInterval = model.SetNullable[int64](nil)
+			} else if reflect.TypeOf(interval).Kind() == reflect.Float64 {
+				minutes := int64(interval.(float64))
+				if minutes <= 0 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				params.RefreshInterval = model.SetNullable(&minutes)
+			}
+		}
+		if fetch, ok := body["fetch_content"].(bool); ok {
+			params.FetchContent = &fetch
+		}
+		if download, ok := body["download_media"].(bool); ok {
+			params.DownloadMedia = &download
+		}
+		if _, err := s.db.UpdateFeed(id, params); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	case http.MethodDelete:
 		s.db.DeleteFeed(id)
@@ -344,9 +607,11 @@ func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
 		}
 
 		item.Content = sanitizer.Sanitize(item.Link, item.Content)
+		item.FullContent = sanitizer.Sanitize(item.Link, item.FullContent)
 		for i, link := range item.MediaLinks {
 			item.MediaLinks[i].Description = sanitizer.Sanitize(item.Link, link.Description)
 		}
+		s.localMediaLinks([]model.Item{*item})
 
 		writeJSON(w, http.StatusOK, item)
 	case http.MethodPut:
@@ -357,7 +622,7 @@ func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if body.Status != nil {
-			s.db.UpdateItemStatus(id, *body.Status)
+			s.updateItemStatus(id, *body.Status)
 		}
 		w.WriteHeader(http.StatusOK)
 	default:
@@ -378,6 +643,9 @@ func (s *Server) handleItemList(w http.ResponseWriter, r *http.Request) {
 		if feedID, err := strconv.ParseInt(query.Get("feed_id"), 10, 64); err == nil {
 			filter.FeedID = &feedID
 		}
+		if labelID, err := strconv.ParseInt(query.Get("label_id"), 10, 64); err == nil {
+			filter.Label = &labelID
+		}
 		if after, err := strconv.ParseInt(query.Get("after"), 10, 64); err == nil {
 			filter.After = &after
 		}
@@ -388,6 +656,28 @@ func (s *Server) handleItemList(w http.ResponseWriter, r *http.Request) {
 		if search := query.Get("search"); len(search) != 0 {
 			filter.Search = &search
 		}
+		if author := query.Get("author"); len(author) != 0 {
+			filter.Author = &author
+		}
+		if category := query.Get("category"); len(category) != 0 {
+			filter.Category = &category
+		}
+		// a saved search replaces the search query, and the status if it has one
+		if searchID, err := strconv.ParseInt(query.Get("search_id"), 10, 64); err == nil {
+			saved, err := s.db.GetSavedSearch(searchID)
+			if err != nil {
+				log.Print(err)
+			}
+			if saved == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			filter.Search = &saved.Query
+			if saved.Status != nil {
+				filter.Status = saved.Status
+			}
+		}
+		filter.SortByRelevance = query.Get("relevance") == "true"
 		newestFirst := query.Get("oldest_first") != "true"
 
 		items := s.db.ListItems(filter, perPage+1, newestFirst, true)
@@ -403,6 +693,7 @@ func (s *Server) handleItemList(w http.ResponseWriter, r *http.Request) {
 				items[i].Title = htmlutil.TruncateText(text, 140)
 			}
 		}
+		s.localMediaLinks(items)
 		writeJSON(w, http.StatusOK, map[string]any{
 			"list":     items,
 			"has_more": hasMore,
@@ -417,7 +708,10 @@ func (s *Server) handleItemList(w http.ResponseWriter, r *http.Request) {
 		if feedID, err := strconv.ParseInt(query.Get("feed_id"), 10, 64); err == nil {
 			filter.FeedID = &feedID
 		}
-		s.db.MarkItemsRead(filter)
+		if labelID, err := strconv.ParseInt(query.Get("label_id"), 10, 64); err == nil {
+			filter.Label = &labelID
+		}
+		s.markItemsRead(filter)
 		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -427,13 +721,17 @@ func (s *Server) handleItemList(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, s.db.GetSettings())
+		writeJSON(w, http.StatusOK, s.userSettings())
 	case http.MethodPut:
 		var params model.UpdateSettingsParams
 		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		if params.RefreshRate != nil && s.db.UserID() != model.DefaultUserID {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "The refresh rate can only be changed by the default user."})
+			return
+		}
 		if s.db.UpdateSettings(params) {
 			if params.RefreshRate != nil {
 				s.worker.SetRefreshRate(s.db.GetSettings().RefreshRate)
@@ -447,6 +745,71 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleAPIKeyList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := s.db.ListAPIKeys()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+	case http.MethodPost:
+		var body APIKeyCreateForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(body.Name) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "API key name missing."})
+			return
+		}
+		if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "API key expiry must be in the future."})
+			return
+		}
+		token, hash := auth.NewToken()
+		key, err := s.db.CreateAPIKey(model.CreateAPIKeyParams{
+			Name:      body.Name,
+			TokenHash: hash,
+			ReadOnly:  body.ReadOnly,
+			ExpiresAt: body.ExpiresAt,
+		})
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// the token is shown only once and can't be recovered afterwards
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"key":   key,
+			"token": token,
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodDelete:
+		if !s.db.DeleteAPIKey(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (s *Server) handleOPMLImport(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
@@ -461,27 +824,7 @@ func (s *Server) handleOPMLImport(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		for _, f := range doc.Feeds {
-			s.db.CreateFeed(model.CreateFeedParams{
-				Title:    f.Title,
-				Link:     f.SiteUrl,
-				FeedLink: f.FeedUrl,
-			})
-		}
-		for _, f := range doc.Folders {
-			folder := s.db.CreateFolder(f.Title)
-			for _, ff := range f.AllFeeds() {
-				s.db.CreateFeed(model.CreateFeedParams{
-					Title:    ff.Title,
-					Link:     ff.SiteUrl,
-					FeedLink: ff.FeedUrl,
-					FolderID: &folder.Id,
-				})
-			}
-		}
-
-		s.worker.RefreshFeeds()
-
+		s.importOPML(doc)
 		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -494,43 +837,68 @@ func (s *Server) handleOPMLExport(w http.ResponseWriter, r *http.Request) {
 		filename := fmt.Sprintf("subscriptions_%s.opml", time.Now().Format("2006-01-02_15-04-05"))
 		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
 		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		w.Write([]byte(s.exportOPML().OPML()))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
 
-		doc := opml.Folder{}
-
-		feedsByFolderID := make(map[int64][]*model.Feed)
-		for _, feed := range s.db.ListFeeds() {
-			if feed.FolderId == nil {
-				doc.Feeds = append(doc.Feeds, opml.Feed{
-					Title:   feed.Title,
-					FeedUrl: feed.FeedLink,
-					SiteUrl: feed.Link,
-				})
-			} else {
-				id := *feed.FolderId
-				feedsByFolderID[id] = append(feedsByFolderID[id], &feed)
-			}
+func (s *Server) importOPML(doc opml.Folder) {
+	for _, f := range doc.Feeds {
+		s.db.CreateFeed(model.CreateFeedParams{
+			Title:    f.Title,
+			Link:     f.SiteUrl,
+			FeedLink: f.FeedUrl,
+		})
+	}
+	for _, f := range doc.Folders {
+		folder := s.db.CreateFolder(f.Title)
+		for _, ff := range f.AllFeeds() {
+			s.db.CreateFeed(model.CreateFeedParams{
+				Title:    ff.Title,
+				Link:     ff.SiteUrl,
+				FeedLink: ff.FeedUrl,
+				FolderID: &folder.Id,
+			})
 		}
+	}
 
-		for _, folder := range s.db.ListFolders() {
-			folderFeeds := feedsByFolderID[folder.Id]
-			if len(folderFeeds) == 0 {
-				continue
-			}
-			opmlfolder := opml.Folder{Title: folder.Title}
-			for _, feed := range folderFeeds {
-				opmlfolder.Feeds = append(opmlfolder.Feeds, opml.Feed{
-					Title:   feed.Title,
-					FeedUrl: feed.FeedLink,
-					SiteUrl: feed.Link,
-				})
-			}
-			doc.Folders = append(doc.Folders, opmlfolder)
+	s.worker.RefreshFeeds(s.db.UserID())
+}
+
+func (s *Server) exportOPML() opml.Folder {
+	doc := opml.Folder{}
+
+	feedsByFolderID := make(map[int64][]*model.Feed)
+	for _, feed := range s.db.ListFeeds() {
+		if feed.FolderId == nil {
+			doc.Feeds = append(doc.Feeds, opml.Feed{
+				Title:   feed.Title,
+				FeedUrl: feed.FeedLink,
+				SiteUrl: feed.Link,
+			})
+		} else {
+			id := *feed.FolderId
+			feedsByFolderID[id] = append(feedsByFolderID[id], &feed)
 		}
+	}
 
-		w.Write([]byte(doc.OPML()))
-	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	for _, folder := range s.db.ListFolders() {
+		folderFeeds := feedsByFolderID[folder.Id]
+		if len(folderFeeds) == 0 {
+			continue
+		}
+		opmlfolder := opml.Folder{Title: folder.Title}
+		for _, feed := range folderFeeds {
+			opmlfolder.Feeds = append(opmlfolder.Feeds, opml.Feed{
+				Title:   feed.Title,
+				FeedUrl: feed.FeedLink,
+				SiteUrl: feed.Link,
+			})
+		}
+		doc.Folders = append(doc.Folders, opmlfolder)
 	}
+	return doc
 }
 
 func (s *Server) handlePageCrawl(w http.ResponseWriter, r *http.Request) {
@@ -572,8 +940,9 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		username := r.FormValue("username")
 		password := r.FormValue("password")
-		if auth.StringsEqual(username, s.Username) && auth.StringsEqual(password, s.Password) {
-			auth.Authenticate(w, s.Username, s.Password, s.BasePath)
+		if _, ok := s.checkPassword(username, password); ok {
+			_, key, _ := s.lookupUser(username)
+			auth.Authenticate(w, username, key, s.BasePath)
 			return
 		} else {
 			w.WriteHeader(http.StatusUnauthorized)
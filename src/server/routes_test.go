@@ -10,8 +10,11 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/nkanaev/yarr/src/server/auth"
 	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
 )
 
 func TestStatic(t *testing.T) {
@@ -148,3 +151,152 @@ func TestFeedCreateWithTitleOverride(t *testing.T) {
 		}
 	})
 }
+
+func TestAPITokens(t *testing.T) {
+	db, serve := newTestServer(t, withCredentials("user", "pass"))
+
+	full, hash := auth.NewToken()
+	db.CreateAPIKey(model.CreateAPIKeyParams{Name: "full", TokenHash: hash})
+	readOnly, hash := auth.NewToken()
+	db.CreateAPIKey(model.CreateAPIKeyParams{Name: "read-only", TokenHash: hash, ReadOnly: true})
+	expired, hash := auth.NewToken()
+	past := time.Now().Add(-time.Minute)
+	db.CreateAPIKey(model.CreateAPIKeyParams{Name: "expired", TokenHash: hash, ExpiresAt: &past})
+
+	request := func(method, path, token, body string) int {
+		header := http.Header{}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+		return serve(method, path, body, header).Code
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", "GET", "/api/folders", "", "", http.StatusUnauthorized},
+		{"unknown token", "GET", "/api/folders", "nope", "", http.StatusUnauthorized},
+		{"expired token", "GET", "/api/folders", expired, "", http.StatusUnauthorized},
+		{"full read", "GET", "/api/folders", full, "", http.StatusOK},
+		{"full write", "POST", "/api/folders", full, `{"title": "one"}`, http.StatusCreated},
+		{"read-only read", "GET", "/api/folders", readOnly, "", http.StatusOK},
+		{"read-only write", "POST", "/api/folders", readOnly, `{"title": "two"}`, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		if status := request(test.method, test.path, test.token, test.body); status != test.status {
+			t.Errorf("%s: expected %d, got %d", test.name, test.status, status)
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		recorder := serve("POST", "/api/apikeys", `{"name": "script", "read_only": true}`, http.Header{"Authorization": {"Bearer " + full}})
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", recorder.Code)
+		}
+		var resp struct {
+			Key   model.APIKey `json:"key"`
+			Token string       `json:"token"`
+		}
+		json.NewDecoder(recorder.Body).Decode(&resp)
+		if !resp.Key.ReadOnly || resp.Token == "" {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+		if status := request("GET", "/api/folders", resp.Token, ""); status != http.StatusOK {
+			t.Errorf("expected new token to work, got %d", status)
+		}
+		if status := request("DELETE", fmt.Sprintf("/api/apikeys/%d", resp.Key.Id), full, ""); status != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", status)
+		}
+		if status := request("GET", "/api/folders", resp.Token, ""); status != http.StatusUnauthorized {
+			t.Errorf("expected revoked token to be rejected, got %d", status)
+		}
+	})
+}
+
+func TestLabelAPI(t *testing.T) {
+	db, request := newTestServer(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+	db.CreateItems([]model.Item{{GUID: "1", FeedId: feed.Id, Title: "one"}})
+	item := db.ListItems(model.ItemFilter{}, 1, false, false)[0]
+
+	res := request("POST", "/api/labels", `{"title": "to-review"}`)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var label model.Label
+	json.NewDecoder(res.Body).Decode(&label)
+
+	if res := request("PUT", fmt.Sprintf("/api/labels/%d/items/%d", label.Id, item.Id), ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	if res := request("PUT", fmt.Sprintf("/api/labels/%d/items/%d", label.Id+1, item.Id), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown label, got %d", res.Code)
+	}
+
+	var labels []model.Label
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/items/%d/labels", item.Id), "").Body).Decode(&labels)
+	if len(labels) != 1 || labels[0].Title != "to-review" {
+		t.Errorf("unexpected item labels: %#v", labels)
+	}
+
+	var items struct {
+		List []model.Item `json:"list"`
+	}
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/items?label_id=%d", label.Id), "").Body).Decode(&items)
+	if len(items.List) != 1 || items.List[0].Id != item.Id {
+		t.Errorf("unexpected labelled items: %#v", items.List)
+	}
+
+	if res := request("DELETE", fmt.Sprintf("/api/labels/%d/items/%d", label.Id, item.Id), ""); res.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", res.Code)
+	}
+	if res := request("DELETE", fmt.Sprintf("/api/labels/%d", label.Id), ""); res.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", res.Code)
+	}
+	json.NewDecoder(request("GET", "/api/labels", "").Body).Decode(&labels)
+	if len(labels) != 0 {
+		t.Errorf("expected no labels, got %#v", labels)
+	}
+}
+
+func TestFeedErrorsAndEnable(t *testing.T) {
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+	}))
+	defer feedSrv.Close()
+
+	db, request := newTestServer(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: feedSrv.URL})
+	errMsg, failures, disabled := "feed is gone", 3, true
+	db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{
+		LastError:    &errMsg,
+		FailureCount: &failures,
+		Disabled:     &disabled,
+	})
+
+	var errors map[string]FeedError
+	json.NewDecoder(request("GET", "/api/feeds/errors", "").Body).Decode(&errors)
+	feedErr, ok := errors[fmt.Sprint(feed.Id)]
+	if !ok || !feedErr.Disabled || feedErr.FailureCount != 3 || feedErr.Error != errMsg {
+		t.Fatalf("unexpected errors: %#v", errors)
+	}
+
+	if res := request("GET", fmt.Sprintf("/api/feeds/%d/enable", feed.Id), ""); res.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", res.Code)
+	}
+	if res := request("POST", fmt.Sprintf("/api/feeds/%d/enable", feed.Id+1), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", res.Code)
+	}
+	if res := request("POST", fmt.Sprintf("/api/feeds/%d/enable", feed.Id), ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	state, _ := db.GetFeedState(feed.Id)
+	if state.Disabled || state.FailureCount != 0 {
+		t.Errorf("expected feed to be enabled: %#v", state)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// number of the most recent items checked by a dry run
+const ruleDryRunLimit = 1000
+
+// parseRuleForm decodes and validates the rule in the request body.
+func parseRuleForm(w http.ResponseWriter, r *http.Request) (*worker.Rule, bool) {
+	var body RuleForm
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	rule := model.Rule{
+		Title:      body.Title,
+		FeedID:     body.FeedID,
+		Conditions: body.Conditions,
+		Action:     body.Action,
+		IsEnabled:  body.IsEnabled == nil || *body.IsEnabled,
+	}
+	compiled, err := worker.CompileRule(rule)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return compiled, true
+}
+
+func (s *Server) handleRuleList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := s.db.ListRules()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+	case http.MethodPost:
+		compiled, ok := parseRuleForm(w, r)
+		if !ok {
+			return
+		}
+		rule, err := s.db.CreateRule(compiled.Rule)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, rule)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		rule, err := s.db.GetRule(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if rule == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, rule)
+	case http.MethodPut:
+		compiled, ok := parseRuleForm(w, r)
+		if !ok {
+			return
+		}
+		updated, err := s.db.UpdateRule(id, compiled.Rule)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !s.db.DeleteRule(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRuleDryRun lists the existing items the rule in the body would match,
+// without changing anything.
+func (s *Server) handleRuleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rule, ok := parseRuleForm(w, r)
+	if !ok {
+		return
+	}
+
+	filter := model.ItemFilter{FeedID: rule.FeedID}
+	matches := make([]model.Item, 0)
+	scanned := 0
+	for scanned < ruleDryRunLimit {
+		items := s.db.ListItems(filter, min(100, ruleDryRunLimit-scanned), true, true)
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			if rule.Match(item) {
+				item.Content = ""
+				matches = append(matches, item)
+			}
+		}
+		scanned += len(items)
+		filter.After = &items[len(items)-1].Id
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"scanned": scanned,
+		"items":   matches,
+	})
+}
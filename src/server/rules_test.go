@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestRuleAPI(t *testing.T) {
+	db, request := newTestServer(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "Sponsored post"},
+		{GUID: "2", FeedId: feed.Id, Title: "Regular post"},
+	})
+
+	const rule = `{"title": "ads", "conditions": [{"field": "title", "op": "contains", "value": "sponsored"}], "action": "read"}`
+
+	if res := request("POST", "/api/rules", `{"action": "read", "conditions": [{"field": "title", "op": "matches", "value": "("}]}`); res.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid rule to be rejected, got %d", res.Code)
+	}
+
+	res := request("POST", "/api/rules/dry-run", rule)
+	var dryRun struct {
+		Scanned int          `json:"scanned"`
+		Items   []model.Item `json:"items"`
+	}
+	json.NewDecoder(res.Body).Decode(&dryRun)
+	if dryRun.Scanned != 2 || len(dryRun.Items) != 1 || dryRun.Items[0].Title != "Sponsored post" {
+		t.Fatalf("unexpected dry run: %#v", dryRun)
+	}
+	if rules, _ := db.ListRules(); len(rules) != 0 {
+		t.Error("expected dry run not to save the rule")
+	}
+	if item := db.ListItems(model.ItemFilter{}, 10, false, false)[0]; item.Status != model.UNREAD {
+		t.Error("expected dry run not to change items")
+	}
+
+	res = request("POST", "/api/rules", rule)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var created model.Rule
+	json.NewDecoder(res.Body).Decode(&created)
+	if created.Id == 0 || !created.IsEnabled || created.Action != model.RuleMarkRead {
+		t.Fatalf("unexpected rule: %#v", created)
+	}
+
+	update := `{"title": "ads", "conditions": [{"field": "title", "op": "contains", "value": "sponsored"}], "action": "drop", "is_enabled": false}`
+	if res := request("PUT", fmt.Sprintf("/api/rules/%d", created.Id), update); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	var fetched model.Rule
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/rules/%d", created.Id), "").Body).Decode(&fetched)
+	if fetched.Action != model.RuleDrop || fetched.IsEnabled {
+		t.Errorf("unexpected rule after update: %#v", fetched)
+	}
+
+	if res := request("DELETE", fmt.Sprintf("/api/rules/%d", created.Id), ""); res.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", res.Code)
+	}
+	if res := request("GET", fmt.Sprintf("/api/rules/%d", created.Id), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", res.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// createScrapedFeed creates a feed from the items scraped off a page
+// without one.
+func (s *Server) createScrapedFeed(w http.ResponseWriter, form FeedCreateForm) {
+	scraper := form.Scraper.scraper(0)
+	sel := worker.ScraperSelectors(scraper)
+	if err := sel.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	result, err := worker.ScrapeFeed(form.Url, sel)
+	if err != nil {
+		log.Printf("Failed to scrape %s: %s", form.Url, err)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "notfound"})
+		return
+	}
+	feed := s.createDiscoveredFeed(result, form.TitleOverride, form.FolderID)
+	if feed != nil {
+		scraper.FeedID = feed.Id
+		if _, err := s.db.UpdateFeedScraper(scraper); err != nil {
+			log.Print(err)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"feed":   feed,
+	})
+}
+
+func (s *Server) handleFeedScraper(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		scraper, err := s.db.GetFeedScraper(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if scraper == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, scraper)
+	case http.MethodPut:
+		var form FeedScraperForm
+		if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		scraper := form.scraper(id)
+		if err := worker.ScraperSelectors(scraper).Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		updated, err := s.db.UpdateFeedScraper(scraper)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, scraper)
+	case http.MethodDelete:
+		if !s.db.DeleteFeedScraper(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
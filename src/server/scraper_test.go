@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestScrapedFeeds(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Status</title></head><body>
+			<div class="incident"><h3><a href="/i/2">Outage</a></h3><p>Resolved.</p></div>
+			<div class="incident"><h3><a href="/i/1">Maintenance</a></h3><p>Done.</p></div>
+		</body></html>`)
+	}))
+	defer page.Close()
+
+	db, serve := newTestServer(t, withCredentials("admin", "pass"))
+	token, hash := auth.NewToken()
+	if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "test", TokenHash: hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(method, path, body, http.Header{"Authorization": {"Bearer " + token}})
+	}
+
+	if res := request("POST", "/api/feeds", `{"url": "`+page.URL+`", "scraper": {"item": "div:first-child"}}`); res.Code != http.StatusBadRequest {
+		t.Errorf("expected unsupported selector to be rejected, got %d", res.Code)
+	}
+
+	res := request("POST", "/api/feeds", `{"url": "`+page.URL+`", "scraper": {"item": "div.incident", "title": "h3", "content": "p"}}`)
+	var created struct {
+		Status string
+		Feed   model.Feed
+	}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil || created.Status != "success" {
+		t.Fatalf("unexpected response: %d %v", res.Code, err)
+	}
+	if created.Feed.Title != "Status" || created.Feed.FeedLink != page.URL {
+		t.Errorf("unexpected feed: %#v", created.Feed)
+	}
+	items := db.ListItems(model.ItemFilter{}, 10, true, true)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %#v", items)
+	}
+	for _, item := range items {
+		if item.GUID != item.Link || !strings.HasPrefix(item.Link, page.URL+"/i/") {
+			t.Errorf("unexpected item: %#v", item)
+		}
+	}
+
+	path := "/api/feeds/" + strconv.FormatInt(created.Feed.Id, 10) + "/scraper"
+	res = request("GET", path, "")
+	var scraper model.FeedScraper
+	if err := json.NewDecoder(res.Body).Decode(&scraper); err != nil || scraper.Item != "div.incident" || scraper.Content != "p" {
+		t.Errorf("unexpected scraper: %#v (%v)", scraper, err)
+	}
+	if res := request("PUT", path, `{"item": ""}`); res.Code != http.StatusBadRequest {
+		t.Errorf("expected missing item selector to be rejected, got %d", res.Code)
+	}
+	if res := request("PUT", path, `{"item": "div.incident", "title": "h3 a"}`); res.Code != http.StatusOK {
+		t.Errorf("expected scraper to be updated, got %d", res.Code)
+	}
+	if res := request("DELETE", path, ""); res.Code != http.StatusNoContent {
+		t.Errorf("expected scraper to be deleted, got %d", res.Code)
+	}
+	if res := request("GET", path, ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected no scraper, got %d", res.Code)
+	}
+}
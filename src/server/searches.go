@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/storage/search"
+)
+
+// parseSavedSearchForm decodes and validates the saved search in the request body.
+func parseSavedSearchForm(w http.ResponseWriter, r *http.Request) (*model.SavedSearch, bool) {
+	var body SavedSearchForm
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	saved := &model.SavedSearch{
+		Name:  strings.TrimSpace(body.Name),
+		Query: strings.TrimSpace(body.Query),
+	}
+	if saved.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Search name missing."})
+		return nil, false
+	}
+	if search.Parse(saved.Query) == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Search query missing."})
+		return nil, false
+	}
+	if body.Status != nil && *body.Status != "" {
+		status, ok := model.StatusValues[*body.Status]
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid status."})
+			return nil, false
+		}
+		saved.Status = &status
+	}
+	return saved, true
+}
+
+func (s *Server) handleSavedSearchList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		searches, err := s.db.ListSavedSearches()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, searches)
+	case http.MethodPost:
+		form, ok := parseSavedSearchForm(w, r)
+		if !ok {
+			return
+		}
+		saved, err := s.db.CreateSavedSearch(*form)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, saved)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		saved, err := s.db.GetSavedSearch(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if saved == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, saved)
+	case http.MethodPut:
+		form, ok := parseSavedSearchForm(w, r)
+		if !ok {
+			return
+		}
+		updated, err := s.db.UpdateSavedSearch(id, *form)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !s.db.DeleteSavedSearch(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestSavedSearchAPI(t *testing.T) {
+	db, request := newTestServer(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+	db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "Go generics", Status: model.UNREAD},
+		{GUID: "2", FeedId: feed.Id, Title: "Go modules", Status: model.STARRED},
+		{GUID: "3", FeedId: feed.Id, Title: "Rust traits", Status: model.UNREAD},
+	})
+
+	invalid := []string{
+		`{"name": "", "query": "go"}`,
+		`{"name": "go", "query": "  "}`,
+		`{"name": "go", "query": "go", "status": "archived"}`,
+	}
+	for _, body := range invalid {
+		if res := request("POST", "/api/searches", body); res.Code != http.StatusBadRequest {
+			t.Errorf("expected %s to be rejected, got %d", body, res.Code)
+		}
+	}
+
+	res := request("POST", "/api/searches", `{"name": "Go", "query": "title:go"}`)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var created model.SavedSearch
+	json.NewDecoder(res.Body).Decode(&created)
+	if created.Id == 0 || created.Query != "title:go" || created.Status != nil {
+		t.Fatalf("unexpected search: %#v", created)
+	}
+
+	var status struct {
+		Stats []model.SavedSearchStat `json:"search_stats"`
+	}
+	json.NewDecoder(request("GET", "/api/status", "").Body).Decode(&status)
+	if len(status.Stats) != 1 || status.Stats[0] != (model.SavedSearchStat{SearchID: created.Id, UnreadCount: 1, StarredCount: 1}) {
+		t.Errorf("unexpected stats: %#v", status.Stats)
+	}
+
+	var items struct {
+		List []model.Item `json:"list"`
+	}
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/items?search_id=%d", created.Id), "").Body).Decode(&items)
+	if len(items.List) != 2 {
+		t.Errorf("expected 2 items, got %#v", items.List)
+	}
+
+	update := `{"name": "Go", "query": "title:go", "status": "starred"}`
+	if res := request("PUT", fmt.Sprintf("/api/searches/%d", created.Id), update); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	items.List = nil
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/items?search_id=%d&status=unread", created.Id), "").Body).Decode(&items)
+	if len(items.List) != 1 || items.List[0].Title != "Go modules" {
+		t.Errorf("expected the status of the search to be used, got %#v", items.List)
+	}
+
+	if res := request("DELETE", fmt.Sprintf("/api/searches/%d", created.Id), ""); res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.Code)
+	}
+	if res := request("GET", fmt.Sprintf("/api/searches/%d", created.Id), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", res.Code)
+	}
+	if res := request("GET", fmt.Sprintf("/api/items?search_id=%d", created.Id), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing search, got %d", res.Code)
+	}
+}
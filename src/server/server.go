@@ -7,7 +7,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/nkanaev/yarr/src/events"
 	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
 	"github.com/nkanaev/yarr/src/worker"
 )
 
@@ -15,8 +17,15 @@ type Server struct {
 	Addr   string
 	db     storage.Storage
 	worker *worker.Worker
+	events *events.Bus
 
 	BasePath string
+	// public url of the service, enables WebSub push subscriptions
+	PublicURL string
+	// local copies of enclosures
+	Media worker.MediaConfig
+	// periodic backups of the database
+	Backup worker.BackupConfig
 
 	// auth
 	Username string
@@ -27,10 +36,12 @@ type Server struct {
 }
 
 func NewServer(db storage.Storage, addr string) *Server {
+	bus := events.NewBus()
 	return &Server{
 		db:     db,
 		Addr:   addr,
-		worker: worker.NewWorker(db),
+		worker: worker.NewWorker(db, bus),
+		events: bus,
 	}
 }
 
@@ -43,8 +54,20 @@ func (h *Server) GetAddr() string {
 }
 
 func (s *Server) Start() {
+	if s.authEnabled() {
+		// keep the default user in sync with the credentials from the command line
+		_, err := s.db.UpdateUser(model.DefaultUserID, model.UpdateUserParams{Username: &s.Username})
+		if err != nil {
+			log.Print(err)
+		}
+	}
+	if s.PublicURL != "" {
+		s.worker.SetWebSubCallback(strings.TrimSuffix(s.PublicURL, "/") + "/websub")
+	}
+	s.worker.SetMediaConfig(s.Media)
 	refreshRate := s.db.GetSettings().RefreshRate
 	s.worker.StartFeedCleaner()
+	s.worker.StartBackups(s.Backup)
 	s.worker.SetRefreshRate(refreshRate)
 
 	var ln net.Listener
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+)
+
+// newTestDB returns an in-memory database, silencing the log until the
+// test is over.
+func newTestDB(t *testing.T) storage.Storage {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestServer returns an in-memory database along with a function
+// sending requests to a server backed by it. The options are applied to
+// the server (e.g. to set the credentials) before its handler is built;
+// the headers are set on each request.
+func newTestServer(t *testing.T, options ...func(*Server)) (storage.Storage, func(method, path, body string, header ...http.Header) *httptest.ResponseRecorder) {
+	t.Helper()
+	db := newTestDB(t)
+	server := NewServer(db, "127.0.0.1:8000")
+	for _, option := range options {
+		option(server)
+	}
+	handler := server.handler()
+
+	return db, func(method, path, body string, header ...http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for _, h := range header {
+			for key, values := range h {
+				req.Header[key] = values
+			}
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+}
+
+// withCredentials makes the server require the given username & password.
+func withCredentials(username, password string) func(*Server) {
+	return func(s *Server) {
+		s.Username = username
+		s.Password = password
+	}
+}
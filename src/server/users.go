@@ -0,0 +1,301 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/nkanaev/yarr/src/server/auth"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *Server) authEnabled() bool {
+	return s.Username != "" && s.Password != ""
+}
+
+// lookupUser resolves the user signing in with the given name. The
+// credentials passed via -auth belong to the default user, the others
+// are stored in the database.
+func (s *Server) lookupUser(username string) (int64, string, bool) {
+	if !s.authEnabled() {
+		return 0, "", false
+	}
+	if auth.StringsEqual(username, s.Username) {
+		return model.DefaultUserID, s.Password, true
+	}
+	user, err := s.db.GetUserByName(username)
+	if err != nil {
+		log.Print(err)
+	}
+	if user == nil || user.Id == model.DefaultUserID || user.PasswordHash == "" {
+		return 0, "", false
+	}
+	return user.Id, user.PasswordHash, true
+}
+
+// checkPassword returns the id of the user with the given credentials.
+func (s *Server) checkPassword(username, password string) (int64, bool) {
+	if !s.authEnabled() {
+		return 0, false
+	}
+	if auth.StringsEqual(username, s.Username) {
+		return model.DefaultUserID, auth.StringsEqual(password, s.Password)
+	}
+	user, err := s.db.GetUserByName(username)
+	if err != nil {
+		log.Print(err)
+	}
+	if user == nil || user.Id == model.DefaultUserID || !auth.CheckPassword(password, user.PasswordHash) {
+		return 0, false
+	}
+	return user.Id, true
+}
+
+// lookupToken resolves the owner and the scope of an API token.
+func (s *Server) lookupToken(token string) (int64, bool, bool) {
+	key, err := s.db.GetAPIKeyByHash(auth.HashToken(token))
+	if err != nil {
+		log.Print(err)
+	}
+	if key == nil {
+		return 0, false, false
+	}
+	return key.UserId, key.ReadOnly, true
+}
+
+// checkFeverKey returns the id of the user with the given Fever api key.
+func (s *Server) checkFeverKey(apiKey string) (int64, bool) {
+	if !s.authEnabled() {
+		return 0, false
+	}
+	if auth.StringsEqual(apiKey, auth.FeverKey(s.Username, s.Password)) {
+		return model.DefaultUserID, true
+	}
+	users, err := s.db.ListUsers()
+	if err != nil {
+		log.Print(err)
+		return 0, false
+	}
+	for _, user := range users {
+		if user.Id != model.DefaultUserID && user.FeverKey != "" && auth.StringsEqual(apiKey, user.FeverKey) {
+			return user.Id, true
+		}
+	}
+	return 0, false
+}
+
+// userName returns the name of the user the server is scoped to.
+func (s *Server) userName() string {
+	if s.db.UserID() == model.DefaultUserID {
+		return s.Username
+	}
+	user, err := s.db.GetUser(s.db.UserID())
+	if err != nil {
+		log.Print(err)
+	}
+	if user == nil {
+		return ""
+	}
+	return user.Username
+}
+
+// userSettings returns the settings of the user. The refresh rate is shared
+// by all the users, so the one of the default user is reported to the others.
+func (s *Server) userSettings() map[string]any {
+	settings := s.db.GetSettings().Map()
+	if s.db.UserID() != model.DefaultUserID {
+		settings["refresh_rate"] = s.forUser(model.DefaultUserID).db.GetSettings().RefreshRate
+		settings["refresh_rate_locked"] = true
+	}
+	return settings
+}
+
+// forUser returns a copy of the server with the storage scoped to the user.
+func (s *Server) forUser(userID int64) *Server {
+	scoped := *s
+	scoped.db = storage.ForUser(s.db, userID)
+	return &scoped
+}
+
+// userHandler runs the handler on behalf of the user attached to the request.
+func (s *Server) userHandler(handler func(*Server, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(s.forUser(auth.UserID(r)), w, r)
+	}
+}
+
+func (s *Server) isAdmin() bool {
+	user, err := s.db.GetUser(s.db.UserID())
+	if err != nil {
+		log.Print(err)
+	}
+	return user != nil && user.IsAdmin
+}
+
+func (s *Server) handleUserList(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		users, err := s.db.ListUsers()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, users)
+	case http.MethodPost:
+		var body UserCreateForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body.Username == "" || body.Password == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Username or password missing."})
+			return
+		}
+		if body.Username == s.Username {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Username already taken."})
+			return
+		}
+		passwordHash, err := auth.HashPassword(body.Password)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		user, err := s.db.CreateUser(model.CreateUserParams{
+			Username:     body.Username,
+			PasswordHash: passwordHash,
+			FeverKey:     auth.FeverKey(body.Username, body.Password),
+			IsAdmin:      body.IsAdmin,
+		})
+		if err != nil {
+			log.Print(err)
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Username already taken."})
+			return
+		}
+		writeJSON(w, http.StatusCreated, user)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// lastAdmin reports whether the user is the only admin left.
+func (s *Server) lastAdmin(user *model.User) (bool, error) {
+	if !user.IsAdmin {
+		return false, nil
+	}
+	users, err := s.db.ListUsers()
+	if err != nil {
+		return false, err
+	}
+	for _, u := range users {
+		if u.IsAdmin && u.Id != user.Id {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var body UserUpdateForm
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		user, err := s.db.GetUser(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if user == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		params := model.UpdateUserParams{IsAdmin: body.IsAdmin}
+		if body.IsAdmin != nil && !*body.IsAdmin {
+			if id == model.DefaultUserID {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The default user is always an admin."})
+				return
+			}
+			last, err := s.lastAdmin(user)
+			if err != nil {
+				log.Print(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if last {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The last admin can't be removed."})
+				return
+			}
+		}
+		if body.Password != nil {
+			if id == model.DefaultUserID {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The default user's password is set with -auth."})
+				return
+			}
+			passwordHash, err := auth.HashPassword(*body.Password)
+			if err != nil {
+				log.Print(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			feverKey := auth.FeverKey(user.Username, *body.Password)
+			params.PasswordHash = &passwordHash
+			params.FeverKey = &feverKey
+		}
+		if _, err := s.db.UpdateUser(id, params); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if id == model.DefaultUserID {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The default user can't be deleted."})
+			return
+		}
+		user, err := s.db.GetUser(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if user == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		last, err := s.lastAdmin(user)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if last {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The last admin can't be removed."})
+			return
+		}
+		if !s.db.DeleteUser(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
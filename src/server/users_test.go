@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestUsers(t *testing.T) {
+	db, serve := newTestServer(t, withCredentials("admin", "pass"))
+	db.CreateFeed(model.CreateFeedParams{Title: "admin feed", FeedLink: "http://example.com/admin.xml"})
+
+	login := func(username, password string) []*http.Cookie {
+		form := url.Values{"username": {username}, "password": {password}}
+		recorder := serve("POST", "/login", form.Encode(), http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+		if recorder.Code != http.StatusOK {
+			return nil
+		}
+		return recorder.Result().Cookies()
+	}
+	request := func(cookies []*http.Cookie, method, path, body string) *httptest.ResponseRecorder {
+		header := http.Header{}
+		for _, cookie := range cookies {
+			header.Add("Cookie", cookie.Name+"="+cookie.Value)
+		}
+		return serve(method, path, body, header)
+	}
+
+	admin := login("admin", "pass")
+	if admin == nil {
+		t.Fatal("admin login failed")
+	}
+
+	res := request(admin, "POST", "/api/users", `{"username": "alice", "password": "secret"}`)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var user model.User
+	json.NewDecoder(res.Body).Decode(&user)
+	if user.Id == 0 || user.Username != "alice" || user.IsAdmin {
+		t.Fatalf("unexpected user: %#v", user)
+	}
+
+	if login("alice", "wrong") != nil {
+		t.Error("expected login with wrong password to fail")
+	}
+	alice := login("alice", "secret")
+	if alice == nil {
+		t.Fatal("alice login failed")
+	}
+
+	t.Run("isolation", func(t *testing.T) {
+		var feeds []model.Feed
+		json.NewDecoder(request(alice, "GET", "/api/feeds", "").Body).Decode(&feeds)
+		if len(feeds) != 0 {
+			t.Fatalf("expected no feeds for alice, got %#v", feeds)
+		}
+		json.NewDecoder(request(admin, "GET", "/api/feeds", "").Body).Decode(&feeds)
+		if len(feeds) != 1 || feeds[0].Title != "admin feed" {
+			t.Fatalf("unexpected admin feeds: %#v", feeds)
+		}
+		if res := request(alice, "GET", "/api/feeds/"+strconv.FormatInt(feeds[0].Id, 10), ""); res.Code == http.StatusOK {
+			t.Errorf("expected admin feed to be hidden from alice")
+		}
+		folder := storage.ForUser(db, user.Id).CreateFolder("alice folder")
+		body := fmt.Sprintf(`{"folder_id": %d}`, folder.Id)
+		if res := request(admin, "PUT", "/api/feeds/"+strconv.FormatInt(feeds[0].Id, 10), body); res.Code != http.StatusBadRequest {
+			t.Errorf("expected the folder of alice to be refused, got %d", res.Code)
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		night, sepia := "night", "sepia"
+		storage.ForUser(db, model.DefaultUserID).UpdateSettings(model.UpdateSettingsParams{ThemeName: &night})
+		storage.ForUser(db, user.Id).UpdateSettings(model.UpdateSettingsParams{ThemeName: &sepia})
+		if body := request(nil, "GET", "/", "").Body.String(); !strings.Contains(body, `data-theme="night"`) {
+			t.Error("expected anonymous visitors to get the theme of the default user")
+		}
+		if body := request(alice, "GET", "/", "").Body.String(); !strings.Contains(body, `data-theme="sepia"`) {
+			t.Error("expected users to get their own theme")
+		}
+	})
+
+	t.Run("refresh rate", func(t *testing.T) {
+		if res := request(alice, "PUT", "/api/settings", `{"refresh_rate": 10}`); res.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", res.Code)
+		}
+		if res := request(admin, "PUT", "/api/settings", `{"refresh_rate": 30}`); res.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", res.Code)
+		}
+		var settings map[string]any
+		json.NewDecoder(request(alice, "GET", "/api/settings", "").Body).Decode(&settings)
+		if settings["refresh_rate"] != float64(30) || settings["refresh_rate_locked"] != true {
+			t.Errorf("expected the refresh rate of the default user, got %#v", settings)
+		}
+		settings = nil
+		json.NewDecoder(request(admin, "GET", "/api/settings", "").Body).Decode(&settings)
+		if _, ok := settings["refresh_rate_locked"]; ok {
+			t.Errorf("expected the refresh rate to be unlocked for the default user, got %#v", settings)
+		}
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		if res := request(alice, "GET", "/api/users", ""); res.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", res.Code)
+		}
+		if res := request(alice, "GET", "/api/backup", ""); res.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for the backup, got %d", res.Code)
+		}
+		var users []model.User
+		json.NewDecoder(request(admin, "GET", "/api/users", "").Body).Decode(&users)
+		if len(users) != 2 {
+			t.Errorf("unexpected users: %#v", users)
+		}
+	})
+
+	t.Run("admins", func(t *testing.T) {
+		if res := request(admin, "PUT", "/api/users/1", `{"is_admin": false}`); res.Code != http.StatusBadRequest {
+			t.Errorf("expected the default user to stay admin, got %d", res.Code)
+		}
+		path := "/api/users/" + strconv.FormatInt(user.Id, 10)
+		if res := request(admin, "PUT", path, `{"is_admin": true}`); res.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.Code)
+		}
+		// only alice is left as an admin
+		isAdmin := false
+		db.UpdateUser(model.DefaultUserID, model.UpdateUserParams{IsAdmin: &isAdmin})
+		defer func() {
+			isAdmin = true
+			db.UpdateUser(model.DefaultUserID, model.UpdateUserParams{IsAdmin: &isAdmin})
+			isAdmin = false
+			db.UpdateUser(user.Id, model.UpdateUserParams{IsAdmin: &isAdmin})
+		}()
+		if res := request(alice, "PUT", path, `{"is_admin": false}`); res.Code != http.StatusBadRequest {
+			t.Errorf("expected the last admin to be kept, got %d", res.Code)
+		}
+		if res := request(alice, "DELETE", path, ""); res.Code != http.StatusBadRequest {
+			t.Errorf("expected the last admin not to be deleted, got %d", res.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if res := request(admin, "DELETE", "/api/users/1", ""); res.Code != http.StatusBadRequest {
+			t.Errorf("expected default user deletion to be refused, got %d", res.Code)
+		}
+		if res := request(admin, "DELETE", "/api/users/"+strconv.FormatInt(user.Id, 10), ""); res.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", res.Code)
+		}
+		if res := request(alice, "GET", "/api/feeds", ""); res.Code != http.StatusUnauthorized {
+			t.Errorf("expected deleted user to be signed out, got %d", res.Code)
+		}
+	})
+}
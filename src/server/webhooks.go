@@ -0,0 +1,204 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// number of deliveries returned by the delivery log
+const webhookDeliveriesLimit = 100
+
+// parseWebhookForm decodes and validates the webhook in the request body,
+// which gets the secret given unless the body has one.
+func parseWebhookForm(w http.ResponseWriter, r *http.Request, secret string) (*worker.Webhook, bool) {
+	var body WebhookForm
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	hook := model.Webhook{
+		Title:     body.Title,
+		URL:       body.URL,
+		FolderID:  body.FolderID,
+		FeedID:    body.FeedID,
+		Keyword:   body.Keyword,
+		Format:    body.Format,
+		Template:  body.Template,
+		Secret:    secret,
+		IsEnabled: body.IsEnabled == nil || *body.IsEnabled,
+	}
+	if body.Secret != nil {
+		hook.Secret = *body.Secret
+	}
+	if hook.Format == "" {
+		hook.Format = model.WebhookJSON
+	}
+	compiled, err := worker.CompileWebhook(hook)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return compiled, true
+}
+
+func (s *Server) handleWebhookList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := s.db.ListWebhooks()
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+		writeJSON(w, http.StatusOK, hooks)
+	case http.MethodPost:
+		compiled, ok := parseWebhookForm(w, r, "")
+		if !ok {
+			return
+		}
+		hook, err := s.db.CreateWebhook(compiled.Webhook)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		hook.Secret = ""
+		writeJSON(w, http.StatusCreated, hook)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		hook, err := s.db.GetWebhook(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if hook == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		hook.Secret = ""
+		writeJSON(w, http.StatusOK, hook)
+	case http.MethodPut:
+		current, err := s.db.GetWebhook(id)
+		if err != nil {
+			log.Print(err)
+		}
+		if current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		compiled, ok := parseWebhookForm(w, r, current.Secret)
+		if !ok {
+			return
+		}
+		updated, err := s.db.UpdateWebhook(id, compiled.Webhook)
+		if err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if !s.db.DeleteWebhook(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if hook, _ := s.db.GetWebhook(id); hook == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	deliveries, err := s.db.ListWebhookDeliveries(id, webhookDeliveriesLimit)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// handleWebhookTest makes a single delivery of the latest item the webhook
+// targets (or of a placeholder if there is none) and returns the outcome.
+func (s *Server) handleWebhookTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	hook, err := s.db.GetWebhook(id)
+	if err != nil {
+		log.Print(err)
+	}
+	if hook == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	compiled, err := worker.CompileWebhook(*hook)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	compiled.PublicOnly = !s.isAdmin()
+
+	feed := model.Feed{Title: "yarr"}
+	item := model.Item{GUID: "test", Title: "Test item", Date: time.Now().UTC()}
+	delivery := model.WebhookDelivery{WebhookID: id, Attempt: 1}
+	filter := model.ItemFilter{FeedID: hook.FeedID, FolderID: hook.FolderID}
+	if items := s.db.ListItems(filter, 1, true, true); len(items) > 0 {
+		if f := s.db.GetFeed(items[0].FeedId); f != nil {
+			feed, item = *f, items[0]
+			delivery.ItemID = &item.Id
+		}
+	}
+
+	delivery.StatusCode, err = compiled.Fire(feed, item)
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.CreatedAt = time.Now().UTC()
+	if _, err := s.db.CreateWebhookDelivery(delivery); err != nil {
+		log.Print(err)
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
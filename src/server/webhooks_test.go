@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestWebhookAPI(t *testing.T) {
+	var received string
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hookSrv.Close()
+
+	db, request := newTestServer(t)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+	db.CreateItems([]model.Item{{GUID: "1", FeedId: feed.Id, Title: "Latest post"}})
+
+	if res := request("POST", "/api/webhooks", `{"url": "http://example.com", "template": "{{.Item"}`); res.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid template to be rejected, got %d", res.Code)
+	}
+
+	res := request("POST", "/api/webhooks", fmt.Sprintf(`{"url": %q, "feed_id": %d, "template": "{{.Feed.Title}}: {{.Item.Title}}", "secret": "s3cret"}`, hookSrv.URL, feed.Id))
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var created model.Webhook
+	json.NewDecoder(res.Body).Decode(&created)
+	if created.Id == 0 || !created.IsEnabled || created.Format != model.WebhookJSON || created.Secret != "" {
+		t.Fatalf("unexpected webhook: %#v", created)
+	}
+	for _, path := range []string{"/api/webhooks", fmt.Sprintf("/api/webhooks/%d", created.Id)} {
+		if body := request("GET", path, "").Body.String(); strings.Contains(body, "s3cret") {
+			t.Errorf("expected %s not to reveal the secret, got %s", path, body)
+		}
+	}
+
+	res = request("POST", fmt.Sprintf("/api/webhooks/%d/test", created.Id), "")
+	var delivery model.WebhookDelivery
+	json.NewDecoder(res.Body).Decode(&delivery)
+	if res.Code != http.StatusOK || delivery.StatusCode != http.StatusNoContent || delivery.ItemID == nil {
+		t.Fatalf("unexpected test delivery: %d %#v", res.Code, delivery)
+	}
+	if received != "feed: Latest post" {
+		t.Errorf("unexpected body: %q", received)
+	}
+
+	var deliveries []model.WebhookDelivery
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/webhooks/%d/deliveries", created.Id), "").Body).Decode(&deliveries)
+	if len(deliveries) != 1 || deliveries[0].StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+
+	if res := request("PUT", fmt.Sprintf("/api/webhooks/%d", created.Id), `{"url": "https://example.com/new", "format": "form", "is_enabled": false}`); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	var fetched model.Webhook
+	json.NewDecoder(request("GET", fmt.Sprintf("/api/webhooks/%d", created.Id), "").Body).Decode(&fetched)
+	if fetched.URL != "https://example.com/new" || fetched.Format != model.WebhookForm || fetched.IsEnabled || fetched.FeedID != nil {
+		t.Errorf("unexpected webhook after update: %#v", fetched)
+	}
+	if hook, _ := db.GetWebhook(created.Id); hook.Secret != "s3cret" {
+		t.Errorf("expected the secret to be kept, got %q", hook.Secret)
+	}
+	request("PUT", fmt.Sprintf("/api/webhooks/%d", created.Id), `{"url": "https://example.com/new", "secret": ""}`)
+	if hook, _ := db.GetWebhook(created.Id); hook.Secret != "" {
+		t.Errorf("expected the secret to be removed, got %q", hook.Secret)
+	}
+
+	if res := request("DELETE", fmt.Sprintf("/api/webhooks/%d", created.Id), ""); res.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", res.Code)
+	}
+	if res := request("GET", fmt.Sprintf("/api/webhooks/%d/deliveries", created.Id), ""); res.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", res.Code)
+	}
+}
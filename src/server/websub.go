@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/worker"
+)
+
+// maximum size of a pushed feed document
+const websubMaxBody = 10 << 20
+
+var websubHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// validWebSubSignature checks the `X-Hub-Signature: method=signature` header.
+func validWebSubSignature(secret, header string, body []byte) bool {
+	method, signature, ok := strings.Cut(header, "=")
+	newHash, known := websubHashes[method]
+	if !ok || !known {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// handleWebSub is the callback for hubs: it answers verification
+// requests (GET) and ingests content distribution requests (POST).
+func (s *Server) handleWebSub(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	// hubs aren't users, the subscription itself is the credential
+	db := storage.ForUser(s.db, 0)
+	sub, err := db.GetWebSubSubscription(id)
+	if err != nil {
+		log.Print(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sub == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		mode := query.Get("hub.mode")
+		// only the requests yarr is waiting for are confirmed
+		now := time.Now()
+		if query.Get("hub.topic") != sub.Topic || !worker.WebSubPending(sub, mode, now) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if mode == "denied" {
+			db.DeleteWebSubSubscription(id)
+			log.Printf("WebSub subscription to %s denied by %s: %s", sub.Topic, sub.Hub, query.Get("hub.reason"))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		seconds, err := strconv.ParseInt(query.Get("hub.lease_seconds"), 10, 64)
+		if err != nil || seconds <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lease := worker.WebSubLease
+		if seconds < int64(lease/time.Second) {
+			lease = time.Duration(seconds) * time.Second
+		}
+		expires := now.Add(lease)
+		sub.ExpiresAt = &expires
+		sub.RequestedAt = time.Time{}
+		if _, err := db.UpdateWebSubSubscription(*sub); err != nil {
+			log.Print(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, query.Get("hub.challenge"))
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, websubMaxBody))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		feed := db.GetFeed(id)
+		if feed == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// hubs without TLS get no secret: their notifications can't be
+		// trusted, so the feed is fetched from its origin instead
+		if sub.Secret == "" {
+			s.worker.RefreshFeed(*feed)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		// per spec, invalid notifications are acknowledged but ignored
+		if !validWebSubSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+			log.Printf("Ignoring WebSub notification for %s: invalid signature", sub.Topic)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if err := s.worker.IngestPush(*feed, bytes.NewReader(body), r.Header.Get("Content-Type")); err != nil {
+			log.Printf("Failed to ingest WebSub notification for %s: %s", sub.Topic, err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestWebSub(t *testing.T) {
+	// stand-in hub: verifies the intent of the subscriber before accepting,
+	// and keeps the callback & secret to push content later on
+	var mu sync.Mutex
+	var subscription url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		query := url.Values{
+			"hub.mode":          {r.Form.Get("hub.mode")},
+			"hub.topic":         {r.Form.Get("hub.topic")},
+			"hub.challenge":     {"challenge-123"},
+			"hub.lease_seconds": {"3600"},
+		}
+		res, err := http.Get(r.Form.Get("hub.callback") + "?" + query.Encode())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer res.Body.Close()
+		challenge, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK || string(challenge) != "challenge-123" {
+			t.Errorf("verification failed: %d %q", res.StatusCode, challenge)
+		}
+		mu.Lock()
+		subscription = r.Form
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	var feedSrv *httptest.Server
+	atom := func(entries ...string) string {
+		return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<title>Pushed</title>
+				<link rel="hub" href="%s"/>
+				<link rel="self" href="%s/feed"/>
+				%s
+			</feed>`, hub.URL, feedSrv.URL, strings.Join(entries, ""))
+	}
+	entry := func(id string) string {
+		return fmt.Sprintf(`<entry><id>%s</id><title>%s</title><link href="https://example.com/%s"/></entry>`, id, id, id)
+	}
+	published := []string{entry("first")}
+	feedSrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(atom(published...)))
+	}))
+	defer feedSrv.Close()
+
+	db := newTestDB(t)
+	server := NewServer(db, "127.0.0.1:8000")
+	yarr := httptest.NewServer(server.handler())
+	defer yarr.Close()
+	server.worker.SetWebSubCallback(yarr.URL + "/websub")
+
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Pushed", FeedLink: feedSrv.URL + "/feed"})
+	server.worker.RefreshFeed(*feed)
+	for deadline := time.Now().Add(5 * time.Second); server.worker.FeedsPending() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("refresh timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sub, _ := db.GetWebSubSubscription(feed.Id)
+	if sub == nil || sub.ExpiresAt == nil || sub.Hub != hub.URL || sub.Topic != feedSrv.URL+"/feed" {
+		t.Fatalf("expected verified subscription, got %#v", sub)
+	}
+	if state, _ := db.GetFeedState(feed.Id); state.NextRefresh == nil || time.Until(*state.NextRefresh) < 50*time.Minute {
+		t.Errorf("expected polling to be postponed until lease renewal, got %v", state.NextRefresh)
+	}
+
+	mu.Lock()
+	callback := subscription.Get("hub.callback")
+	if _, ok := subscription["hub.secret"]; ok || sub.Secret != "" {
+		t.Error("expected no secret to be shared with a hub without TLS")
+	}
+	mu.Unlock()
+	push := func(body, signature string) int {
+		req, _ := http.NewRequest("POST", callback, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/atom+xml")
+		req.Header.Set("X-Hub-Signature", signature)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	titles := func() []string {
+		titles := make([]string, 0)
+		for _, item := range db.ListItems(model.ItemFilter{}, 10, false, false) {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+
+	// without a secret, pushes only trigger a refresh from the feed's url
+	mu.Lock()
+	published = append(published, entry("second"))
+	mu.Unlock()
+	if code := push(atom(entry("unsigned")), ""); code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+	for deadline := time.Now().Add(5 * time.Second); strings.Join(titles(), ",") != "first,second"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected refresh on push, got %v", titles())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// as if the hub was served over https
+	sub.Secret = "s3cret"
+	db.UpdateWebSubSubscription(*sub)
+	forged := atom(entry("forged"))
+	if code := push(forged, "sha256=00"); code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+	pushed := atom(entry("third"))
+	if code := push(pushed, sign(pushed)); code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+	if have := strings.Join(titles(), ","); have != "first,second,third" {
+		t.Errorf("unexpected items: %s", have)
+	}
+
+	// verification of requests yarr didn't make is refused
+	for _, query := range []url.Values{
+		{"hub.mode": {"subscribe"}, "hub.topic": {"other"}, "hub.challenge": {"x"}, "hub.lease_seconds": {"60"}},
+		{"hub.mode": {"subscribe"}, "hub.topic": {sub.Topic}, "hub.challenge": {"x"}, "hub.lease_seconds": {"999999999"}},
+		{"hub.mode": {"unsubscribe"}, "hub.topic": {sub.Topic}, "hub.challenge": {"x"}},
+		{"hub.mode": {"denied"}, "hub.topic": {sub.Topic}},
+	} {
+		res, _ := http.Get(callback + "?" + query.Encode())
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404 for %s, got %d", query.Encode(), res.StatusCode)
+		}
+	}
+	if have, _ := db.GetWebSubSubscription(feed.Id); have == nil || !have.ExpiresAt.Equal(*sub.ExpiresAt) {
+		t.Errorf("expected subscription to be kept as is, got %#v", have)
+	}
+}
+
+func TestWebSubSignature(t *testing.T) {
+	body := []byte("payload")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !validWebSubSignature("secret", valid, body) {
+		t.Error("expected valid signature")
+	}
+	for _, header := range []string{"", valid[7:], "md5=" + valid[7:], "sha256=zz", "sha1=" + valid[7:]} {
+		if validWebSubSignature("secret", header, body) {
+			t.Errorf("expected %q to be invalid", header)
+		}
+	}
+	if validWebSubSignature("other", valid, body) {
+		t.Error("expected signature with another secret to be invalid")
+	}
+}
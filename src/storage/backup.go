@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/sqlite"
+)
+
+// format of the logical dumps written by Dump: a header line followed,
+// for every table, by a line with its columns and a line per row.
+//
+//	{"format":"yarr-backup","version":1}
+//	{"table":"folders","columns":["id","user_id","title","is_expanded"],"rows":1}
+//	[1,1,"News",true]
+const (
+	dumpFormat  = "yarr-backup"
+	dumpVersion = 1
+)
+
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+type dumpHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+type dumpTable struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// missing from the dumps of older versions
+	Rows *int64 `json:"rows,omitempty"`
+}
+
+// BackupExt returns the extension of the files written by Backup.
+func BackupExt(s Storage) string {
+	if _, ok := s.(*sqlite.SQLiteStorage); ok {
+		return ".db"
+	}
+	return ".json"
+}
+
+// Backup writes a consistent copy of the database to a new file while
+// the database is in use: a SQLite database file for SQLite (which can
+// be used as is), a logical dump for the other backends.
+func Backup(s Storage, path string) error {
+	db, err := rawDatabase(s)
+	if err != nil {
+		return err
+	}
+	// existing files are refused, and backups are only readable by the
+	// owner; SQLite vacuums into the empty file, keeping its mode
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if !db.postgres {
+		f.Close()
+		if _, err := db.db.Exec("vacuum into ?", path); err != nil {
+			os.Remove(path)
+			return err
+		}
+		return nil
+	}
+
+	w := bufio.NewWriter(f)
+	if err = db.dump(w); err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// Dump writes a logical dump of the database, which can be restored
+// into either backend.
+func Dump(s Storage, w io.Writer) error {
+	db, err := rawDatabase(s)
+	if err != nil {
+		return err
+	}
+	return db.dump(w)
+}
+
+func (d *database) dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(dumpHeader{Format: dumpFormat, Version: dumpVersion}); err != nil {
+		return err
+	}
+	reader, done, err := d.snapshot()
+	if err != nil {
+		return err
+	}
+	defer done()
+	for _, t := range tables {
+		names := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			names[i] = c.name
+		}
+		count, err := reader.count(t)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(dumpTable{Table: t.name, Columns: names, Rows: &count}); err != nil {
+			return err
+		}
+		// times are encoded in RFC 3339, blobs in base64
+		err = reader.readTable(t, func(row []any) error {
+			return enc.Encode(row)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// Restore loads a file written by Backup (or Dump) into the database,
+// keeping the ids. Unless replace is set, the database must not have
+// any folders, feeds or items yet.
+func Restore(dst Storage, path string, replace bool) ([]TableCount, error) {
+	to, err := rawDatabase(dst)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	head, err := r.Peek(len(sqliteHeader))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(head, sqliteHeader) {
+		return to.load(&dumpReader{dec: json.NewDecoder(r)}, replace)
+	}
+
+	// the backup may come from an older version, so it's upgraded
+	// on a copy before reading it
+	tmp, err := os.MkdirTemp("", "yarr-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	tmpPath := filepath.Join(tmp, "storage.db")
+	if err := copyFile(tmpPath, r); err != nil {
+		return nil, err
+	}
+	src, err := sqlite.New(tmpPath + "?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return to.load(&database{db: src.DB()}, replace)
+}
+
+func copyFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// dumpReader reads the tables of a logical dump as they come.
+type dumpReader struct {
+	dec *json.Decoder
+	// the table line following the rows of the previous table
+	next json.RawMessage
+}
+
+func (r *dumpReader) eachTable(fn func(t table, count int64, rows func(fn func(row []any) error) error) error) error {
+	r.dec.UseNumber()
+	var header dumpHeader
+	if err := r.dec.Decode(&header); err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+	if header.Format != dumpFormat {
+		return errors.New("invalid backup: unknown format")
+	}
+	if header.Version > dumpVersion {
+		return fmt.Errorf("unsupported backup version %d", header.Version)
+	}
+
+	if err := r.dec.Decode(&r.next); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	for r.next != nil {
+		var info dumpTable
+		if err := json.Unmarshal(r.next, &info); err != nil {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+		r.next = nil
+		t, err := dumpedTable(info)
+		if err != nil {
+			return err
+		}
+		count := int64(-1)
+		if info.Rows != nil {
+			count = *info.Rows
+		}
+		if err := fn(t, count, func(fn func(row []any) error) error { return r.rows(t, fn) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rows calls fn with every row until the next table or the end of the dump.
+func (r *dumpReader) rows(t table, fn func(row []any) error) error {
+	for {
+		var raw json.RawMessage
+		if err := r.dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '{' {
+			r.next = raw
+			return nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var row []any
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+		if len(row) != len(t.columns) {
+			return fmt.Errorf("expected %d values, got %d", len(t.columns), len(row))
+		}
+		for i, c := range t.columns {
+			v, err := decodeValue(c.kind, row[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", c.name, err)
+			}
+			row[i] = v
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// dumpedTable returns the known table with the columns of the dump,
+// in the same order. Columns missing from the dump get their defaults.
+func dumpedTable(info dumpTable) (table, error) {
+	for _, t := range tables {
+		if t.name != info.Table {
+			continue
+		}
+		result := table{name: t.name, serial: t.serial}
+		for _, name := range info.Columns {
+			i := t.columnIndex(name)
+			if i == -1 {
+				return table{}, fmt.Errorf("unknown column %s.%s", t.name, name)
+			}
+			result.columns = append(result.columns, t.columns[i])
+		}
+		return result, nil
+	}
+	return table{}, fmt.Errorf("unknown table %s", info.Table)
+}
+
+func decodeValue(kind columnKind, v any) (any, error) {
+	switch x := v.(type) {
+	case json.Number:
+		n, err := x.Int64()
+		if err != nil {
+			return nil, err
+		}
+		v = n
+	case string:
+		if kind == kindBlob {
+			return base64.StdEncoding.DecodeString(x)
+		}
+	}
+	return normalize(kind, v)
+}
+
+// BackupName returns the name of a backup file made at the given time.
+func BackupName(s Storage, t time.Time) string {
+	return "yarr-backup-" + t.UTC().Format("20060102-150405") + BackupExt(s)
+}
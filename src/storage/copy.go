@@ -0,0 +1,505 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/content/htmlutil"
+	"github.com/nkanaev/yarr/src/storage/postgres"
+	"github.com/nkanaev/yarr/src/storage/sqlite"
+)
+
+// kinds of column values, normalized when moving rows between backends
+type columnKind int
+
+const (
+	kindInt columnKind = iota
+	kindText
+	kindBool
+	kindTime
+	kindJSON
+	kindBlob
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+type table struct {
+	name    string
+	columns []column
+	// whether ids come from a sequence (bigserial in postgres)
+	serial bool
+}
+
+// tables lists the columns shared by both backends, parents first.
+var tables = []table{
+	{name: "users", serial: true, columns: []column{
+		{"id", kindInt}, {"username", kindText}, {"password_hash", kindText},
+		{"fever_key", kindText}, {"is_admin", kindBool}, {"created_at", kindTime},
+	}},
+	{name: "folders", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"title", kindText}, {"is_expanded", kindBool},
+	}},
+	{name: "feeds", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"folder_id", kindInt},
+		{"title", kindText}, {"description", kindText}, {"link", kindText}, {"feed_link", kindText},
+		{"icon", kindBlob}, {"refresh_interval", kindInt},
+		{"fetch_content", kindBool}, {"download_media", kindBool},
+	}},
+	{name: "feed_states", columns: []column{
+		{"feed_id", kindInt}, {"last_refreshed", kindTime}, {"last_error", kindText},
+		{"http_lmod", kindText}, {"http_etag", kindText},
+		{"next_refresh", kindTime}, {"refresh_interval", kindInt}, {"skip_hours", kindText},
+		{"failure_count", kindInt}, {"first_failure", kindTime}, {"disabled", kindBool},
+	}},
+	{name: "feed_scrapers", columns: []column{
+		{"feed_id", kindInt}, {"item_selector", kindText}, {"title_selector", kindText},
+		{"link_selector", kindText}, {"date_selector", kindText}, {"content_selector", kindText},
+	}},
+	{name: "feed_link_history", serial: true, columns: []column{
+		{"id", kindInt}, {"feed_id", kindInt}, {"old_link", kindText}, {"new_link", kindText},
+		{"status_code", kindInt}, {"created_at", kindTime},
+	}},
+	{name: "websub_subscriptions", columns: []column{
+		{"feed_id", kindInt}, {"hub", kindText}, {"topic", kindText}, {"secret", kindText},
+		{"requested_at", kindTime}, {"expires_at", kindTime},
+	}},
+	{name: "items", serial: true, columns: []column{
+		{"id", kindInt}, {"guid", kindText}, {"feed_id", kindInt},
+		{"title", kindText}, {"link", kindText}, {"content", kindText}, {"full_content", kindText},
+		{"date", kindTime}, {"date_arrived", kindTime}, {"last_arrived", kindTime},
+		{"status", kindInt}, {"media_links", kindJSON},
+		{"author", kindText}, {"categories", kindJSON}, {"comments_url", kindText}, {"podcast", kindJSON},
+	}},
+	{name: "labels", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"title", kindText},
+	}},
+	{name: "item_labels", columns: []column{
+		{"item_id", kindInt}, {"label_id", kindInt},
+	}},
+	{name: "rules", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"title", kindText}, {"feed_id", kindInt},
+		{"conditions", kindJSON}, {"action", kindText}, {"is_enabled", kindBool},
+	}},
+	{name: "saved_searches", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"name", kindText}, {"query", kindText},
+		{"status", kindInt},
+	}},
+	{name: "webhooks", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"title", kindText}, {"url", kindText},
+		{"folder_id", kindInt}, {"feed_id", kindInt}, {"keyword", kindText}, {"format", kindText},
+		{"template", kindText}, {"secret", kindText}, {"is_enabled", kindBool},
+	}},
+	{name: "webhook_deliveries", serial: true, columns: []column{
+		{"id", kindInt}, {"webhook_id", kindInt}, {"item_id", kindInt}, {"attempt", kindInt},
+		{"status_code", kindInt}, {"error", kindText}, {"created_at", kindTime},
+	}},
+	{name: "output_feeds", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"source", kindText}, {"folder_id", kindInt},
+		{"label_id", kindInt}, {"token", kindText}, {"created_at", kindTime},
+	}},
+	{name: "api_keys", serial: true, columns: []column{
+		{"id", kindInt}, {"user_id", kindInt}, {"name", kindText}, {"token_hash", kindText},
+		{"read_only", kindBool}, {"created_at", kindTime}, {"expires_at", kindTime}, {"last_used_at", kindTime},
+	}},
+	{name: "settings", columns: []column{
+		{"user_id", kindInt}, {"key", kindText}, {"val", kindJSON},
+	}},
+	{name: "media_files", serial: true, columns: []column{
+		{"id", kindInt}, {"item_id", kindInt}, {"url", kindText}, {"path", kindText},
+		{"mime_type", kindText}, {"size", kindInt}, {"created_at", kindTime},
+	}},
+}
+
+// TableCount is the number of rows of a table.
+type TableCount struct {
+	Table string
+	Rows  int64
+}
+
+// database is the raw connection of a backend.
+type database struct {
+	db       *sql.DB
+	postgres bool
+	// if set, the tables are read within the transaction
+	tx *sql.Tx
+}
+
+func rawDatabase(s Storage) (*database, error) {
+	switch db := s.(type) {
+	case *sqlite.SQLiteStorage:
+		return &database{db: db.DB()}, nil
+	case *postgres.PostgresStorage:
+		return &database{db: db.DB(), postgres: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported storage %T", s)
+}
+
+func (d *database) placeholder(n int) string {
+	if d.postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+func (d *database) counts() ([]TableCount, error) {
+	result := make([]TableCount, 0, len(tables))
+	for _, t := range tables {
+		n, err := d.count(t)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, TableCount{Table: t.name, Rows: n})
+	}
+	return result, nil
+}
+
+func (d *database) count(t table) (int64, error) {
+	query := "select count(*) from " + t.name
+	var row *sql.Row
+	if d.tx != nil {
+		row = d.tx.QueryRow(query)
+	} else {
+		row = d.db.QueryRow(query)
+	}
+	var n int64
+	if err := row.Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", t.name, err)
+	}
+	return n, nil
+}
+
+// snapshot returns a reader of the database within a read-only transaction,
+// so that the tables (and their counts) are consistent with each other.
+// The transaction ends with done.
+func (d *database) snapshot() (reader *database, done func(), err error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.postgres {
+		if _, err := tx.Exec("set transaction isolation level repeatable read, read only"); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+	return &database{db: d.db, postgres: d.postgres, tx: tx}, func() { tx.Rollback() }, nil
+}
+
+// readTable calls fn with every row of the table, normalized.
+func (d *database) readTable(t table, fn func(row []any) error) error {
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.name
+	}
+	query := fmt.Sprintf(
+		"select %s from %s order by %s",
+		strings.Join(names, ", "), t.name, strings.Join(names[:min(2, len(names))], ", "),
+	)
+	var rows *sql.Rows
+	var err error
+	if d.tx != nil {
+		rows, err = d.tx.Query(query)
+	} else {
+		rows, err = d.db.Query(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make([]any, len(t.columns))
+		ptrs := make([]any, len(row))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, c := range t.columns {
+			if row[i], err = normalize(c.kind, row[i]); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.name, c.name, err)
+			}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// insertQuery returns the statement inserting a row of the table, along
+// with a function computing the extra values the backend needs.
+func (d *database) insertQuery(t table) (string, func(row []any) []any) {
+	names := make([]string, 0, len(t.columns)+1)
+	values := make([]string, 0, len(t.columns)+1)
+	for i, c := range t.columns {
+		value := d.placeholder(i + 1)
+		if t.name == "items" && c.name == "date" && !d.postgres {
+			// same format as in CreateItems, dates are compared as strings
+			value = "strftime('%Y-%m-%d %H:%M:%f', " + value + ")"
+		}
+		names = append(names, c.name)
+		values = append(values, value)
+	}
+	extra := func(row []any) []any { return row }
+	if t.name == "items" && d.postgres {
+		// the full-text search column is computed on insert
+		names = append(names, "search")
+		values = append(values, "to_tsvector('simple', "+d.placeholder(len(t.columns)+1)+")")
+		title, content := t.columnIndex("title"), t.columnIndex("content")
+		extra = func(row []any) []any {
+			var text string
+			if title != -1 {
+				text, _ = row[title].(string)
+			}
+			if content != -1 {
+				if c, ok := row[content].(string); ok {
+					text += " " + htmlutil.ExtractText(c)
+				}
+			}
+			return append(row, text)
+		}
+	}
+	query := fmt.Sprintf(
+		"insert into %s (%s) values (%s)",
+		t.name, strings.Join(names, ", "), strings.Join(values, ", "),
+	)
+	return query, extra
+}
+
+func (t table) columnIndex(name string) int {
+	for i, c := range t.columns {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// tableSource provides the rows of the tables to load, parents first,
+// along with the number of rows the source has for each of them (-1 if
+// unknown). The tables (and columns) it doesn't know about are left out.
+type tableSource interface {
+	eachTable(fn func(t table, count int64, rows func(fn func(row []any) error) error) error) error
+}
+
+func (d *database) eachTable(fn func(t table, count int64, rows func(fn func(row []any) error) error) error) error {
+	reader, done, err := d.snapshot()
+	if err != nil {
+		return err
+	}
+	defer done()
+	for _, t := range tables {
+		count, err := reader.count(t)
+		if err != nil {
+			return err
+		}
+		err = fn(t, count, func(fn func(row []any) error) error {
+			return reader.readTable(t, fn)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load inserts all the rows of the source into the database, then checks
+// that every table has as many rows as the source has. Without replace,
+// the database must not have any folders, feeds or items yet.
+func (d *database) load(src tableSource, replace bool) ([]TableCount, error) {
+	if !replace {
+		var existing int64
+		err := d.db.QueryRow(`
+			select (select count(*) from folders) + (select count(*) from feeds) + (select count(*) from items)
+		`).Scan(&existing)
+		if err != nil {
+			return nil, err
+		}
+		if existing > 0 {
+			return nil, errors.New("destination database isn't empty")
+		}
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the default user and settings are replaced as well
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, err := tx.Exec("delete from " + tables[i].name); err != nil {
+			return nil, fmt.Errorf("%s: %w", tables[i].name, err)
+		}
+	}
+	// rows of the source by table; tables missing from it are left empty
+	expected := make(map[string]int64)
+	err = src.eachTable(func(t table, count int64, rows func(fn func(row []any) error) error) error {
+		query, extra := d.insertQuery(t)
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+		defer stmt.Close()
+		var read int64
+		err = rows(func(row []any) error {
+			read++
+			_, err := stmt.Exec(extra(row)...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+		if count == -1 {
+			count = read
+		}
+		expected[t.name] = count
+		if t.serial && d.postgres && t.columnIndex("id") != -1 {
+			_, err := tx.Exec(fmt.Sprintf(
+				"select setval(pg_get_serial_sequence('%[1]s', 'id'), coalesce(max(id), 1), max(id) is not null) from %[1]s",
+				t.name,
+			))
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	counts, err := d.counts()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		if c.Rows != expected[c.Table] {
+			return counts, fmt.Errorf("%s: %d rows loaded out of %d", c.Table, c.Rows, expected[c.Table])
+		}
+	}
+	return counts, nil
+}
+
+// Copy moves all the data of src into dst, keeping the ids, and checks
+// that both have the same number of rows afterwards. The destination must
+// not have any feeds, folders or items yet; its default user and settings
+// are replaced by the ones of the source.
+func Copy(dst, src Storage) ([]TableCount, error) {
+	from, err := rawDatabase(src)
+	if err != nil {
+		return nil, err
+	}
+	to, err := rawDatabase(dst)
+	if err != nil {
+		return nil, err
+	}
+	return to.load(from, false)
+}
+
+// sqlite returns dates in whatever format they were stored
+var timeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// normalize converts a value read from either backend to the Go type
+// both of them accept for the kind of column.
+func normalize(kind columnKind, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch kind {
+	case kindInt:
+		switch x := v.(type) {
+		case int64:
+			return x, nil
+		case float64:
+			return int64(x), nil
+		case bool:
+			if x {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case []byte:
+			return strconv.ParseInt(string(x), 10, 64)
+		case string:
+			return strconv.ParseInt(x, 10, 64)
+		}
+	case kindBool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case int64:
+			return x != 0, nil
+		case []byte:
+			return strconv.ParseBool(string(x))
+		case string:
+			return strconv.ParseBool(x)
+		}
+	case kindText:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case []byte:
+			return string(x), nil
+		case int64:
+			return strconv.FormatInt(x, 10), nil
+		}
+	case kindJSON:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case []byte:
+			return string(x), nil
+		}
+	case kindBlob:
+		switch x := v.(type) {
+		case []byte:
+			return x, nil
+		case string:
+			return []byte(x), nil
+		}
+	case kindTime:
+		switch x := v.(type) {
+		case time.Time:
+			return x.UTC(), nil
+		case int64:
+			return time.Unix(x, 0).UTC(), nil
+		case []byte:
+			return parseTime(string(x))
+		case string:
+			return parseTime(x)
+		}
+	}
+	return nil, fmt.Errorf("unexpected value %#v", v)
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range timeFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected time %q", s)
+}
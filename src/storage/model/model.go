@@ -17,6 +17,15 @@ type Feed struct {
 	Link        string `json:"link"`
 	FeedLink    string `json:"feed_link"`
 	Icon        *Icon  `json:"icon,omitempty"`
+
+	// Refresh interval override in minutes; nil means automatic.
+	RefreshInterval *int64 `json:"refresh_interval"`
+
+	// Whether the full article of new items is downloaded at refresh.
+	FetchContent bool `json:"fetch_content"`
+
+	// Whether audio and video enclosures of new items are cached locally.
+	DownloadMedia bool `json:"download_media"`
 }
 
 // Icon holds a feed favicon's raw bytes and serializes to a self-describing
@@ -67,15 +76,21 @@ type CreateFeedParams struct {
 }
 
 type Item struct {
-	Id         int64      `json:"id"`
-	GUID       string     `json:"guid"`
-	FeedId     int64      `json:"feed_id"`
-	Title      string     `json:"title"`
-	Link       string     `json:"link"`
-	Content    string     `json:"content,omitempty"`
-	Date       time.Time  `json:"date"`
-	Status     ItemStatus `json:"status"`
-	MediaLinks MediaLinks `json:"media_links"`
+	Id      int64     `json:"id"`
+	GUID    string    `json:"guid"`
+	FeedId  int64     `json:"feed_id"`
+	Title   string    `json:"title"`
+	Link    string    `json:"link"`
+	Content string    `json:"content,omitempty"`
+	Date    time.Time `json:"date"`
+	// article extracted from the link, for feeds with FetchContent
+	FullContent string     `json:"full_content,omitempty"`
+	Status      ItemStatus `json:"status"`
+	MediaLinks  MediaLinks `json:"media_links"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	CommentsURL string     `json:"comments_url,omitempty"`
+	Podcast     *Podcast   `json:"podcast,omitempty"`
 }
 
 type ItemStatus int
@@ -115,10 +130,39 @@ type MediaLink struct {
 	URL         string `json:"url"`
 	Type        string `json:"type"`
 	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Duration    int    `json:"duration,omitempty"`
 }
 
 type MediaLinks []MediaLink
 
+// Podcast is the episode metadata from the iTunes and Podcasting 2.0 namespaces.
+type Podcast struct {
+	Image   string `json:"image,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+
+	Chapters    *PodcastLink    `json:"chapters,omitempty"`
+	Transcripts []PodcastLink   `json:"transcripts,omitempty"`
+	Persons     []PodcastPerson `json:"persons,omitempty"`
+}
+
+type PodcastLink struct {
+	URL      string `json:"url"`
+	Type     string `json:"type,omitempty"`
+	Language string `json:"language,omitempty"`
+	Rel      string `json:"rel,omitempty"`
+}
+
+type PodcastPerson struct {
+	Name  string `json:"name"`
+	Role  string `json:"role,omitempty"`
+	Group string `json:"group,omitempty"`
+	Image string `json:"image,omitempty"`
+	Href  string `json:"href,omitempty"`
+}
+
 type ItemFilter struct {
 	FolderID *int64
 	FeedID   *int64
@@ -129,19 +173,31 @@ type ItemFilter struct {
 	SinceID  *int64
 	MaxID    *int64
 	Before   *time.Time
+	Since    *time.Time
+	Label    *int64
+	// case-insensitive exact matches
+	Author   *string
+	Category *string
+	// sort the items matching Search by relevance rather than by date
+	SortByRelevance bool
+	// number of matching items skipped by lists
+	Offset int
 }
 
 type UpdateItemParams struct {
 	Title       *string
 	Status      *ItemStatus
 	LastArrived *time.Time
+	FullContent *string
 }
 
 type MarkFilter struct {
 	FolderID *int64
 	FeedID   *int64
+	Label    *int64
 
 	Before *time.Time
+	MaxID  *int64
 }
 
 type Folder struct {
@@ -155,6 +211,115 @@ type UpdateFolderParams struct {
 	IsExpanded *bool
 }
 
+// Label is a user-defined tag attached to any number of items,
+// independent of the folder of their feed.
+type Label struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type UpdateLabelParams struct {
+	Title *string
+}
+
+// Rule performs an action on the incoming items matching all its conditions.
+// Rules without a feed apply to all the feeds of the user.
+type Rule struct {
+	Id         int64          `json:"id"`
+	Title      string         `json:"title"`
+	FeedID     *int64         `json:"feed_id"`
+	Conditions RuleConditions `json:"conditions"`
+	Action     RuleAction     `json:"action"`
+	IsEnabled  bool           `json:"is_enabled"`
+}
+
+type RuleCondition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+type RuleConditions []RuleCondition
+
+type RuleAction string
+
+const (
+	RuleMarkRead RuleAction = "read"
+	RuleStar     RuleAction = "star"
+	RuleDrop     RuleAction = "drop"
+)
+
+// SavedSearch is a search query kept as a virtual feed,
+// optionally restricted to the items with the given status.
+type SavedSearch struct {
+	Id     int64       `json:"id"`
+	Name   string      `json:"name"`
+	Query  string      `json:"query"`
+	Status *ItemStatus `json:"status"`
+}
+
+type SavedSearchStat struct {
+	SearchID     int64 `json:"search_id"`
+	UnreadCount  int64 `json:"unread"`
+	StarredCount int64 `json:"starred"`
+}
+
+// Webhook posts the new items matching its filters to an url.
+// Webhooks without a folder, feed or keyword get the items of all the feeds.
+type Webhook struct {
+	Id       int64         `json:"id"`
+	UserId   int64         `json:"user_id"`
+	Title    string        `json:"title"`
+	URL      string        `json:"url"`
+	FolderID *int64        `json:"folder_id"`
+	FeedID   *int64        `json:"feed_id"`
+	Keyword  string        `json:"keyword"`
+	Format   WebhookFormat `json:"format"`
+	// text/template for the request body, a default payload is sent if empty
+	Template string `json:"template"`
+	// key for the HMAC-SHA256 signature of the body, unsigned if empty;
+	// write-only, left out of the API responses
+	Secret    string `json:"secret,omitempty"`
+	IsEnabled bool   `json:"is_enabled"`
+}
+
+type WebhookFormat string
+
+const (
+	WebhookJSON WebhookFormat = "json"
+	WebhookForm WebhookFormat = "form"
+)
+
+// WebhookDelivery records an attempt to deliver an item to a webhook.
+type WebhookDelivery struct {
+	Id         int64     `json:"id"`
+	WebhookID  int64     `json:"webhook_id"`
+	ItemID     *int64    `json:"item_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OutputFeed re-publishes the starred items, or the items of a folder or
+// label, as a feed anyone with the token can read.
+type OutputFeed struct {
+	Id        int64        `json:"id"`
+	UserId    int64        `json:"user_id"`
+	Source    OutputSource `json:"source"`
+	SourceID  *int64       `json:"source_id"`
+	Token     string       `json:"token"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type OutputSource string
+
+const (
+	OutputStarred OutputSource = "starred"
+	OutputFolder  OutputSource = "folder"
+	OutputLabel   OutputSource = "label"
+)
+
 type FeedStat struct {
 	FeedId       int64 `json:"feed_id"`
 	UnreadCount  int64 `json:"unread"`
@@ -223,6 +388,61 @@ type FeedState struct {
 	LastError        string
 	HTTPLastModified string
 	HTTPEtag         string
+
+	// Scheduling: when the feed is due next (nil means immediately)
+	// and the interval it was computed with.
+	NextRefresh     *time.Time
+	RefreshInterval time.Duration
+	SkipHours       []int
+
+	// Consecutive failed refreshes and when they started.
+	// Disabled feeds are no longer polled until re-enabled.
+	FailureCount int
+	FirstFailure *time.Time
+	Disabled     bool
+}
+
+// FeedLinkChange records a feed moving to a new url (permanent redirect).
+type FeedLinkChange struct {
+	OldLink    string    `json:"old_link"`
+	NewLink    string    `json:"new_link"`
+	StatusCode int       `json:"status_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebSubSubscription is a push subscription of a feed at a WebSub hub.
+type WebSubSubscription struct {
+	FeedID int64
+	Hub    string
+	Topic  string
+	Secret string
+	// time of the subscription request pending verification, zero once verified
+	RequestedAt time.Time
+	// set once the hub has verified the subscription
+	ExpiresAt *time.Time
+}
+
+// FeedScraper makes a feed out of a web page without one: CSS selectors for
+// the item containers and, within each, the title, link, date and content.
+type FeedScraper struct {
+	FeedID  int64  `json:"feed_id"`
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Date    string `json:"date"`
+	Content string `json:"content"`
+}
+
+// MediaFile is a local copy of an item's enclosure, stored under
+// the media directory at Path.
+type MediaFile struct {
+	Id        int64     `json:"id"`
+	ItemID    int64     `json:"item_id"`
+	URL       string    `json:"url"`
+	Path      string    `json:"-"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type UpdateFeedStateParams struct {
@@ -230,13 +450,66 @@ type UpdateFeedStateParams struct {
 	LastError        *string
 	HTTPLastModified *string
 	HTTPEtag         *string
+	NextRefresh      *time.Time
+	RefreshInterval  *time.Duration
+	SkipHours        *[]int
+	FailureCount     *int
+	FirstFailure     Nullable[time.Time]
+	Disabled         *bool
 }
 
 type UpdateFeedParams struct {
-	Title    *string
-	FeedLink *string
-	FolderID Nullable[int64]
-	Icon     Nullable[Icon]
+	Title           *string
+	FeedLink        *string
+	FolderID        Nullable[int64]
+	Icon            Nullable[Icon]
+	RefreshInterval Nullable[int64]
+	FetchContent    *bool
+	DownloadMedia   *bool
+}
+
+type APIKey struct {
+	Id         int64      `json:"id"`
+	UserId     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	ReadOnly   bool       `json:"read_only"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type CreateAPIKeyParams struct {
+	Name      string
+	TokenHash string
+	ReadOnly  bool
+	ExpiresAt *time.Time
+}
+
+// DefaultUserID owns the data created before multi-user support was added,
+// and is the one used when authentication is disabled.
+const DefaultUserID int64 = 1
+
+type User struct {
+	Id           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	FeverKey     string    `json:"-"`
+	IsAdmin      bool      `json:"is_admin"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreateUserParams struct {
+	Username     string
+	PasswordHash string
+	FeverKey     string
+	IsAdmin      bool
+}
+
+type UpdateUserParams struct {
+	Username     *string
+	PasswordHash *string
+	FeverKey     *string
+	IsAdmin      *bool
 }
 
 type Nullable[T any] struct {
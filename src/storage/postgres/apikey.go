@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *PostgresStorage) CreateAPIKey(params model.CreateAPIKeyParams) (*model.APIKey, error) {
+	now := time.Now().UTC()
+	var id int64
+	err := s.db.QueryRow(`
+		insert into api_keys (name, token_hash, read_only, created_at, expires_at, user_id)
+		values ($1, $2, $3, $4, $5, $6)
+		returning id`,
+		params.Name,
+		params.TokenHash,
+		params.ReadOnly,
+		now,
+		params.ExpiresAt,
+		s.userID,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.APIKey{
+		Id:        id,
+		UserId:    s.userID,
+		Name:      params.Name,
+		ReadOnly:  params.ReadOnly,
+		CreatedAt: now,
+		ExpiresAt: params.ExpiresAt,
+	}, nil
+}
+
+func (s *PostgresStorage) ListAPIKeys() ([]model.APIKey, error) {
+	rows, err := s.db.Query(`
+		select id, user_id, name, read_only, created_at, expires_at, last_used_at
+		from api_keys
+		where `+userScope(1)+`
+		order by id
+	`, s.userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		var key model.APIKey
+		if err := rows.Scan(&key.Id, &key.UserId, &key.Name, &key.ReadOnly, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *PostgresStorage) GetAPIKeyByHash(tokenHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := s.db.QueryRow(`
+		update api_keys set last_used_at = $1
+		where token_hash = $2 and (expires_at is null or expires_at > $1)
+		returning id, user_id, name, read_only, created_at, expires_at, last_used_at
+	`,
+		time.Now().UTC(),
+		tokenHash,
+	).Scan(&key.Id, &key.UserId, &key.Name, &key.ReadOnly, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *PostgresStorage) DeleteAPIKey(id int64) bool {
+	result, err := s.db.Exec(`delete from api_keys where id = $1 and `+userScope(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
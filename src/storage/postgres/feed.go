@@ -2,26 +2,54 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/nkanaev/yarr/src/storage/model"
 )
 
+var errFeedFolderNotFound = errors.New("folder not found")
+
+// checkFolder fails unless the folder, if any, belongs to the user.
+func (s *PostgresStorage) checkFolder(folderID *int64) error {
+	if folderID == nil {
+		return nil
+	}
+	var n int
+	err := s.db.QueryRow(
+		`select count(*) from folders where id = $1 and `+userScope(2),
+		*folderID, s.userID,
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errFeedFolderNotFound
+	}
+	return nil
+}
+
+// CreateFeed returns nil if the folder belongs to another user.
 func (s *PostgresStorage) CreateFeed(params model.CreateFeedParams) *model.Feed {
+	if err := s.checkFolder(params.FolderID); err != nil {
+		log.Print(err)
+		return nil
+	}
 	title := params.Title
 	if title == "" {
 		title = params.FeedLink
 	}
 	row := s.db.QueryRow(`
-		insert into feeds (title, description, link, feed_link, folder_id)
-		values ($1, $2, $3, $4, $5)
-		on conflict (feed_link) do update set folder_id = $5
+		insert into feeds (title, description, link, feed_link, folder_id, user_id)
+		values ($1, $2, $3, $4, $5, $6)
+		on conflict (user_id, feed_link) do update set folder_id = $5
 		returning id`,
 		title,
 		params.Description,
 		params.Link,
 		params.FeedLink,
 		params.FolderID,
+		s.userID,
 	)
 
 	var id int64
@@ -41,7 +69,7 @@ func (s *PostgresStorage) CreateFeed(params model.CreateFeedParams) *model.Feed
 }
 
 func (s *PostgresStorage) DeleteFeed(feedId int64) bool {
-	result, err := s.db.Exec(`delete from feeds where id = $1`, feedId)
+	result, err := s.db.Exec(`delete from feeds where id = $1 and `+userScope(2), feedId, s.userID)
 	if err != nil {
 		log.Print(err)
 		return false
@@ -54,15 +82,23 @@ func (s *PostgresStorage) DeleteFeed(feedId int64) bool {
 	return nrows == 1
 }
 
+// UpdateFeed fails if the folder belongs to another user.
 func (s *PostgresStorage) UpdateFeed(feedId int64, params model.UpdateFeedParams) (bool, error) {
+	if params.FolderID.Set {
+		if err := s.checkFolder(params.FolderID.Value); err != nil {
+			return false, err
+		}
+	}
 	_, err := s.db.Exec(`
 		update feeds set
 			title     = coalesce($2, title),
 			feed_link = coalesce($3, feed_link),
 			folder_id = case when $4 then $5 else folder_id end,
-			icon      = case when $6 then $7 else icon end
-		where id = $1
-	`,
+			icon      = case when $6 then $7 else icon end,
+			refresh_interval = case when $8 then $9 else refresh_interval end,
+			fetch_content = coalesce($11, fetch_content),
+			download_media = coalesce($12, download_media)
+		where id = $1 and `+userScope(10),
 		feedId,
 		params.Title,
 		params.FeedLink,
@@ -70,21 +106,39 @@ func (s *PostgresStorage) UpdateFeed(feedId int64, params model.UpdateFeedParams
 		params.FolderID.Value,
 		params.Icon.Set,
 		params.Icon.Value,
+		params.RefreshInterval.Set,
+		params.RefreshInterval.Value,
+		s.userID,
+		params.FetchContent,
+		params.DownloadMedia,
 	)
 	if err != nil {
 		log.Print(err)
 		return false, err
 	}
+	if params.RefreshInterval.Set {
+		// reschedule with the new interval on the next tick
+		_, err = s.db.Exec(`
+			update feed_states set next_refresh = null
+			where feed_id in (select id from feeds where id = $1 and `+userScope(2)+`)`,
+			feedId, s.userID,
+		)
+		if err != nil {
+			log.Print(err)
+			return false, err
+		}
+	}
 	return true, nil
 }
 
 func (s *PostgresStorage) ListFeeds() []model.Feed {
 	result := make([]model.Feed, 0)
 	rows, err := s.db.Query(`
-		select id, folder_id, title, description, link, feed_link, icon
+		select id, folder_id, title, description, link, feed_link, icon, refresh_interval, fetch_content, download_media
 		from feeds
+		where `+userScope(1)+`
 		order by lower(title)
-	`)
+	`, s.userID)
 	if err != nil {
 		log.Print(err)
 		return result
@@ -101,6 +155,9 @@ func (s *PostgresStorage) ListFeeds() []model.Feed {
 			&f.Link,
 			&f.FeedLink,
 			&f.Icon,
+			&f.RefreshInterval,
+			&f.FetchContent,
+			&f.DownloadMedia,
 		)
 		if err != nil {
 			log.Print(err)
@@ -116,11 +173,12 @@ func (s *PostgresStorage) GetFeed(id int64) *model.Feed {
 	err := s.db.QueryRow(`
 		select
 			id, folder_id, title, link, feed_link,
-			icon
-		from feeds where id = $1
-	`, id).Scan(
+			icon, refresh_interval, fetch_content, download_media
+		from feeds where id = $1 and `+userScope(2),
+		id, s.userID,
+	).Scan(
 		&f.Id, &f.FolderId, &f.Title, &f.Link, &f.FeedLink,
-		&f.Icon,
+		&f.Icon, &f.RefreshInterval, &f.FetchContent, &f.DownloadMedia,
 	)
 	if err != nil {
 		if err != sql.ErrNoRows {
@@ -2,10 +2,35 @@ package postgres
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
 
 	"github.com/nkanaev/yarr/src/storage/model"
 )
 
+// skipHours stores model.FeedState.SkipHours as a json array.
+type skipHours []int
+
+func (h *skipHours) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, h)
+	case string:
+		return json.Unmarshal([]byte(data), h)
+	default:
+		return nil
+	}
+}
+
+func (h skipHours) Value() (driver.Value, error) {
+	if h == nil {
+		h = skipHours{}
+	}
+	data, err := json.Marshal(h)
+	return string(data), err
+}
+
 func (s *PostgresStorage) ListFeedStates() ([]model.FeedState, error) {
 	rows, err := s.db.Query(`
 		select
@@ -14,8 +39,15 @@ func (s *PostgresStorage) ListFeedStates() ([]model.FeedState, error) {
 			, last_error
 			, http_lmod
 			, http_etag
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
 		from feed_states
-	`)
+		where feed_id in (select id from feeds where `+userScope(1)+`)
+	`, s.userID)
 	if err != nil {
 		return nil, err
 	}
@@ -24,16 +56,24 @@ func (s *PostgresStorage) ListFeedStates() ([]model.FeedState, error) {
 	states := make([]model.FeedState, 0)
 	for rows.Next() {
 		var state model.FeedState
+		var interval int64
 		err := rows.Scan(
 			&state.FeedID,
 			&state.LastRefreshed,
 			&state.LastError,
 			&state.HTTPLastModified,
 			&state.HTTPEtag,
+			&state.NextRefresh,
+			&interval,
+			(*skipHours)(&state.SkipHours),
+			&state.FailureCount,
+			&state.FirstFailure,
+			&state.Disabled,
 		)
 		if err != nil {
 			return nil, err
 		}
+		state.RefreshInterval = time.Duration(interval) * time.Second
 		states = append(states, state)
 	}
 	return states, nil
@@ -41,6 +81,7 @@ func (s *PostgresStorage) ListFeedStates() ([]model.FeedState, error) {
 
 func (s *PostgresStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 	var state model.FeedState
+	var interval int64
 	err := s.db.QueryRow(`
 		select
 			feed_id
@@ -48,13 +89,26 @@ func (s *PostgresStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 			, last_error
 			, http_lmod
 			, http_etag
-		from feed_states where feed_id = $1
-	`, feedID).Scan(
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
+		from feed_states
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+	`, feedID, s.userID).Scan(
 		&state.FeedID,
 		&state.LastRefreshed,
 		&state.LastError,
 		&state.HTTPLastModified,
 		&state.HTTPEtag,
+		&state.NextRefresh,
+		&interval,
+		(*skipHours)(&state.SkipHours),
+		&state.FailureCount,
+		&state.FirstFailure,
+		&state.Disabled,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -62,6 +116,7 @@ func (s *PostgresStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 	if err != nil {
 		return nil, err
 	}
+	state.RefreshInterval = time.Duration(interval) * time.Second
 	return &state, nil
 }
 
@@ -70,6 +125,15 @@ func (s *PostgresStorage) UpdateFeedState(feedID int64, params model.UpdateFeedS
 	if lastError != nil && *lastError == "" {
 		lastError = nil
 	}
+	var interval *int64
+	if params.RefreshInterval != nil {
+		seconds := int64(*params.RefreshInterval / time.Second)
+		interval = &seconds
+	}
+	var hours driver.Valuer
+	if params.SkipHours != nil {
+		hours = skipHours(*params.SkipHours)
+	}
 
 	_, err := s.db.Exec(`
 		insert into feed_states (
@@ -78,28 +142,101 @@ func (s *PostgresStorage) UpdateFeedState(feedID int64, params model.UpdateFeedS
 			, last_error
 			, http_lmod
 			, http_etag
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
 		)
-		values (
-			$1
+		select
+			$1::bigint
 			, coalesce($2, '1970-01-01 00:00:00+00'::timestamptz)
 			, coalesce($3, '')
 			, coalesce($4, '')
 			, coalesce($5, '')
-		)
+			, $6::timestamptz
+			, coalesce($7, 0)
+			, coalesce($8, '[]')
+			, coalesce($9, 0)
+			, $11::timestamptz
+			, coalesce($12, false)
+		where exists (select 1 from feeds where id = $1 and `+userScope(13)+`)
 		on conflict (feed_id) do update set
 			last_refreshed = coalesce($2, feed_states.last_refreshed),
 			last_error     = coalesce($3, feed_states.last_error),
 			http_lmod      = coalesce($4, feed_states.http_lmod),
-			http_etag      = coalesce($5, feed_states.http_etag)
+			http_etag      = coalesce($5, feed_states.http_etag),
+			next_refresh   = coalesce($6, feed_states.next_refresh),
+			refresh_interval = coalesce($7, feed_states.refresh_interval),
+			skip_hours     = coalesce($8, feed_states.skip_hours),
+			failure_count  = coalesce($9, feed_states.failure_count),
+			first_failure  = case when $10 then $11 else feed_states.first_failure end,
+			disabled       = coalesce($12, feed_states.disabled)
 	`,
 		feedID,
 		params.LastRefreshed,
 		params.LastError,
 		params.HTTPLastModified,
 		params.HTTPEtag,
+		params.NextRefresh,
+		interval,
+		hours,
+		params.FailureCount,
+		params.FirstFailure.Set,
+		params.FirstFailure.Value,
+		params.Disabled,
+		s.userID,
 	)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
+
+func (s *PostgresStorage) CreateFeedLinkChange(feedID int64, change model.FeedLinkChange) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into feed_link_history (feed_id, old_link, new_link, status_code, created_at)
+		select $1::bigint, $2::text, $3::text, $4::integer, $5::timestamptz
+		where exists (select 1 from feeds where id = $1 and `+userScope(6)+`)
+	`,
+		feedID,
+		change.OldLink,
+		change.NewLink,
+		change.StatusCode,
+		change.CreatedAt,
+		s.userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *PostgresStorage) ListFeedLinkChanges(feedID int64) ([]model.FeedLinkChange, error) {
+	rows, err := s.db.Query(`
+		select old_link, new_link, status_code, created_at
+		from feed_link_history
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+		order by id
+	`, feedID, s.userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]model.FeedLinkChange, 0)
+	for rows.Next() {
+		var change model.FeedLinkChange
+		err := rows.Scan(&change.OldLink, &change.NewLink, &change.StatusCode, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -9,11 +9,12 @@ import (
 func (s *PostgresStorage) CreateFolder(title string) *model.Folder {
 	expanded := true
 	row := s.db.QueryRow(`
-		insert into folders (title, is_expanded) values ($1, $2)
-		on conflict (title) do update set title = $1
+		insert into folders (title, is_expanded, user_id) values ($1, $2, $3)
+		on conflict (user_id, title) do update set title = $1
 		returning id`,
 		title,
 		expanded,
+		s.userID,
 	)
 	var id int64
 	err := row.Scan(&id)
@@ -26,7 +27,7 @@ func (s *PostgresStorage) CreateFolder(title string) *model.Folder {
 }
 
 func (s *PostgresStorage) DeleteFolder(folderId int64) bool {
-	_, err := s.db.Exec(`delete from folders where id = $1`, folderId)
+	_, err := s.db.Exec(`delete from folders where id = $1 and `+userScope(2), folderId, s.userID)
 	if err != nil {
 		log.Print(err)
 	}
@@ -38,11 +39,11 @@ func (s *PostgresStorage) UpdateFolder(folderId int64, params model.UpdateFolder
 		update folders set
 			title       = coalesce($2, title),
 			is_expanded = coalesce($3, is_expanded)
-		where id = $1
-	`,
+		where id = $1 and `+userScope(4),
 		folderId,
 		params.Title,
 		params.IsExpanded,
+		s.userID,
 	)
 	if err != nil {
 		log.Print(err)
@@ -56,8 +57,9 @@ func (s *PostgresStorage) ListFolders() []model.Folder {
 	rows, err := s.db.Query(`
 		select id, title, is_expanded
 		from folders
+		where `+userScope(1)+`
 		order by lower(title)
-	`)
+	`, s.userID)
 	if err != nil {
 		log.Print(err)
 		return result
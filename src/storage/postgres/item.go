@@ -12,6 +12,7 @@ import (
 
 	"github.com/nkanaev/yarr/src/content/htmlutil"
 	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/storage/search"
 )
 
 type MediaLinks model.MediaLinks
@@ -31,11 +32,56 @@ func (m MediaLinks) Value() (driver.Value, error) {
 	return json.Marshal(m)
 }
 
-func (s *PostgresStorage) CreateItems(items []model.Item) bool {
+type Categories []string
+
+func (c *Categories) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	default:
+		return nil
+	}
+}
+
+func (c Categories) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Podcast reads and writes the nullable podcast column of an item.
+type Podcast struct{ dst **model.Podcast }
+
+func (p *Podcast) Scan(src any) error {
+	var data []byte
+	switch x := src.(type) {
+	case []byte:
+		data = x
+	case string:
+		data = []byte(x)
+	default:
+		return nil
+	}
+	return json.Unmarshal(data, p.dst)
+}
+
+func (p Podcast) Value() (driver.Value, error) {
+	if *p.dst == nil {
+		return nil, nil
+	}
+	return json.Marshal(*p.dst)
+}
+
+// CreateItems stores the items, skipping known ones, and returns the new
+// ones with their ids. The boolean is false if the items couldn't be stored.
+func (s *PostgresStorage) CreateItems(items []model.Item) ([]model.Item, bool) {
 	tx, err := s.db.Begin()
 	if err != nil {
 		log.Print(err)
-		return false
+		return nil, false
 	}
 
 	now := time.Now().UTC()
@@ -46,23 +92,29 @@ func (s *PostgresStorage) CreateItems(items []model.Item) bool {
 		return cmp.Compare(sa, sb)
 	})
 
+	created := make([]model.Item, 0)
 	for _, item := range items {
 		searchText := item.Title + " " + htmlutil.ExtractText(item.Content)
-		_, err = tx.Exec(`
+		var id int64
+		var isNew bool
+		err = tx.QueryRow(`
 			insert into items (
 				guid, feed_id, title, link, date,
 				content, media_links,
 				date_arrived, last_arrived, status,
-				search
+				search,
+				author, categories, comments_url, podcast
 			)
 			values (
 				$1, $2, $3, $4, $5,
 				$6, $7,
 				$8, $9, $10,
-				to_tsvector('simple', $11)
+				to_tsvector('simple', $11),
+				$12, $13, $14, $15
 			)
 			on conflict (feed_id, guid) do update set
-				last_arrived = excluded.last_arrived`,
+				last_arrived = excluded.last_arrived
+			returning id, (xmax = 0)`,
 			item.GUID,
 			item.FeedId,
 			item.Title,
@@ -74,24 +126,32 @@ func (s *PostgresStorage) CreateItems(items []model.Item) bool {
 			now,
 			item.Status,
 			searchText,
-		)
+			item.Author,
+			Categories(item.Categories),
+			item.CommentsURL,
+			Podcast{&item.Podcast},
+		).Scan(&id, &isNew)
 		if err != nil {
 			log.Print(err)
 			if err = tx.Rollback(); err != nil {
 				log.Print(err)
-				return false
+				return nil, false
 			}
-			return false
+			return nil, false
+		}
+		if isNew {
+			item.Id = id
+			created = append(created, item)
 		}
 	}
 	if err = tx.Commit(); err != nil {
 		log.Print(err)
-		return false
+		return nil, false
 	}
-	return true
+	return created, true
 }
 
-func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []any) {
+func listQueryPredicate(filter model.ItemFilter, newestFirst bool, userID int64) (string, []any) {
 	cond := make([]string, 0)
 	args := make([]any, 0)
 	n := 0
@@ -101,6 +161,9 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 		return n
 	}
 
+	cond = append(cond, userItems(next()))
+	args = append(args, userID)
+
 	if filter.FolderID != nil {
 		cond = append(cond, fmt.Sprintf("i.feed_id in (select id from feeds where folder_id = $%d)", next()))
 		args = append(args, *filter.FolderID)
@@ -109,23 +172,37 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 		cond = append(cond, fmt.Sprintf("i.feed_id = $%d", next()))
 		args = append(args, *filter.FeedID)
 	}
+	if filter.Label != nil {
+		cond = append(cond, fmt.Sprintf("i.id in (select item_id from item_labels where label_id = $%d)", next()))
+		args = append(args, *filter.Label)
+	}
 	if filter.Status != nil {
 		cond = append(cond, fmt.Sprintf("i.status = $%d", next()))
 		args = append(args, *filter.Status)
 	}
+	if filter.Author != nil {
+		cond = append(cond, fmt.Sprintf("lower(i.author) = lower($%d)", next()))
+		args = append(args, *filter.Author)
+	}
+	if filter.Category != nil {
+		cond = append(cond, fmt.Sprintf("exists (select 1 from jsonb_array_elements_text(i.categories) c where lower(c) = lower($%d))", next()))
+		args = append(args, *filter.Category)
+	}
 	if filter.Search != nil {
-		words := strings.Fields(*filter.Search)
-		terms := make([]string, len(words))
-		for idx, word := range words {
-			terms[idx] = word + ":*"
+		if node := search.Parse(*filter.Search); node != nil {
+			p := &searchPredicate{next: next}
+			cond = append(cond, p.compile(node))
+			args = append(args, p.args...)
 		}
-
+	}
+	if rank := rankQuery(filter); filter.After != nil && rank != "" {
+		rankParam, afterParam := fmt.Sprintf("$%d", next()), fmt.Sprintf("$%d", next())
 		cond = append(cond, fmt.Sprintf(
-			"i.search @@ to_tsquery('simple', $%d)", next(),
+			"(%s, i.id) < (select %s, id from items a where id = %s)",
+			rankExpr("i", rankParam), rankExpr("a", rankParam), afterParam,
 		))
-		args = append(args, strings.Join(terms, " & "))
-	}
-	if filter.After != nil {
+		args = append(args, rank, *filter.After)
+	} else if filter.After != nil {
 		compare := ">"
 		if newestFirst {
 			compare = "<"
@@ -156,6 +233,10 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 		cond = append(cond, fmt.Sprintf("i.date < $%d", next()))
 		args = append(args, filter.Before)
 	}
+	if filter.Since != nil {
+		cond = append(cond, fmt.Sprintf("i.date >= $%d", next()))
+		args = append(args, filter.Since)
+	}
 
 	predicate := "true"
 	if len(cond) > 0 {
@@ -165,9 +246,11 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 	return predicate, args
 }
 
-func (s *PostgresStorage) CountItems() int {
+// CountItems returns the number of items matching the filter.
+func (s *PostgresStorage) CountItems(filter model.ItemFilter) int {
+	predicate, args := listQueryPredicate(filter, false, s.userID)
 	var count int
-	err := s.db.QueryRow(`select count(*) from items`).Scan(&count)
+	err := s.db.QueryRow(`select count(*) from items i where `+predicate, args...).Scan(&count)
 	if err != nil {
 		log.Print(err)
 		return 0
@@ -181,13 +264,17 @@ func (s *PostgresStorage) ListItems(
 	newestFirst bool,
 	withContent bool,
 ) []model.Item {
-	predicate, args := listQueryPredicate(filter, newestFirst)
+	predicate, args := listQueryPredicate(filter, newestFirst, s.userID)
 	result := make([]model.Item, 0)
 
 	order := "date desc, id desc"
 	if !newestFirst {
 		order = "date asc, id asc"
 	}
+	if rank := rankQuery(filter); rank != "" {
+		args = append(args, rank)
+		order = rankExpr("i", fmt.Sprintf("$%d", len(args))) + " desc, i.id desc"
+	}
 	if filter.IDs != nil || filter.SinceID != nil {
 		order = "i.id asc"
 	}
@@ -195,7 +282,7 @@ func (s *PostgresStorage) ListItems(
 		order = "i.id desc"
 	}
 
-	selectCols := "i.id, i.guid, i.feed_id, i.title, i.link, i.date, i.status, i.media_links"
+	selectCols := "i.id, i.guid, i.feed_id, i.title, i.link, i.date, i.status, i.media_links, i.author, i.categories, i.comments_url, i.podcast"
 	if withContent {
 		selectCols += ", i.content"
 	} else {
@@ -206,8 +293,8 @@ func (s *PostgresStorage) ListItems(
 		from items i
 		where %s
 		order by %s
-		limit %d
-		`, selectCols, predicate, order, limit)
+		limit %d offset %d
+		`, selectCols, predicate, order, limit, filter.Offset)
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		log.Print(err)
@@ -220,7 +307,9 @@ func (s *PostgresStorage) ListItems(
 		err = rows.Scan(
 			&x.Id, &x.GUID, &x.FeedId,
 			&x.Title, &x.Link, &x.Date,
-			&x.Status, (*MediaLinks)(&x.MediaLinks), &x.Content,
+			&x.Status, (*MediaLinks)(&x.MediaLinks), &x.Author, (*Categories)(&x.Categories), &x.CommentsURL,
+			&Podcast{&x.Podcast},
+			&x.Content,
 		)
 		if err != nil {
 			log.Print(err)
@@ -236,12 +325,15 @@ func (s *PostgresStorage) GetItem(id int64) *model.Item {
 	err := s.db.QueryRow(`
 		select
 			i.id, i.guid, i.feed_id, i.title, i.link, i.content,
-			i.date, i.status, i.media_links
+			i.date, i.status, i.media_links, i.full_content,
+			i.author, i.categories, i.comments_url, i.podcast
 		from items i
-		where i.id = $1
-	`, id).Scan(
+		where i.id = $1 and `+userItems(2),
+		id, s.userID,
+	).Scan(
 		&i.Id, &i.GUID, &i.FeedId, &i.Title, &i.Link, &i.Content,
-		&i.Date, &i.Status, (*MediaLinks)(&i.MediaLinks),
+		&i.Date, &i.Status, (*MediaLinks)(&i.MediaLinks), &i.FullContent,
+		&i.Author, (*Categories)(&i.Categories), &i.CommentsURL, &Podcast{&i.Podcast},
 	)
 	if err != nil {
 		log.Print(err)
@@ -250,6 +342,44 @@ func (s *PostgresStorage) GetItem(id int64) *model.Item {
 	return i
 }
 
+// ListItemFullContents returns the full content of the given items,
+// which is left out of the item lists. Items without one are skipped.
+func (s *PostgresStorage) ListItemFullContents(itemIDs []int64) map[int64]string {
+	result := make(map[int64]string)
+	if len(itemIDs) == 0 {
+		return result
+	}
+
+	args := []any{s.userID}
+	placeholders := make([]string, len(itemIDs))
+	for i, id := range itemIDs {
+		args = append(args, id)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+	rows, err := s.db.Query(`
+		select i.id, i.full_content
+		from items i
+		where i.id in (`+strings.Join(placeholders, ",")+`) and i.full_content != '' and `+userItems(1),
+		args...,
+	)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var content string
+		if err = rows.Scan(&id, &content); err != nil {
+			log.Print(err)
+			return result
+		}
+		result[id] = content
+	}
+	return result
+}
+
 func (s *PostgresStorage) UpdateItem(id int64, params model.UpdateItemParams) bool {
 	sets := make([]string, 0)
 	args := make([]any, 0)
@@ -273,26 +403,34 @@ func (s *PostgresStorage) UpdateItem(id int64, params model.UpdateItemParams) bo
 		sets = append(sets, fmt.Sprintf("last_arrived = $%d", n))
 		args = append(args, *params.LastArrived)
 	}
+	if params.FullContent != nil {
+		n++
+		sets = append(sets, fmt.Sprintf("full_content = $%d", n))
+		args = append(args, *params.FullContent)
+	}
 	if len(sets) == 0 {
 		return true
 	}
 
-	n++
-	args = append(args, id)
-	query := fmt.Sprintf("update items set %s where id = $%d", strings.Join(sets, ", "), n)
+	args = append(args, id, s.userID)
+	query := fmt.Sprintf(
+		"update items as i set %s where i.id = $%d and %s",
+		strings.Join(sets, ", "), n+1, userItems(n+2),
+	)
 	_, err := s.db.Exec(query, args...)
 	return err == nil
 }
 
 func (s *PostgresStorage) DeleteItem(id int64) bool {
-	_, err := s.db.Exec(`delete from items where id = $1`, id)
+	_, err := s.db.Exec(`delete from items as i where i.id = $1 and `+userItems(2), id, s.userID)
 	return err == nil
 }
 
 func (s *PostgresStorage) UpdateItemStatus(item_id int64, status model.ItemStatus) bool {
-	_, err := s.db.Exec(`update items set status = $2 where id = $1`,
+	_, err := s.db.Exec(`update items as i set status = $2 where i.id = $1 and `+userItems(3),
 		item_id,
 		status,
+		s.userID,
 	)
 	return err == nil
 }
@@ -301,8 +439,10 @@ func (s *PostgresStorage) MarkItemsRead(filter model.MarkFilter) bool {
 	predicate, args := listQueryPredicate(model.ItemFilter{
 		FolderID: filter.FolderID,
 		FeedID:   filter.FeedID,
+		Label:    filter.Label,
 		Before:   filter.Before,
-	}, false)
+		MaxID:    filter.MaxID,
+	}, false, s.userID)
 	query := fmt.Sprintf(`
 		update items as i set status = %d
 		where %s and i.status != %d
@@ -321,9 +461,10 @@ func (s *PostgresStorage) FeedStats() []model.FeedStat {
 			feed_id,
 			sum(case status when %d then 1 else 0 end),
 			sum(case status when %d then 1 else 0 end)
-		from items
+		from items i
+		where %s
 		group by feed_id
-	`, model.UNREAD, model.STARRED))
+	`, model.UNREAD, model.STARRED, userItems(1)), s.userID)
 	if err != nil {
 		log.Print(err)
 		return result
@@ -357,6 +498,7 @@ func (s *PostgresStorage) DeleteOldItems() {
 					max(last_arrived) over (partition by feed_id) as max_la
 				from items
 				where status != $1
+				  and id not in (select item_id from item_labels)
 			) sub
 			where rn > $2
 			  and last_arrived < max_la + $3::interval
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// userLabels restricts a query to the labels owned by the user passed as the n-th param.
+func userLabels(n int) string {
+	return "label_id in (select id from labels where " + userScope(n) + ")"
+}
+
+func (s *PostgresStorage) CreateLabel(title string) *model.Label {
+	row := s.db.QueryRow(`
+		insert into labels (title, user_id) values ($1, $2)
+		on conflict (user_id, title) do update set title = $1
+		returning id`,
+		title,
+		s.userID,
+	)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		log.Print(err)
+		return nil
+	}
+	return &model.Label{Id: id, Title: title}
+}
+
+func (s *PostgresStorage) DeleteLabel(id int64) bool {
+	_, err := s.db.Exec(`delete from labels where id = $1 and `+userScope(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+	}
+	return err == nil
+}
+
+func (s *PostgresStorage) UpdateLabel(id int64, params model.UpdateLabelParams) (bool, error) {
+	_, err := s.db.Exec(`
+		update labels set title = coalesce($2, title)
+		where id = $1 and `+userScope(3),
+		id,
+		params.Title,
+		s.userID,
+	)
+	if err != nil {
+		log.Print(err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PostgresStorage) ListLabels() []model.Label {
+	result := make([]model.Label, 0)
+	rows, err := s.db.Query(`
+		select id, title
+		from labels
+		where `+userScope(1)+`
+		order by lower(title)
+	`, s.userID)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l model.Label
+		if err = rows.Scan(&l.Id, &l.Title); err != nil {
+			log.Print(err)
+			return result
+		}
+		result = append(result, l)
+	}
+	return result
+}
+
+// AddItemLabel attaches the label to the item. Both must belong to the same user.
+func (s *PostgresStorage) AddItemLabel(itemID, labelID int64) bool {
+	result, err := s.db.Exec(`
+		insert into item_labels (item_id, label_id)
+		select i.id, l.id
+		from items i
+		join feeds f on f.id = i.feed_id
+		join labels l on l.user_id = f.user_id
+		where i.id = $1 and l.id = $2
+		  and ($3::bigint = 0 or l.user_id = $3)
+		on conflict (item_id, label_id) do update set label_id = excluded.label_id`,
+		itemID,
+		labelID,
+		s.userID,
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *PostgresStorage) RemoveItemLabel(itemID, labelID int64) bool {
+	result, err := s.db.Exec(
+		`delete from item_labels where item_id = $1 and label_id = $2 and `+userLabels(3),
+		itemID,
+		labelID,
+		s.userID,
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// ListItemLabels returns the label ids of the given items.
+// If itemIDs is nil, the labels of all the labelled items are returned.
+func (s *PostgresStorage) ListItemLabels(itemIDs []int64) map[int64][]int64 {
+	result := make(map[int64][]int64)
+	if itemIDs != nil && len(itemIDs) == 0 {
+		return result
+	}
+
+	cond := userLabels(1)
+	args := []any{s.userID}
+	if itemIDs != nil {
+		placeholders := make([]string, len(itemIDs))
+		for i, id := range itemIDs {
+			args = append(args, id)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		cond += " and item_id in (" + strings.Join(placeholders, ",") + ")"
+	}
+	rows, err := s.db.Query(`
+		select item_id, label_id
+		from item_labels
+		where `+cond+`
+		order by item_id, label_id
+	`, args...)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID, labelID int64
+		if err = rows.Scan(&itemID, &labelID); err != nil {
+			log.Print(err)
+			return result
+		}
+		result[itemID] = append(result[itemID], labelID)
+	}
+	return result
+}
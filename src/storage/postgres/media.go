@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// userMedia restricts a query to the media files of the items owned by the user passed as the n-th param.
+func userMedia(n int) string {
+	return "item_id in (select i.id from items i where " + userItems(n) + ")"
+}
+
+// CreateMediaFile records a downloaded enclosure, replacing
+// the previous copy of the same url, if any.
+func (s *PostgresStorage) CreateMediaFile(file model.MediaFile) (*model.MediaFile, error) {
+	file.CreatedAt = time.Now().UTC()
+	err := s.db.QueryRow(`
+		insert into media_files (item_id, url, path, mime_type, size, created_at)
+		select $1::bigint, $2::text, $3::text, $4::text, $5::bigint, $6::timestamptz
+		where exists (select 1 from items i where i.id = $1 and `+userItems(7)+`)
+		on conflict (item_id, url) do update set
+			path       = excluded.path,
+			mime_type  = excluded.mime_type,
+			size       = excluded.size,
+			created_at = excluded.created_at
+		returning id`,
+		file.ItemID,
+		file.URL,
+		file.Path,
+		file.MimeType,
+		file.Size,
+		file.CreatedAt,
+		s.userID,
+	).Scan(&file.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (s *PostgresStorage) DeleteMediaFile(id int64) bool {
+	result, err := s.db.Exec(`delete from media_files where id = $1 and `+userMedia(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *PostgresStorage) GetMediaFile(id int64) (*model.MediaFile, error) {
+	var x model.MediaFile
+	err := s.db.QueryRow(`
+		select id, item_id, url, path, mime_type, size, created_at
+		from media_files
+		where id = $1 and `+userMedia(2),
+		id, s.userID,
+	).Scan(&x.Id, &x.ItemID, &x.URL, &x.Path, &x.MimeType, &x.Size, &x.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// ListMediaFiles returns the media files of the given items, oldest first.
+// If itemIDs is nil, all the media files are returned.
+func (s *PostgresStorage) ListMediaFiles(itemIDs []int64) ([]model.MediaFile, error) {
+	result := make([]model.MediaFile, 0)
+	if itemIDs != nil && len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	cond := userMedia(1)
+	args := []any{s.userID}
+	if itemIDs != nil {
+		placeholders := make([]string, len(itemIDs))
+		for i, id := range itemIDs {
+			args = append(args, id)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		cond += " and item_id in (" + strings.Join(placeholders, ",") + ")"
+	}
+	rows, err := s.db.Query(`
+		select id, item_id, url, path, mime_type, size, created_at
+		from media_files
+		where `+cond+`
+		order by created_at, id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var x model.MediaFile
+		if err := rows.Scan(&x.Id, &x.ItemID, &x.URL, &x.Path, &x.MimeType, &x.Size, &x.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, x)
+	}
+	return result, rows.Err()
+}
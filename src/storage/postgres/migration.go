@@ -8,6 +8,23 @@ import (
 
 var migrations = []func(*sql.Tx) error{
 	m01_initial,
+	m02_add_api_keys,
+	m03_add_users,
+	m04_add_api_key_scopes,
+	m05_add_labels,
+	m06_add_rules,
+	m07_add_feed_schedule,
+	m08_add_feed_failures,
+	m09_add_feed_link_history,
+	m10_add_websub_subscriptions,
+	m11_add_webhooks,
+	m12_add_output_feeds,
+	m13_add_full_content,
+	m14_add_feed_scrapers,
+	m15_add_item_authors,
+	m16_add_item_podcast,
+	m17_add_media_files,
+	m18_add_saved_searches,
 }
 
 var maxVersion = int64(len(migrations))
@@ -118,3 +135,255 @@ func m01_initial(tx *sql.Tx) error {
 	`)
 	return err
 }
+
+func m02_add_api_keys(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists api_keys (
+			id           bigserial primary key,
+			name         text not null,
+			token_hash   text not null unique,
+			created_at   timestamptz not null,
+			last_used_at timestamptz
+		);
+	`)
+	return err
+}
+
+func m03_add_users(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists users (
+			id            bigserial primary key,
+			username      text not null unique,
+			password_hash text not null default '',
+			fever_key     text not null default '',
+			is_admin      boolean not null default false,
+			created_at    timestamptz not null default now()
+		);
+
+		-- the existing data belongs to the default user
+		insert into users (id, username, is_admin) values (1, 'admin', true);
+		select setval('users_id_seq', (select max(id) from users));
+
+		alter table folders add column user_id bigint not null default 1 references users(id) on delete cascade;
+		drop index if exists idx_folder_title;
+		create unique index idx_folder_title on folders(user_id, title);
+
+		alter table feeds add column user_id bigint not null default 1 references users(id) on delete cascade;
+		drop index if exists idx_feed_feed_link;
+		create unique index idx_feed_feed_link on feeds(user_id, feed_link);
+		create index idx_feed_user_id on feeds(user_id);
+
+		alter table settings add column user_id bigint not null default 1 references users(id) on delete cascade;
+		alter table settings drop constraint settings_pkey;
+		alter table settings add primary key (user_id, key);
+
+		alter table api_keys add column user_id bigint not null default 1 references users(id) on delete cascade;
+	`)
+	return err
+}
+
+func m04_add_api_key_scopes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table api_keys add column read_only boolean not null default false;
+		alter table api_keys add column expires_at timestamptz;
+	`)
+	return err
+}
+
+func m05_add_labels(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists labels (
+			id      bigserial primary key,
+			user_id bigint not null default 1 references users(id) on delete cascade,
+			title   text not null
+		);
+		create unique index if not exists idx_label_title on labels(user_id, title);
+
+		create table if not exists item_labels (
+			item_id  bigint not null references items(id) on delete cascade,
+			label_id bigint not null references labels(id) on delete cascade,
+			primary key (item_id, label_id)
+		);
+		create index if not exists idx_item_labels_label_id on item_labels(label_id);
+	`)
+	return err
+}
+
+func m06_add_rules(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists rules (
+			id         bigserial primary key,
+			user_id    bigint not null default 1 references users(id) on delete cascade,
+			title      text not null default '',
+			feed_id    bigint references feeds(id) on delete cascade,
+			conditions jsonb not null default '[]',
+			action     text not null,
+			is_enabled boolean not null default true
+		);
+		create index if not exists idx_rule_user_id on rules(user_id);
+	`)
+	return err
+}
+
+func m07_add_feed_schedule(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column if not exists refresh_interval bigint;
+		alter table feed_states add column if not exists next_refresh timestamptz;
+		alter table feed_states add column if not exists refresh_interval bigint not null default 0;
+		alter table feed_states add column if not exists skip_hours text not null default '[]';
+	`)
+	return err
+}
+
+func m08_add_feed_failures(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feed_states add column if not exists failure_count integer not null default 0;
+		alter table feed_states add column if not exists first_failure timestamptz;
+		alter table feed_states add column if not exists disabled boolean not null default false;
+	`)
+	return err
+}
+
+func m09_add_feed_link_history(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists feed_link_history (
+			id          bigserial primary key,
+			feed_id     bigint not null references feeds(id) on delete cascade,
+			old_link    text not null,
+			new_link    text not null,
+			status_code integer not null,
+			created_at  timestamptz not null
+		);
+		create index if not exists idx_feed_link_history_feed_id on feed_link_history(feed_id);
+	`)
+	return err
+}
+
+func m10_add_websub_subscriptions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists websub_subscriptions (
+			feed_id      bigint primary key references feeds(id) on delete cascade,
+			hub          text not null,
+			topic        text not null,
+			secret       text not null,
+			requested_at timestamptz not null,
+			expires_at   timestamptz
+		);
+	`)
+	return err
+}
+
+func m11_add_webhooks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists webhooks (
+			id         bigserial primary key,
+			user_id    bigint not null default 1 references users(id) on delete cascade,
+			title      text not null default '',
+			url        text not null,
+			folder_id  bigint references folders(id) on delete cascade,
+			feed_id    bigint references feeds(id) on delete cascade,
+			keyword    text not null default '',
+			format     text not null default 'json',
+			template   text not null default '',
+			secret     text not null default '',
+			is_enabled boolean not null default true
+		);
+		create index if not exists idx_webhook_user_id on webhooks(user_id);
+
+		create table if not exists webhook_deliveries (
+			id          bigserial primary key,
+			webhook_id  bigint not null references webhooks(id) on delete cascade,
+			item_id     bigint references items(id) on delete set null,
+			attempt     integer not null,
+			status_code integer not null default 0,
+			error       text not null default '',
+			created_at  timestamptz not null
+		);
+		create index if not exists idx_webhook_delivery_webhook_id on webhook_deliveries(webhook_id);
+	`)
+	return err
+}
+
+func m12_add_output_feeds(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists output_feeds (
+			id         bigserial primary key,
+			user_id    bigint not null default 1 references users(id) on delete cascade,
+			source     text not null,
+			folder_id  bigint references folders(id) on delete cascade,
+			label_id   bigint references labels(id) on delete cascade,
+			token      text not null unique,
+			created_at timestamptz not null
+		);
+		create index if not exists idx_output_feed_user_id on output_feeds(user_id);
+	`)
+	return err
+}
+
+func m13_add_full_content(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column if not exists fetch_content boolean not null default false;
+		alter table items add column if not exists full_content text not null default '';
+	`)
+	return err
+}
+
+func m14_add_feed_scrapers(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists feed_scrapers (
+			feed_id          bigint primary key references feeds(id) on delete cascade,
+			item_selector    text not null,
+			title_selector   text not null default '',
+			link_selector    text not null default '',
+			date_selector    text not null default '',
+			content_selector text not null default ''
+		);
+	`)
+	return err
+}
+
+func m15_add_item_authors(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table items add column if not exists author text not null default '';
+		alter table items add column if not exists categories jsonb;
+		alter table items add column if not exists comments_url text not null default '';
+	`)
+	return err
+}
+
+func m16_add_item_podcast(tx *sql.Tx) error {
+	_, err := tx.Exec(`alter table items add column if not exists podcast jsonb;`)
+	return err
+}
+
+func m17_add_media_files(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column if not exists download_media boolean not null default false;
+
+		create table if not exists media_files (
+			id         bigserial primary key,
+			item_id    bigint not null references items(id) on delete cascade,
+			url        text not null,
+			path       text not null,
+			mime_type  text not null default '',
+			size       bigint not null default 0,
+			created_at timestamptz not null,
+			unique(item_id, url)
+		);
+	`)
+	return err
+}
+
+func m18_add_saved_searches(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table if not exists saved_searches (
+			id       bigserial primary key,
+			user_id  bigint not null default 1 references users(id) on delete cascade,
+			name     text not null,
+			query    text not null,
+			status   integer
+		);
+		create index if not exists idx_saved_search_user_id on saved_searches(user_id);
+	`)
+	return err
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+var errOutputSourceNotFound = errors.New("folder or label not found")
+
+const outputFeedColumns = "id, user_id, source, coalesce(folder_id, label_id), token, created_at"
+
+func scanOutputFeed(row interface{ Scan(...any) error }) (model.OutputFeed, error) {
+	var feed model.OutputFeed
+	err := row.Scan(&feed.Id, &feed.UserId, &feed.Source, &feed.SourceID, &feed.Token, &feed.CreatedAt)
+	return feed, err
+}
+
+// CreateOutputFeed fails if the source is a folder or label of another user.
+func (s *PostgresStorage) CreateOutputFeed(feed model.OutputFeed) (*model.OutputFeed, error) {
+	var folderID, labelID *int64
+	switch feed.Source {
+	case model.OutputFolder:
+		folderID = feed.SourceID
+	case model.OutputLabel:
+		labelID = feed.SourceID
+	default:
+		feed.SourceID = nil
+	}
+	feed.UserId = s.userID
+	feed.CreatedAt = time.Now().UTC()
+	err := s.db.QueryRow(`
+		insert into output_feeds (user_id, source, folder_id, label_id, token, created_at)
+		select $1, $2, $3, $4, $5, $6
+		where ($3::bigint is null or $3 in (select id from folders where `+userScope(1)+`))
+		  and ($4::bigint is null or $4 in (select id from labels where `+userScope(1)+`))
+		returning id`,
+		s.userID,
+		feed.Source,
+		folderID,
+		labelID,
+		feed.Token,
+		feed.CreatedAt,
+	).Scan(&feed.Id)
+	if err == sql.ErrNoRows {
+		return nil, errOutputSourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (s *PostgresStorage) DeleteOutputFeed(id int64) bool {
+	result, err := s.db.Exec(`delete from output_feeds where id = $1 and `+userScope(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *PostgresStorage) ListOutputFeeds() ([]model.OutputFeed, error) {
+	rows, err := s.db.Query(
+		`select `+outputFeedColumns+` from output_feeds where `+userScope(1)+` order by id`,
+		s.userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make([]model.OutputFeed, 0)
+	for rows.Next() {
+		feed, err := scanOutputFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+// GetOutputFeedByToken looks up the output feed of any user.
+// Returns nil if no feed matches.
+func (s *PostgresStorage) GetOutputFeedByToken(token string) (*model.OutputFeed, error) {
+	feed, err := scanOutputFeed(s.db.QueryRow(
+		`select `+outputFeedColumns+` from output_feeds where token = $1`,
+		token,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+type RuleConditions model.RuleConditions
+
+func (c *RuleConditions) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	default:
+		return nil
+	}
+}
+
+func (c RuleConditions) Value() (driver.Value, error) {
+	if c == nil {
+		c = RuleConditions{}
+	}
+	return json.Marshal(c)
+}
+
+var errRuleFeedNotFound = errors.New("feed not found")
+
+const ruleColumns = "id, title, feed_id, conditions, action, is_enabled"
+
+func scanRule(row interface{ Scan(...any) error }) (model.Rule, error) {
+	var rule model.Rule
+	err := row.Scan(
+		&rule.Id, &rule.Title, &rule.FeedID,
+		(*RuleConditions)(&rule.Conditions), &rule.Action, &rule.IsEnabled,
+	)
+	return rule, err
+}
+
+// CreateRule fails if the rule refers to a feed of another user.
+func (s *PostgresStorage) CreateRule(rule model.Rule) (*model.Rule, error) {
+	err := s.db.QueryRow(`
+		insert into rules (user_id, title, feed_id, conditions, action, is_enabled)
+		select $1, $2, $3, $4, $5, $6
+		where $3::bigint is null or $3 in (select id from feeds where `+userScope(1)+`)
+		returning id`,
+		s.userID,
+		rule.Title,
+		rule.FeedID,
+		RuleConditions(rule.Conditions),
+		rule.Action,
+		rule.IsEnabled,
+	).Scan(&rule.Id)
+	if err == sql.ErrNoRows {
+		return nil, errRuleFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule replaces all the fields of the rule.
+func (s *PostgresStorage) UpdateRule(id int64, rule model.Rule) (bool, error) {
+	result, err := s.db.Exec(`
+		update rules set
+			title      = $3,
+			feed_id    = $4,
+			conditions = $5,
+			action     = $6,
+			is_enabled = $7
+		where id = $1 and `+userScope(2)+`
+		  and ($4::bigint is null or $4 in (select id from feeds where `+userScope(2)+`))`,
+		id,
+		s.userID,
+		rule.Title,
+		rule.FeedID,
+		RuleConditions(rule.Conditions),
+		rule.Action,
+		rule.IsEnabled,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *PostgresStorage) DeleteRule(id int64) bool {
+	result, err := s.db.Exec(`delete from rules where id = $1 and `+userScope(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// GetRule returns nil if the rule doesn't exist.
+func (s *PostgresStorage) GetRule(id int64) (*model.Rule, error) {
+	rule, err := scanRule(s.db.QueryRow(
+		`select `+ruleColumns+` from rules where id = $1 and `+userScope(2),
+		id,
+		s.userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *PostgresStorage) ListRules() ([]model.Rule, error) {
+	return s.listRules(
+		`select `+ruleColumns+` from rules where `+userScope(1)+` order by id`,
+		s.userID,
+	)
+}
+
+// ListFeedRules returns the enabled rules applying to the feed,
+// i.e. the ones of the feed owner targeting either the feed or all feeds.
+func (s *PostgresStorage) ListFeedRules(feedID int64) ([]model.Rule, error) {
+	return s.listRules(`
+		select `+ruleColumns+`
+		from rules
+		where is_enabled
+		  and user_id = (select user_id from feeds where id = $1 and `+userScope(2)+`)
+		  and (feed_id is null or feed_id = $1)
+		order by id`,
+		feedID,
+		s.userID,
+	)
+}
+
+func (s *PostgresStorage) listRules(query string, args ...any) ([]model.Rule, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]model.Rule, 0)
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
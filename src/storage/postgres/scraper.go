@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *PostgresStorage) GetFeedScraper(feedID int64) (*model.FeedScraper, error) {
+	var x model.FeedScraper
+	err := s.db.QueryRow(`
+		select feed_id, item_selector, title_selector, link_selector, date_selector, content_selector
+		from feed_scrapers
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+	`, feedID, s.userID).Scan(
+		&x.FeedID, &x.Item, &x.Title, &x.Link, &x.Date, &x.Content,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// UpdateFeedScraper creates or replaces the scraper of the feed.
+func (s *PostgresStorage) UpdateFeedScraper(x model.FeedScraper) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into feed_scrapers (feed_id, item_selector, title_selector, link_selector, date_selector, content_selector)
+		select $1::bigint, $2::text, $3::text, $4::text, $5::text, $6::text
+		where exists (select 1 from feeds where id = $1 and `+userScope(7)+`)
+		on conflict (feed_id) do update set
+			item_selector    = excluded.item_selector,
+			title_selector   = excluded.title_selector,
+			link_selector    = excluded.link_selector,
+			date_selector    = excluded.date_selector,
+			content_selector = excluded.content_selector
+	`,
+		x.FeedID,
+		x.Item,
+		x.Title,
+		x.Link,
+		x.Date,
+		x.Content,
+		s.userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *PostgresStorage) DeleteFeedScraper(feedID int64) bool {
+	result, err := s.db.Exec(`
+		delete from feed_scrapers
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+	`, feedID, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/storage/search"
+)
+
+// searchPredicate compiles the search query, numbering its params with next.
+type searchPredicate struct {
+	next func() int
+	args []any
+}
+
+func (p *searchPredicate) param(value any) string {
+	p.args = append(p.args, value)
+	return fmt.Sprintf("$%d", p.next())
+}
+
+func (p *searchPredicate) compile(node search.Node) string {
+	switch n := node.(type) {
+	case search.And:
+		return p.join(n.Nodes, " and ")
+	case search.Or:
+		return p.join(n.Nodes, " or ")
+	case search.Not:
+		return "not (" + p.compile(n.Node) + ")"
+	case search.Term:
+		switch n.Field {
+		case search.Feed:
+			return "i.feed_id in (select id from feeds where title ilike " + p.param(likePattern(n.Value)) + ")"
+		case search.Folder:
+			return "i.feed_id in (select f.id from feeds f join folders d on d.id = f.folder_id where d.title ilike " +
+				p.param(likePattern(n.Value)) + ")"
+		case search.Author:
+			return "i.author ilike " + p.param(likePattern(n.Value))
+		case search.Title:
+			return "to_tsvector('simple', i.title) @@ to_tsquery('simple', " + p.param(tsQuery(n)) + ")"
+		}
+		return "i.search @@ to_tsquery('simple', " + p.param(tsQuery(n)) + ")"
+	case search.Date:
+		if n.Before {
+			return "i.date < " + p.param(n.Time)
+		}
+		return "i.date >= " + p.param(n.Time)
+	case search.Status:
+		return "i.status = " + p.param(n.Status)
+	}
+	return "true"
+}
+
+func (p *searchPredicate) join(nodes []search.Node, op string) string {
+	conds := make([]string, len(nodes))
+	for i, node := range nodes {
+		conds[i] = p.compile(node)
+	}
+	return "(" + strings.Join(conds, op) + ")"
+}
+
+// tsQuery returns the tsquery of a text term: the words
+// by prefix, or the exact phrase.
+func tsQuery(term search.Term) string {
+	words := search.Words(term.Value)
+	if term.Phrase {
+		lexemes := make([]string, len(words))
+		for i, word := range words {
+			lexemes[i] = "'" + word + "'"
+		}
+		return strings.Join(lexemes, " <-> ")
+	}
+	lexemes := make([]string, len(words))
+	for i, word := range words {
+		lexemes[i] = "'" + word + "':*"
+	}
+	return strings.Join(lexemes, " & ")
+}
+
+// rankQuery returns the tsquery the relevance is computed on, or an
+// empty string if the items aren't sorted by relevance.
+func rankQuery(filter model.ItemFilter) string {
+	if !filter.SortByRelevance || filter.Search == nil {
+		return ""
+	}
+	terms := search.Ranked(search.Parse(*filter.Search))
+	queries := make([]string, len(terms))
+	for i, term := range terms {
+		queries[i] = "(" + tsQuery(term) + ")"
+	}
+	return strings.Join(queries, " | ")
+}
+
+// rankExpr returns the relevance of the item, higher is better.
+func rankExpr(item, param string) string {
+	return fmt.Sprintf("ts_rank(%s.search, to_tsquery('simple', %s))", item, param)
+}
+
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
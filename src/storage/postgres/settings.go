@@ -9,7 +9,7 @@ import (
 
 func (s *PostgresStorage) GetSettings() model.Settings {
 	result := model.SettingsDefault()
-	rows, err := s.db.Query(`select key, val from settings;`)
+	rows, err := s.db.Query(`select key, val from settings where user_id = $1`, s.userID)
 	if err != nil {
 		log.Print(err)
 		return result
@@ -61,10 +61,11 @@ func (s *PostgresStorage) UpdateSettings(params model.UpdateSettingsParams) bool
 			return err
 		}
 		_, err = tx.Exec(`
-			insert into settings (key, val) values ($1, $2)
-			on conflict (key) do update set val = $2`,
+			insert into settings (key, val, user_id) values ($1, $2, $3)
+			on conflict (user_id, key) do update set val = $2`,
 			key,
 			valEncoded,
+			s.userID,
 		)
 		return err
 	}
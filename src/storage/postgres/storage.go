@@ -2,13 +2,28 @@ package postgres
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	_ "github.com/lib/pq"
+	"github.com/nkanaev/yarr/src/storage/model"
 )
 
 type PostgresStorage struct {
 	db *sql.DB
+
+	// the user owning the feeds, folders & settings; 0 means all users
+	userID int64
+}
+
+// userScope restricts a query to the rows owned by the user passed as the n-th param.
+func userScope(n int) string {
+	return fmt.Sprintf("($%d::bigint = 0 or user_id = $%d)", n, n)
+}
+
+// userItems restricts a query to the items of the feeds owned by the user passed as the n-th param.
+func userItems(n int) string {
+	return "i.feed_id in (select id from feeds where " + userScope(n) + ")"
 }
 
 func New(connStr string) (*PostgresStorage, error) {
@@ -26,7 +41,22 @@ func New(connStr string) (*PostgresStorage, error) {
 	}
 
 	log.Print("connected to postgres")
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{db: db, userID: model.DefaultUserID}, nil
+}
+
+// ForUser returns a view of the storage scoped to the given user.
+func (s *PostgresStorage) ForUser(userID int64) *PostgresStorage {
+	return &PostgresStorage{db: s.db, userID: userID}
+}
+
+func (s *PostgresStorage) UserID() int64 {
+	return s.userID
+}
+
+// DB returns the underlying connection pool, for bulk operations
+// such as copying the data to another database.
+func (s *PostgresStorage) DB() *sql.DB {
+	return s.db
 }
 
 func (s *PostgresStorage) Close() error {
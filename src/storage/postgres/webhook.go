@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// number of deliveries kept per webhook
+const webhookDeliveryLimit = 100
+
+var errWebhookTargetNotFound = errors.New("folder or feed not found")
+
+const webhookColumns = "id, user_id, title, url, folder_id, feed_id, keyword, format, template, secret, is_enabled"
+
+func scanWebhook(row interface{ Scan(...any) error }) (model.Webhook, error) {
+	var hook model.Webhook
+	err := row.Scan(
+		&hook.Id, &hook.UserId, &hook.Title, &hook.URL, &hook.FolderID, &hook.FeedID,
+		&hook.Keyword, &hook.Format, &hook.Template, &hook.Secret, &hook.IsEnabled,
+	)
+	return hook, err
+}
+
+// CreateWebhook fails if the webhook refers to a folder or feed of another user.
+func (s *PostgresStorage) CreateWebhook(hook model.Webhook) (*model.Webhook, error) {
+	err := s.db.QueryRow(`
+		insert into webhooks (user_id, title, url, folder_id, feed_id, keyword, format, template, secret, is_enabled)
+		select $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		where ($4::bigint is null or $4 in (select id from folders where `+userScope(1)+`))
+		  and ($5::bigint is null or $5 in (select id from feeds where `+userScope(1)+`))
+		returning id`,
+		s.userID,
+		hook.Title,
+		hook.URL,
+		hook.FolderID,
+		hook.FeedID,
+		hook.Keyword,
+		hook.Format,
+		hook.Template,
+		hook.Secret,
+		hook.IsEnabled,
+	).Scan(&hook.Id)
+	if err == sql.ErrNoRows {
+		return nil, errWebhookTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	hook.UserId = s.userID
+	return &hook, nil
+}
+
+// UpdateWebhook replaces all the fields of the webhook.
+func (s *PostgresStorage) UpdateWebhook(id int64, hook model.Webhook) (bool, error) {
+	result, err := s.db.Exec(`
+		update webhooks set
+			title      = $3,
+			url        = $4,
+			folder_id  = $5,
+			feed_id    = $6,
+			keyword    = $7,
+			format     = $8,
+			template   = $9,
+			secret     = $10,
+			is_enabled = $11
+		where id = $1 and `+userScope(2)+`
+		  and ($5::bigint is null or $5 in (select id from folders where `+userScope(2)+`))
+		  and ($6::bigint is null or $6 in (select id from feeds where `+userScope(2)+`))`,
+		id,
+		s.userID,
+		hook.Title,
+		hook.URL,
+		hook.FolderID,
+		hook.FeedID,
+		hook.Keyword,
+		hook.Format,
+		hook.Template,
+		hook.Secret,
+		hook.IsEnabled,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *PostgresStorage) DeleteWebhook(id int64) bool {
+	result, err := s.db.Exec(`delete from webhooks where id = $1 and `+userScope(2), id, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// GetWebhook returns nil if the webhook doesn't exist.
+func (s *PostgresStorage) GetWebhook(id int64) (*model.Webhook, error) {
+	hook, err := scanWebhook(s.db.QueryRow(
+		`select `+webhookColumns+` from webhooks where id = $1 and `+userScope(2),
+		id, s.userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s *PostgresStorage) ListWebhooks() ([]model.Webhook, error) {
+	return s.listWebhooks(
+		`select `+webhookColumns+` from webhooks where `+userScope(1)+` order by id`,
+		s.userID,
+	)
+}
+
+// ListFeedWebhooks returns the enabled webhooks of the feed owner targeting
+// the feed, its folder, or all feeds. Keywords are left to the caller.
+func (s *PostgresStorage) ListFeedWebhooks(feedID int64) ([]model.Webhook, error) {
+	return s.listWebhooks(`
+		select `+webhookColumns+`
+		from webhooks
+		where is_enabled
+		  and user_id = (select user_id from feeds where id = $1 and `+userScope(2)+`)
+		  and (feed_id is null or feed_id = $1)
+		  and (folder_id is null or folder_id = (select folder_id from feeds where id = $1))
+		order by id`,
+		feedID, s.userID,
+	)
+}
+
+func (s *PostgresStorage) listWebhooks(query string, args ...any) ([]model.Webhook, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]model.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// CreateWebhookDelivery logs a delivery attempt, dropping the oldest
+// ones past the last 100 of the webhook.
+func (s *PostgresStorage) CreateWebhookDelivery(delivery model.WebhookDelivery) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		insert into webhook_deliveries (webhook_id, item_id, attempt, status_code, error, created_at)
+		select $1, $2, $3, $4, $5, $6
+		where exists (select 1 from webhooks where id = $1 and `+userScope(7)+`)`,
+		delivery.WebhookID,
+		delivery.ItemID,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.CreatedAt,
+		s.userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil || nrows == 0 {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		delete from webhook_deliveries
+		where webhook_id = $1 and id not in (
+			select id from webhook_deliveries
+			where webhook_id = $1
+			order by id desc
+			limit $2
+		)`,
+		delivery.WebhookID,
+		webhookDeliveryLimit,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ListWebhookDeliveries returns the most recent deliveries first.
+func (s *PostgresStorage) ListWebhookDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		select id, webhook_id, item_id, attempt, status_code, error, created_at
+		from webhook_deliveries
+		where webhook_id = $1
+		  and webhook_id in (select id from webhooks where `+userScope(2)+`)
+		order by id desc
+		limit $3`,
+		webhookID, s.userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		err := rows.Scan(&d.Id, &d.WebhookID, &d.ItemID, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *PostgresStorage) GetWebSubSubscription(feedID int64) (*model.WebSubSubscription, error) {
+	var sub model.WebSubSubscription
+	err := s.db.QueryRow(`
+		select feed_id, hub, topic, secret, requested_at, expires_at
+		from websub_subscriptions
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+	`, feedID, s.userID).Scan(
+		&sub.FeedID, &sub.Hub, &sub.Topic, &sub.Secret, &sub.RequestedAt, &sub.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateWebSubSubscription creates or replaces the subscription of the feed.
+func (s *PostgresStorage) UpdateWebSubSubscription(sub model.WebSubSubscription) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into websub_subscriptions (feed_id, hub, topic, secret, requested_at, expires_at)
+		select $1::bigint, $2::text, $3::text, $4::text, $5::timestamptz, $6::timestamptz
+		where exists (select 1 from feeds where id = $1 and `+userScope(7)+`)
+		on conflict (feed_id) do update set
+			hub          = excluded.hub,
+			topic        = excluded.topic,
+			secret       = excluded.secret,
+			requested_at = excluded.requested_at,
+			expires_at   = excluded.expires_at
+	`,
+		sub.FeedID,
+		sub.Hub,
+		sub.Topic,
+		sub.Secret,
+		sub.RequestedAt,
+		sub.ExpiresAt,
+		s.userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *PostgresStorage) DeleteWebSubSubscription(feedID int64) bool {
+	result, err := s.db.Exec(`
+		delete from websub_subscriptions
+		where feed_id = $1 and feed_id in (select id from feeds where `+userScope(2)+`)
+	`, feedID, s.userID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
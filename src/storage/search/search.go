@@ -0,0 +1,247 @@
+// Package search parses the item search queries:
+//
+//	word          items with a word starting with `word`, in the title or content
+//	"some words"  items with the exact phrase
+//	-word         items without the word (works with any of the terms)
+//	a OR b        items matching either side; terms are ANDed otherwise
+//	title:word    the word in the title only (also title:"some words")
+//	feed:name     items of the feeds whose title contains `name`
+//	folder:name   items of the feeds in the folders whose title contains `name`
+//	author:name   items whose author contains `name`
+//	before:DATE   items published before the day (2006-01-02)
+//	after:DATE    items published on the day or after
+//	is:starred    items with the status (also is:read and is:unread)
+//
+// The query is parsed into a tree, which each storage backend compiles
+// into its own conditions. Parsing never fails: anything that doesn't
+// make sense as an operator is searched for as text.
+package search
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// Node is a node of the query tree: And, Or, Not, Term, Date or Status.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Nodes []Node
+}
+
+type Or struct {
+	Nodes []Node
+}
+
+type Not struct {
+	Node Node
+}
+
+// Field is the part of the items a term is searched in.
+type Field string
+
+const (
+	// the title and the content
+	Text   Field = ""
+	Title  Field = "title"
+	Feed   Field = "feed"
+	Folder Field = "folder"
+	Author Field = "author"
+)
+
+// Term is a piece of text to look for. Text terms are matched by words
+// (by prefix, unless Phrase is set), the others by substring.
+type Term struct {
+	Field  Field
+	Value  string
+	Phrase bool
+}
+
+// Date matches the items published before (or on and after) the time.
+type Date struct {
+	Before bool
+	Time   time.Time
+}
+
+type Status struct {
+	Status model.ItemStatus
+}
+
+func (And) node()    {}
+func (Or) node()     {}
+func (Not) node()    {}
+func (Term) node()   {}
+func (Date) node()   {}
+func (Status) node() {}
+
+// token is a word of the query, or a quoted string.
+type token struct {
+	negate bool
+	// the `name:` prefix, if any
+	field  string
+	value  string
+	quoted bool
+}
+
+// Parse returns the tree of the query, or nil if there's nothing to search.
+func Parse(query string) Node {
+	var groups [][]Node
+	var group []Node
+	pendingOr := false
+	for _, tok := range tokenize(query) {
+		if tok.value == "OR" && !tok.quoted && !tok.negate && tok.field == "" {
+			pendingOr = len(group) > 0
+			continue
+		}
+		node := tok.node()
+		if node == nil {
+			continue
+		}
+		if pendingOr {
+			groups = append(groups, group)
+			group = nil
+			pendingOr = false
+		}
+		group = append(group, node)
+	}
+	groups = append(groups, group)
+
+	var alternatives []Node
+	for _, group := range groups {
+		switch len(group) {
+		case 0:
+		case 1:
+			alternatives = append(alternatives, group[0])
+		default:
+			alternatives = append(alternatives, And{Nodes: group})
+		}
+	}
+	switch len(alternatives) {
+	case 0:
+		return nil
+	case 1:
+		return alternatives[0]
+	}
+	return Or{Nodes: alternatives}
+}
+
+func tokenize(query string) []token {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		var tok token
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negate = true
+			i++
+		}
+		// the field name, if the word has a `name:` prefix
+		for j := i; j < len(runes) && unicode.IsLetter(runes[j]); j++ {
+			if j+1 < len(runes) && runes[j+1] == ':' && j+2 < len(runes) && !unicode.IsSpace(runes[j+2]) {
+				tok.field = strings.ToLower(string(runes[i : j+1]))
+				i = j + 2
+				break
+			}
+		}
+		if runes[i] == '"' {
+			// unterminated quotes run to the end of the query
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			tok.value = string(runes[i+1 : end])
+			tok.quoted = true
+			i = min(end+1, len(runes))
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			tok.value = string(runes[i:end])
+			i = end
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+var dateFormats = []string{"2006-01-02", "2006-01", "2006"}
+
+func (tok token) node() Node {
+	var node Node
+	switch tok.field {
+	case "title":
+		node = textTerm(Title, tok.value, tok.quoted)
+	case "feed", "folder", "author":
+		if value := strings.TrimSpace(tok.value); value != "" {
+			node = Term{Field: Field(tok.field), Value: value, Phrase: tok.quoted}
+		}
+	case "before", "after":
+		for _, format := range dateFormats {
+			if t, err := time.Parse(format, tok.value); err == nil {
+				node = Date{Before: tok.field == "before", Time: t}
+				break
+			}
+		}
+	case "is":
+		if status, ok := model.StatusValues[strings.ToLower(tok.value)]; ok {
+			node = Status{Status: status}
+		}
+	}
+	if node == nil {
+		value := tok.value
+		if tok.field != "" {
+			value = tok.field + ":" + value
+		}
+		node = textTerm(Text, value, tok.quoted)
+	}
+	if node != nil && tok.negate {
+		return Not{Node: node}
+	}
+	return node
+}
+
+// textTerm returns the term, unless it has no words to look for.
+func textTerm(field Field, value string, phrase bool) Node {
+	if len(Words(value)) == 0 {
+		return nil
+	}
+	return Term{Field: field, Value: value, Phrase: phrase}
+}
+
+// Words splits the value of a text term the way the full-text
+// indexes do, on anything that isn't a letter or a digit.
+func Words(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Ranked returns the text terms the items are expected to contain,
+// the ones relevance is computed on.
+func Ranked(node Node) []Term {
+	var terms []Term
+	switch n := node.(type) {
+	case And:
+		for _, child := range n.Nodes {
+			terms = append(terms, Ranked(child)...)
+		}
+	case Or:
+		for _, child := range n.Nodes {
+			terms = append(terms, Ranked(child)...)
+		}
+	case Term:
+		if n.Field == Text || n.Field == Title {
+			terms = append(terms, n)
+		}
+	}
+	return terms
+}
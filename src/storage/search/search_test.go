@@ -0,0 +1,58 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestParse(t *testing.T) {
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		query string
+		want  Node
+	}{
+		{"", nil},
+		{"  - \"\" ", nil},
+		{"go", Term{Value: "go"}},
+		{"go rust", And{Nodes: []Node{Term{Value: "go"}, Term{Value: "rust"}}}},
+		{`"hello world"`, Term{Value: "hello world", Phrase: true}},
+		{`"unterminated phrase`, Term{Value: "unterminated phrase", Phrase: true}},
+		{"-spam", Not{Node: Term{Value: "spam"}}},
+		{"go OR rust", Or{Nodes: []Node{Term{Value: "go"}, Term{Value: "rust"}}}},
+		{"go or rust", And{Nodes: []Node{Term{Value: "go"}, Term{Value: "or"}, Term{Value: "rust"}}}},
+		{"a b OR c", Or{Nodes: []Node{And{Nodes: []Node{Term{Value: "a"}, Term{Value: "b"}}}, Term{Value: "c"}}}},
+		{"OR go OR", Term{Value: "go"}},
+		{`title:"release notes"`, Term{Field: Title, Value: "release notes", Phrase: true}},
+		{"Feed:hacker", Term{Field: Feed, Value: "hacker"}},
+		{`folder:"Tech News"`, Term{Field: Folder, Value: "Tech News", Phrase: true}},
+		{"-author:bob", Not{Node: Term{Field: Author, Value: "bob"}}},
+		{"before:2024-05-01", Date{Before: true, Time: date}},
+		{"after:2024-05", Date{Time: date}},
+		{"after:yesterday", Term{Value: "after:yesterday"}},
+		{"is:starred", Status{Status: model.STARRED}},
+		{"is:unread", Status{Status: model.UNREAD}},
+		{"is:new", Term{Value: "is:new"}},
+		{"http://example.com", Term{Value: "http://example.com"}},
+		{"cve -title:draft is:unread", And{Nodes: []Node{
+			Term{Value: "cve"},
+			Not{Node: Term{Field: Title, Value: "draft"}},
+			Status{Status: model.UNREAD},
+		}}},
+	}
+	for _, tc := range testcases {
+		if have := Parse(tc.query); !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("%q:\nwant: %#v\nhave: %#v", tc.query, tc.want, have)
+		}
+	}
+}
+
+func TestRanked(t *testing.T) {
+	node := Parse(`go -spam feed:blog OR title:"release notes"`)
+	want := []Term{{Value: "go"}, {Field: Title, Value: "release notes", Phrase: true}}
+	if have := Ranked(node); !reflect.DeepEqual(have, want) {
+		t.Errorf("unexpected terms: %#v", have)
+	}
+}
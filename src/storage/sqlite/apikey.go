@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *SQLiteStorage) CreateAPIKey(params model.CreateAPIKeyParams) (*model.APIKey, error) {
+	now := time.Now().UTC()
+	var expiresAt *time.Time
+	if params.ExpiresAt != nil {
+		t := params.ExpiresAt.UTC()
+		expiresAt = &t
+	}
+	var id int64
+	err := s.db.QueryRow(`
+		insert into api_keys (user_id, name, token_hash, read_only, created_at, expires_at)
+		values (:user_id, :name, :token_hash, :read_only, :created_at, :expires_at)
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("name", params.Name),
+		sql.Named("token_hash", params.TokenHash),
+		sql.Named("read_only", params.ReadOnly),
+		sql.Named("created_at", now),
+		sql.Named("expires_at", expiresAt),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.APIKey{
+		Id:        id,
+		UserId:    s.userID,
+		Name:      params.Name,
+		ReadOnly:  params.ReadOnly,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *SQLiteStorage) ListAPIKeys() ([]model.APIKey, error) {
+	rows, err := s.db.Query(`
+		select id, user_id, name, read_only, created_at, expires_at, last_used_at
+		from api_keys
+		where `+userScope+`
+		order by id
+	`, sql.Named("user_id", s.userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		var key model.APIKey
+		if err := rows.Scan(&key.Id, &key.UserId, &key.Name, &key.ReadOnly, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash looks up the key of any user and records its usage time.
+// Returns nil if no key matches or the key has expired.
+func (s *SQLiteStorage) GetAPIKeyByHash(tokenHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := s.db.QueryRow(`
+		update api_keys set last_used_at = :now
+		where token_hash = :token_hash and (expires_at is null or expires_at > :now)
+		returning id, user_id, name, read_only, created_at, expires_at, last_used_at
+	`,
+		sql.Named("now", time.Now().UTC()),
+		sql.Named("token_hash", tokenHash),
+	).Scan(&key.Id, &key.UserId, &key.Name, &key.ReadOnly, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *SQLiteStorage) DeleteAPIKey(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from api_keys where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
@@ -2,21 +2,50 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/nkanaev/yarr/src/storage/model"
 )
 
+var errFeedFolderNotFound = errors.New("folder not found")
+
+// checkFolder fails unless the folder, if any, belongs to the user.
+func (s *SQLiteStorage) checkFolder(folderID *int64) error {
+	if folderID == nil {
+		return nil
+	}
+	var n int
+	err := s.db.QueryRow(
+		`select count(*) from folders where id = :id and `+userScope,
+		sql.Named("id", *folderID),
+		sql.Named("user_id", s.userID),
+	).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errFeedFolderNotFound
+	}
+	return nil
+}
+
+// CreateFeed returns nil if the folder belongs to another user.
 func (s *SQLiteStorage) CreateFeed(params model.CreateFeedParams) *model.Feed {
+	if err := s.checkFolder(params.FolderID); err != nil {
+		log.Print(err)
+		return nil
+	}
 	title := params.Title
 	if title == "" {
 		title = params.FeedLink
 	}
 	row := s.db.QueryRow(`
-		insert into feeds (title, description, link, feed_link, folder_id)
-		values (:title, :description, :link, :feed_link, :folder_id)
-		on conflict (feed_link) do update set folder_id = :folder_id
+		insert into feeds (user_id, title, description, link, feed_link, folder_id)
+		values (:user_id, :title, :description, :link, :feed_link, :folder_id)
+		on conflict (user_id, feed_link) do update set folder_id = :folder_id
         returning id`,
+		sql.Named("user_id", s.userID),
 		sql.Named("title", title),
 		sql.Named("description", params.Description),
 		sql.Named("link", params.Link),
@@ -41,7 +70,11 @@ func (s *SQLiteStorage) CreateFeed(params model.CreateFeedParams) *model.Feed {
 }
 
 func (s *SQLiteStorage) DeleteFeed(feedId int64) bool {
-	result, err := s.db.Exec(`delete from feeds where id = :id`, sql.Named("id", feedId))
+	result, err := s.db.Exec(
+		`delete from feeds where id = :id and `+userScope,
+		sql.Named("id", feedId),
+		sql.Named("user_id", s.userID),
+	)
 	if err != nil {
 		log.Print(err)
 		return false
@@ -56,37 +89,64 @@ func (s *SQLiteStorage) DeleteFeed(feedId int64) bool {
 	return nrows == 1
 }
 
+// UpdateFeed fails if the folder belongs to another user.
 func (s *SQLiteStorage) UpdateFeed(feedId int64, params model.UpdateFeedParams) (bool, error) {
+	if params.FolderID.Set {
+		if err := s.checkFolder(params.FolderID.Value); err != nil {
+			return false, err
+		}
+	}
 	_, err := s.db.Exec(`
 		update feeds set
 			title     = coalesce(:title, title),
 			feed_link = coalesce(:feed_link, feed_link),
 			folder_id = case when :update_folder_id then :folder_id else folder_id end,
-			icon      = case when :update_icon then :icon else icon end
-		where id = :id
-	`,
+			icon      = case when :update_icon then :icon else icon end,
+			refresh_interval = case when :update_refresh_interval then :refresh_interval else refresh_interval end,
+			fetch_content = coalesce(:fetch_content, fetch_content),
+			download_media = coalesce(:download_media, download_media)
+		where id = :id and `+userScope,
 		sql.Named("id", feedId),
+		sql.Named("user_id", s.userID),
 		sql.Named("title", params.Title),
 		sql.Named("feed_link", params.FeedLink),
 		sql.Named("update_folder_id", params.FolderID.Set),
 		sql.Named("folder_id", params.FolderID.Value),
 		sql.Named("update_icon", params.Icon.Set),
 		sql.Named("icon", params.Icon.Value),
+		sql.Named("update_refresh_interval", params.RefreshInterval.Set),
+		sql.Named("refresh_interval", params.RefreshInterval.Value),
+		sql.Named("fetch_content", params.FetchContent),
+		sql.Named("download_media", params.DownloadMedia),
 	)
 	if err != nil {
 		log.Print(err)
 		return false, err
 	}
+	if params.RefreshInterval.Set {
+		// reschedule with the new interval on the next tick
+		_, err = s.db.Exec(`
+			update feed_states set next_refresh = null
+			where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)`,
+			sql.Named("id", feedId),
+			sql.Named("user_id", s.userID),
+		)
+		if err != nil {
+			log.Print(err)
+			return false, err
+		}
+	}
 	return true, nil
 }
 
 func (s *SQLiteStorage) ListFeeds() []model.Feed {
 	result := make([]model.Feed, 0)
 	rows, err := s.db.Query(`
-		select id, folder_id, title, description, link, feed_link, icon
+		select id, folder_id, title, description, link, feed_link, icon, refresh_interval, fetch_content, download_media
 		from feeds
+		where `+userScope+`
 		order by title collate nocase
-	`)
+	`, sql.Named("user_id", s.userID))
 	if err != nil {
 		log.Print(err)
 		return result
@@ -101,6 +161,9 @@ func (s *SQLiteStorage) ListFeeds() []model.Feed {
 			&f.Link,
 			&f.FeedLink,
 			&f.Icon,
+			&f.RefreshInterval,
+			&f.FetchContent,
+			&f.DownloadMedia,
 		)
 		if err != nil {
 			log.Print(err)
@@ -116,11 +179,13 @@ func (s *SQLiteStorage) GetFeed(id int64) *model.Feed {
 	err := s.db.QueryRow(`
 		select
 			id, folder_id, title, link, feed_link,
-			icon
-		from feeds where id = :id
-	`, sql.Named("id", id)).Scan(
+			icon, refresh_interval, fetch_content, download_media
+		from feeds where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	).Scan(
 		&f.Id, &f.FolderId, &f.Title, &f.Link, &f.FeedLink,
-		&f.Icon,
+		&f.Icon, &f.RefreshInterval, &f.FetchContent, &f.DownloadMedia,
 	)
 	if err != nil {
 		if err != sql.ErrNoRows {
@@ -2,10 +2,35 @@ package sqlite
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
 
 	"github.com/nkanaev/yarr/src/storage/model"
 )
 
+// skipHours stores model.FeedState.SkipHours as a json array.
+type skipHours []int
+
+func (h *skipHours) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, h)
+	case string:
+		return json.Unmarshal([]byte(data), h)
+	default:
+		return nil
+	}
+}
+
+func (h skipHours) Value() (driver.Value, error) {
+	if h == nil {
+		h = skipHours{}
+	}
+	data, err := json.Marshal(h)
+	return string(data), err
+}
+
 func (s *SQLiteStorage) ListFeedStates() ([]model.FeedState, error) {
 	rows, err := s.db.Query(`
 		select
@@ -14,8 +39,15 @@ func (s *SQLiteStorage) ListFeedStates() ([]model.FeedState, error) {
 			, last_error
 			, http_lmod
 			, http_etag
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
 		from feed_states
-	`)
+		where feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("user_id", s.userID))
 	if err != nil {
 		return nil, err
 	}
@@ -24,16 +56,24 @@ func (s *SQLiteStorage) ListFeedStates() ([]model.FeedState, error) {
 	states := make([]model.FeedState, 0)
 	for rows.Next() {
 		var state model.FeedState
+		var interval int64
 		err := rows.Scan(
 			&state.FeedID,
 			&state.LastRefreshed,
 			&state.LastError,
 			&state.HTTPLastModified,
 			&state.HTTPEtag,
+			&state.NextRefresh,
+			&interval,
+			(*skipHours)(&state.SkipHours),
+			&state.FailureCount,
+			&state.FirstFailure,
+			&state.Disabled,
 		)
 		if err != nil {
 			return nil, err
 		}
+		state.RefreshInterval = time.Duration(interval) * time.Second
 		states = append(states, state)
 	}
 	return states, nil
@@ -41,6 +81,7 @@ func (s *SQLiteStorage) ListFeedStates() ([]model.FeedState, error) {
 
 func (s *SQLiteStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 	var state model.FeedState
+	var interval int64
 	err := s.db.QueryRow(`
 		select
 			feed_id
@@ -48,13 +89,26 @@ func (s *SQLiteStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 			, last_error
 			, http_lmod
 			, http_etag
-		from feed_states where feed_id = :id
-	`, sql.Named("id", feedID)).Scan(
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
+		from feed_states
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID)).Scan(
 		&state.FeedID,
 		&state.LastRefreshed,
 		&state.LastError,
 		&state.HTTPLastModified,
 		&state.HTTPEtag,
+		&state.NextRefresh,
+		&interval,
+		(*skipHours)(&state.SkipHours),
+		&state.FailureCount,
+		&state.FirstFailure,
+		&state.Disabled,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -62,6 +116,7 @@ func (s *SQLiteStorage) GetFeedState(feedID int64) (*model.FeedState, error) {
 	if err != nil {
 		return nil, err
 	}
+	state.RefreshInterval = time.Duration(interval) * time.Second
 	return &state, nil
 }
 
@@ -70,6 +125,15 @@ func (s *SQLiteStorage) UpdateFeedState(feedID int64, params model.UpdateFeedSta
 	if lastError != nil && *lastError == "" {
 		lastError = nil
 	}
+	var interval *int64
+	if params.RefreshInterval != nil {
+		seconds := int64(*params.RefreshInterval / time.Second)
+		interval = &seconds
+	}
+	var hours driver.Valuer
+	if params.SkipHours != nil {
+		hours = skipHours(*params.SkipHours)
+	}
 
 	_, err := s.db.Exec(`
 		insert into feed_states (
@@ -78,28 +142,101 @@ func (s *SQLiteStorage) UpdateFeedState(feedID int64, params model.UpdateFeedSta
 			, last_error
 			, http_lmod
 			, http_etag
+			, next_refresh
+			, refresh_interval
+			, skip_hours
+			, failure_count
+			, first_failure
+			, disabled
 		)
-		values (
+		select
 			:id
 			, coalesce(:last_refreshed, 0)
 			, coalesce(:last_error, '')
 			, coalesce(:http_lmod, '')
 			, coalesce(:http_etag, '')
-		)
+			, :next_refresh
+			, coalesce(:refresh_interval, 0)
+			, coalesce(:skip_hours, '[]')
+			, coalesce(:failure_count, 0)
+			, :first_failure
+			, coalesce(:disabled, false)
+		where exists (select 1 from feeds where id = :id and `+userScope+`)
 		on conflict (feed_id) do update set
 			last_refreshed = coalesce(:last_refreshed, last_refreshed),
 			last_error     = coalesce(:last_error, last_error),
 			http_lmod      = coalesce(:http_lmod, http_lmod),
-			http_etag      = coalesce(:http_etag, http_etag)
+			http_etag      = coalesce(:http_etag, http_etag),
+			next_refresh   = coalesce(:next_refresh, next_refresh),
+			refresh_interval = coalesce(:refresh_interval, refresh_interval),
+			skip_hours     = coalesce(:skip_hours, skip_hours),
+			failure_count  = coalesce(:failure_count, failure_count),
+			first_failure  = case when :update_first_failure then :first_failure else first_failure end,
+			disabled       = coalesce(:disabled, disabled)
 	`,
 		sql.Named("id", feedID),
 		sql.Named("last_refreshed", params.LastRefreshed),
 		sql.Named("last_error", params.LastError),
 		sql.Named("http_lmod", params.HTTPLastModified),
 		sql.Named("http_etag", params.HTTPEtag),
+		sql.Named("next_refresh", params.NextRefresh),
+		sql.Named("refresh_interval", interval),
+		sql.Named("skip_hours", hours),
+		sql.Named("failure_count", params.FailureCount),
+		sql.Named("update_first_failure", params.FirstFailure.Set),
+		sql.Named("first_failure", params.FirstFailure.Value),
+		sql.Named("disabled", params.Disabled),
+		sql.Named("user_id", s.userID),
 	)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
+
+func (s *SQLiteStorage) CreateFeedLinkChange(feedID int64, change model.FeedLinkChange) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into feed_link_history (feed_id, old_link, new_link, status_code, created_at)
+		select :id, :old_link, :new_link, :status_code, :created_at
+		where exists (select 1 from feeds where id = :id and `+userScope+`)
+	`,
+		sql.Named("id", feedID),
+		sql.Named("old_link", change.OldLink),
+		sql.Named("new_link", change.NewLink),
+		sql.Named("status_code", change.StatusCode),
+		sql.Named("created_at", change.CreatedAt),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) ListFeedLinkChanges(feedID int64) ([]model.FeedLinkChange, error) {
+	rows, err := s.db.Query(`
+		select old_link, new_link, status_code, created_at
+		from feed_link_history
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+		order by id
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]model.FeedLinkChange, 0)
+	for rows.Next() {
+		var change model.FeedLinkChange
+		err := rows.Scan(&change.OldLink, &change.NewLink, &change.StatusCode, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -10,9 +10,10 @@ import (
 func (s *SQLiteStorage) CreateFolder(title string) *model.Folder {
 	expanded := true
 	row := s.db.QueryRow(`
-		insert into folders (title, is_expanded) values (:title, :is_expanded)
-		on conflict (title) do update set title = :title
+		insert into folders (user_id, title, is_expanded) values (:user_id, :title, :is_expanded)
+		on conflict (user_id, title) do update set title = :title
         returning id`,
+		sql.Named("user_id", s.userID),
 		sql.Named("title", title),
 		sql.Named("is_expanded", expanded),
 	)
@@ -27,7 +28,11 @@ func (s *SQLiteStorage) CreateFolder(title string) *model.Folder {
 }
 
 func (s *SQLiteStorage) DeleteFolder(folderId int64) bool {
-	_, err := s.db.Exec(`delete from folders where id = :id`, sql.Named("id", folderId))
+	_, err := s.db.Exec(
+		`delete from folders where id = :id and `+userScope,
+		sql.Named("id", folderId),
+		sql.Named("user_id", s.userID),
+	)
 	if err != nil {
 		log.Print(err)
 	}
@@ -39,9 +44,9 @@ func (s *SQLiteStorage) UpdateFolder(folderId int64, params model.UpdateFolderPa
 		update folders set
 			title       = coalesce(:title, title),
 			is_expanded = coalesce(:is_expanded, is_expanded)
-		where id = :id
-	`,
+		where id = :id and `+userScope,
 		sql.Named("id", folderId),
+		sql.Named("user_id", s.userID),
 		sql.Named("title", params.Title),
 		sql.Named("is_expanded", params.IsExpanded),
 	)
@@ -57,8 +62,9 @@ func (s *SQLiteStorage) ListFolders() []model.Folder {
 	rows, err := s.db.Query(`
 		select id, title, is_expanded
 		from folders
+		where `+userScope+`
 		order by title collate nocase
-	`)
+	`, sql.Named("user_id", s.userID))
 	if err != nil {
 		log.Print(err)
 		return result
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/storage/search"
 )
 
 type MediaLinks model.MediaLinks
@@ -31,11 +32,56 @@ func (m MediaLinks) Value() (driver.Value, error) {
 	return json.Marshal(m)
 }
 
-func (s *SQLiteStorage) CreateItems(items []model.Item) bool {
+type Categories []string
+
+func (c *Categories) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	default:
+		return nil
+	}
+}
+
+func (c Categories) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Podcast reads and writes the nullable podcast column of an item.
+type Podcast struct{ dst **model.Podcast }
+
+func (p *Podcast) Scan(src any) error {
+	var data []byte
+	switch x := src.(type) {
+	case []byte:
+		data = x
+	case string:
+		data = []byte(x)
+	default:
+		return nil
+	}
+	return json.Unmarshal(data, p.dst)
+}
+
+func (p Podcast) Value() (driver.Value, error) {
+	if *p.dst == nil {
+		return nil, nil
+	}
+	return json.Marshal(*p.dst)
+}
+
+// CreateItems stores the items, skipping known ones, and returns the new
+// ones with their ids. The boolean is false if the items couldn't be stored.
+func (s *SQLiteStorage) CreateItems(items []model.Item) ([]model.Item, bool) {
 	tx, err := s.db.Begin()
 	if err != nil {
 		log.Print(err)
-		return false
+		return nil, false
 	}
 
 	now := time.Now().UTC()
@@ -46,20 +92,26 @@ func (s *SQLiteStorage) CreateItems(items []model.Item) bool {
 		return cmp.Compare(sa, sb)
 	})
 
+	created := make([]model.Item, 0)
 	for _, item := range items {
-		_, err = tx.Exec(`
+		var id int64
+		var isNew bool
+		err = tx.QueryRow(`
 			insert into items (
 				guid, feed_id, title, link, date,
 				content, media_links,
-				date_arrived, last_arrived, status
+				date_arrived, last_arrived, status,
+				author, categories, comments_url, podcast
 			)
 			values (
 				:guid, :feed_id, :title, :link, strftime('%Y-%m-%d %H:%M:%f', :date),
 				:content, :media_links,
-				:date_arrived, :last_arrived, :status
+				:date_arrived, :last_arrived, :status,
+				:author, :categories, :comments_url, :podcast
 			)
 			on conflict (feed_id, guid) do update set
-				last_arrived = :last_arrived`,
+				last_arrived = :last_arrived
+			returning id, date_arrived = :date_arrived`,
 			sql.Named("guid", item.GUID),
 			sql.Named("feed_id", item.FeedId),
 			sql.Named("title", item.Title),
@@ -70,26 +122,37 @@ func (s *SQLiteStorage) CreateItems(items []model.Item) bool {
 			sql.Named("date_arrived", now),
 			sql.Named("last_arrived", now),
 			sql.Named("status", item.Status),
-		)
+			sql.Named("author", item.Author),
+			sql.Named("categories", Categories(item.Categories)),
+			sql.Named("comments_url", item.CommentsURL),
+			sql.Named("podcast", Podcast{&item.Podcast}),
+		).Scan(&id, &isNew)
 		if err != nil {
 			log.Print(err)
 			if err = tx.Rollback(); err != nil {
 				log.Print(err)
-				return false
+				return nil, false
 			}
-			return false
+			return nil, false
+		}
+		if isNew {
+			item.Id = id
+			created = append(created, item)
 		}
 	}
 	if err = tx.Commit(); err != nil {
 		log.Print(err)
-		return false
+		return nil, false
 	}
-	return true
+	return created, true
 }
 
-func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []any) {
-	cond := make([]string, 0)
-	args := make([]any, 0)
+// userItems restricts a query to the items of the feeds owned by the `:user_id` param.
+const userItems = "i.feed_id in (select id from feeds where " + userScope + ")"
+
+func listQueryPredicate(filter model.ItemFilter, newestFirst bool, userID int64) (string, []any) {
+	cond := []string{userItems}
+	args := []any{sql.Named("user_id", userID)}
 	if filter.FolderID != nil {
 		cond = append(cond, "i.feed_id in (select id from feeds where folder_id = :folder_id)")
 		args = append(args, sql.Named("folder_id", *filter.FolderID))
@@ -98,24 +161,36 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 		cond = append(cond, "i.feed_id = :feed_id")
 		args = append(args, sql.Named("feed_id", *filter.FeedID))
 	}
+	if filter.Label != nil {
+		cond = append(cond, "i.id in (select item_id from item_labels where label_id = :label_id)")
+		args = append(args, sql.Named("label_id", *filter.Label))
+	}
 	if filter.Status != nil {
 		cond = append(cond, "i.status = :status")
 		args = append(args, sql.Named("status", *filter.Status))
 	}
+	if filter.Author != nil {
+		cond = append(cond, "i.author = :author collate nocase")
+		args = append(args, sql.Named("author", *filter.Author))
+	}
+	if filter.Category != nil {
+		cond = append(cond, "exists (select 1 from json_each(i.categories) where value = :category collate nocase)")
+		args = append(args, sql.Named("category", *filter.Category))
+	}
 	if filter.Search != nil {
-		words := strings.Fields(*filter.Search)
-		terms := make([]string, len(words))
-		for idx, word := range words {
-			terms[idx] = word + "*"
+		if node := search.Parse(*filter.Search); node != nil {
+			p := &searchPredicate{}
+			cond = append(cond, p.compile(node))
+			args = append(args, p.args...)
 		}
-
-		cond = append(
-			cond,
-			"i.id in (select rowid as id from search where search match :search)",
-		)
-		args = append(args, sql.Named("search", strings.Join(terms, " ")))
 	}
-	if filter.After != nil {
+	if rank := rankQuery(filter); filter.After != nil && rank != "" {
+		cond = append(cond, fmt.Sprintf(
+			"(%s, -i.id) > (%s, -:after_id)",
+			rankExpr("i.id", ":after_rank"), rankExpr(":after_id", ":after_rank"),
+		))
+		args = append(args, sql.Named("after_id", *filter.After), sql.Named("after_rank", rank))
+	} else if filter.After != nil {
 		compare := ">"
 		if newestFirst {
 			compare = "<"
@@ -150,18 +225,20 @@ func listQueryPredicate(filter model.ItemFilter, newestFirst bool) (string, []an
 		cond = append(cond, "i.date < :before")
 		args = append(args, sql.Named("before", filter.Before))
 	}
-
-	predicate := "1"
-	if len(cond) > 0 {
-		predicate = strings.Join(cond, " and ")
+	if filter.Since != nil {
+		cond = append(cond, "i.date >= strftime('%Y-%m-%d %H:%M:%f', :since)")
+		args = append(args, sql.Named("since", filter.Since))
 	}
 
+	predicate := strings.Join(cond, " and ")
 	return predicate, args
 }
 
-func (s *SQLiteStorage) CountItems() int {
+// CountItems returns the number of items matching the filter.
+func (s *SQLiteStorage) CountItems(filter model.ItemFilter) int {
+	predicate, args := listQueryPredicate(filter, false, s.userID)
 	var count int
-	err := s.db.QueryRow(`select count(*) from items`).Scan(&count)
+	err := s.db.QueryRow(`select count(*) from items i where `+predicate, args...).Scan(&count)
 	if err != nil {
 		log.Print(err)
 		return 0
@@ -175,13 +252,17 @@ func (s *SQLiteStorage) ListItems(
 	newestFirst bool,
 	withContent bool,
 ) []model.Item {
-	predicate, args := listQueryPredicate(filter, newestFirst)
+	predicate, args := listQueryPredicate(filter, newestFirst, s.userID)
 	result := make([]model.Item, 0)
 
 	order := "date desc, id desc"
 	if !newestFirst {
 		order = "date asc, id asc"
 	}
+	if rank := rankQuery(filter); rank != "" {
+		order = rankExpr("i.id", ":rank") + ", i.id desc"
+		args = append(args, sql.Named("rank", rank))
+	}
 	if filter.IDs != nil || filter.SinceID != nil {
 		order = "i.id asc"
 	}
@@ -189,7 +270,7 @@ func (s *SQLiteStorage) ListItems(
 		order = "i.id desc"
 	}
 
-	selectCols := "i.id, i.guid, i.feed_id, i.title, i.link, i.date, i.status, i.media_links"
+	selectCols := "i.id, i.guid, i.feed_id, i.title, i.link, i.date, i.status, i.media_links, i.author, i.categories, i.comments_url, i.podcast"
 	if withContent {
 		selectCols += ", i.content"
 	} else {
@@ -200,8 +281,8 @@ func (s *SQLiteStorage) ListItems(
 		from items i
 		where %s
 		order by %s
-		limit %d
-		`, selectCols, predicate, order, limit)
+		limit %d offset %d
+		`, selectCols, predicate, order, limit, filter.Offset)
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		log.Print(err)
@@ -212,7 +293,9 @@ func (s *SQLiteStorage) ListItems(
 		err = rows.Scan(
 			&x.Id, &x.GUID, &x.FeedId,
 			&x.Title, &x.Link, &x.Date,
-			&x.Status, (*MediaLinks)(&x.MediaLinks), &x.Content,
+			&x.Status, (*MediaLinks)(&x.MediaLinks), &x.Author, (*Categories)(&x.Categories), &x.CommentsURL,
+			&Podcast{&x.Podcast},
+			&x.Content,
 		)
 		if err != nil {
 			log.Print(err)
@@ -228,12 +311,16 @@ func (s *SQLiteStorage) GetItem(id int64) *model.Item {
 	err := s.db.QueryRow(`
 		select
 			i.id, i.guid, i.feed_id, i.title, i.link, i.content,
-			i.date, i.status, i.media_links
+			i.date, i.status, i.media_links, i.full_content,
+			i.author, i.categories, i.comments_url, i.podcast
 		from items i
-		where i.id = :id
-	`, sql.Named("id", id)).Scan(
+		where i.id = :id and `+userItems,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	).Scan(
 		&i.Id, &i.GUID, &i.FeedId, &i.Title, &i.Link, &i.Content,
-		&i.Date, &i.Status, (*MediaLinks)(&i.MediaLinks),
+		&i.Date, &i.Status, (*MediaLinks)(&i.MediaLinks), &i.FullContent,
+		&i.Author, (*Categories)(&i.Categories), &i.CommentsURL, &Podcast{&i.Podcast},
 	)
 	if err != nil {
 		log.Print(err)
@@ -242,6 +329,45 @@ func (s *SQLiteStorage) GetItem(id int64) *model.Item {
 	return i
 }
 
+// ListItemFullContents returns the full content of the given items,
+// which is left out of the item lists. Items without one are skipped.
+func (s *SQLiteStorage) ListItemFullContents(itemIDs []int64) map[int64]string {
+	result := make(map[int64]string)
+	if len(itemIDs) == 0 {
+		return result
+	}
+
+	args := []any{sql.Named("user_id", s.userID)}
+	qmarks := make([]string, len(itemIDs))
+	for i, id := range itemIDs {
+		name := fmt.Sprintf("id%d", i)
+		qmarks[i] = ":" + name
+		args = append(args, sql.Named(name, id))
+	}
+	rows, err := s.db.Query(`
+		select i.id, i.full_content
+		from items i
+		where i.id in (`+strings.Join(qmarks, ",")+`) and i.full_content != '' and `+userItems,
+		args...,
+	)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var content string
+		if err = rows.Scan(&id, &content); err != nil {
+			log.Print(err)
+			return result
+		}
+		result[id] = content
+	}
+	return result
+}
+
 func (s *SQLiteStorage) UpdateItem(id int64, params model.UpdateItemParams) bool {
 	sets := make([]string, 0)
 	args := make([]any, 0)
@@ -257,24 +383,36 @@ func (s *SQLiteStorage) UpdateItem(id int64, params model.UpdateItemParams) bool
 		sets = append(sets, "last_arrived = :last_arrived")
 		args = append(args, sql.Named("last_arrived", *params.LastArrived))
 	}
+	if params.FullContent != nil {
+		sets = append(sets, "full_content = :full_content")
+		args = append(args, sql.Named("full_content", *params.FullContent))
+	}
 	if len(sets) == 0 {
 		return true
 	}
-	args = append(args, sql.Named("id", id))
-	query := fmt.Sprintf("update items set %s where id = :id", strings.Join(sets, ", "))
+	args = append(args, sql.Named("id", id), sql.Named("user_id", s.userID))
+	query := fmt.Sprintf(
+		"update items as i set %s where i.id = :id and %s",
+		strings.Join(sets, ", "), userItems,
+	)
 	_, err := s.db.Exec(query, args...)
 	return err == nil
 }
 
 func (s *SQLiteStorage) DeleteItem(id int64) bool {
-	_, err := s.db.Exec(`delete from items where id = :id`, sql.Named("id", id))
+	_, err := s.db.Exec(
+		`delete from items as i where i.id = :id and `+userItems,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
 	return err == nil
 }
 
 func (s *SQLiteStorage) UpdateItemStatus(item_id int64, status model.ItemStatus) bool {
-	_, err := s.db.Exec(`update items set status = :status where id = :id`,
+	_, err := s.db.Exec(`update items as i set status = :status where i.id = :id and `+userItems,
 		sql.Named("status", status),
 		sql.Named("id", item_id),
+		sql.Named("user_id", s.userID),
 	)
 	return err == nil
 }
@@ -283,8 +421,10 @@ func (s *SQLiteStorage) MarkItemsRead(filter model.MarkFilter) bool {
 	predicate, args := listQueryPredicate(model.ItemFilter{
 		FolderID: filter.FolderID,
 		FeedID:   filter.FeedID,
+		Label:    filter.Label,
 		Before:   filter.Before,
-	}, false)
+		MaxID:    filter.MaxID,
+	}, false, s.userID)
 	query := fmt.Sprintf(`
 		update items as i set status = %d
 		where %s and i.status != %d
@@ -303,9 +443,10 @@ func (s *SQLiteStorage) FeedStats() []model.FeedStat {
 			feed_id,
 			sum(case status when %d then 1 else 0 end),
 			sum(case status when %d then 1 else 0 end)
-		from items
+		from items i
+		where %s
 		group by feed_id
-	`, model.UNREAD, model.STARRED))
+	`, model.UNREAD, model.STARRED, userItems), sql.Named("user_id", s.userID))
 	if err != nil {
 		log.Print(err)
 		return result
@@ -326,7 +467,7 @@ var (
 // Delete old articles from the database to cleanup space.
 //
 // The rules:
-//   - Never delete starred entries.
+//   - Never delete starred or labelled entries.
 //   - Keep at least 50 latest items for each feed.
 //   - Delete entries older than 90 days relative to the latest arrived item in the same feed.
 func (s *SQLiteStorage) DeleteOldItems() {
@@ -342,6 +483,7 @@ func (s *SQLiteStorage) DeleteOldItems() {
 					max(last_arrived) over (partition by feed_id) as max_la
 				from items
 				where status != :starred_status
+				  and id not in (select item_id from item_labels)
 			)
 			where rn > :keep_size
 			  and last_arrived < datetime(max_la, :keep_days_limit)
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// userLabels restricts a query to the labels owned by the `:user_id` param.
+const userLabels = "label_id in (select id from labels where " + userScope + ")"
+
+func (s *SQLiteStorage) CreateLabel(title string) *model.Label {
+	row := s.db.QueryRow(`
+		insert into labels (user_id, title) values (:user_id, :title)
+		on conflict (user_id, title) do update set title = :title
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("title", title),
+	)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		log.Print(err)
+		return nil
+	}
+	return &model.Label{Id: id, Title: title}
+}
+
+func (s *SQLiteStorage) DeleteLabel(id int64) bool {
+	_, err := s.db.Exec(
+		`delete from labels where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+	}
+	return err == nil
+}
+
+func (s *SQLiteStorage) UpdateLabel(id int64, params model.UpdateLabelParams) (bool, error) {
+	_, err := s.db.Exec(`
+		update labels set title = coalesce(:title, title)
+		where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+		sql.Named("title", params.Title),
+	)
+	if err != nil {
+		log.Print(err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SQLiteStorage) ListLabels() []model.Label {
+	result := make([]model.Label, 0)
+	rows, err := s.db.Query(`
+		select id, title
+		from labels
+		where `+userScope+`
+		order by title collate nocase
+	`, sql.Named("user_id", s.userID))
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l model.Label
+		if err = rows.Scan(&l.Id, &l.Title); err != nil {
+			log.Print(err)
+			return result
+		}
+		result = append(result, l)
+	}
+	return result
+}
+
+// AddItemLabel attaches the label to the item. Both must belong to the same user.
+func (s *SQLiteStorage) AddItemLabel(itemID, labelID int64) bool {
+	result, err := s.db.Exec(`
+		insert into item_labels (item_id, label_id)
+		select i.id, l.id
+		from items i
+		join feeds f on f.id = i.feed_id
+		join labels l on l.user_id = f.user_id
+		where i.id = :item_id and l.id = :label_id
+		  and (:user_id = 0 or l.user_id = :user_id)
+		on conflict (item_id, label_id) do update set label_id = excluded.label_id`,
+		sql.Named("item_id", itemID),
+		sql.Named("label_id", labelID),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *SQLiteStorage) RemoveItemLabel(itemID, labelID int64) bool {
+	result, err := s.db.Exec(
+		`delete from item_labels where item_id = :item_id and label_id = :label_id and `+userLabels,
+		sql.Named("item_id", itemID),
+		sql.Named("label_id", labelID),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// ListItemLabels returns the label ids of the given items.
+// If itemIDs is nil, the labels of all the labelled items are returned.
+func (s *SQLiteStorage) ListItemLabels(itemIDs []int64) map[int64][]int64 {
+	result := make(map[int64][]int64)
+	if itemIDs != nil && len(itemIDs) == 0 {
+		return result
+	}
+
+	cond := userLabels
+	args := []any{sql.Named("user_id", s.userID)}
+	if itemIDs != nil {
+		qmarks := make([]string, len(itemIDs))
+		for i, id := range itemIDs {
+			name := fmt.Sprintf("id%d", i)
+			qmarks[i] = ":" + name
+			args = append(args, sql.Named(name, id))
+		}
+		cond += " and item_id in (" + strings.Join(qmarks, ",") + ")"
+	}
+	rows, err := s.db.Query(`
+		select item_id, label_id
+		from item_labels
+		where `+cond+`
+		order by item_id, label_id
+	`, args...)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID, labelID int64
+		if err = rows.Scan(&itemID, &labelID); err != nil {
+			log.Print(err)
+			return result
+		}
+		result[itemID] = append(result[itemID], labelID)
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// userMedia restricts a query to the media files of the items owned by the `:user_id` param.
+const userMedia = "item_id in (select i.id from items i where " + userItems + ")"
+
+// CreateMediaFile records a downloaded enclosure, replacing
+// the previous copy of the same url, if any.
+func (s *SQLiteStorage) CreateMediaFile(file model.MediaFile) (*model.MediaFile, error) {
+	file.CreatedAt = time.Now().UTC()
+	err := s.db.QueryRow(`
+		insert into media_files (item_id, url, path, mime_type, size, created_at)
+		select :item_id, :url, :path, :mime_type, :size, :created_at
+		where exists (select 1 from items i where i.id = :item_id and `+userItems+`)
+		on conflict (item_id, url) do update set
+			path       = :path,
+			mime_type  = :mime_type,
+			size       = :size,
+			created_at = :created_at
+		returning id`,
+		sql.Named("item_id", file.ItemID),
+		sql.Named("url", file.URL),
+		sql.Named("path", file.Path),
+		sql.Named("mime_type", file.MimeType),
+		sql.Named("size", file.Size),
+		sql.Named("created_at", file.CreatedAt),
+		sql.Named("user_id", s.userID),
+	).Scan(&file.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (s *SQLiteStorage) DeleteMediaFile(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from media_files where id = :id and `+userMedia,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *SQLiteStorage) GetMediaFile(id int64) (*model.MediaFile, error) {
+	var x model.MediaFile
+	err := s.db.QueryRow(`
+		select id, item_id, url, path, mime_type, size, created_at
+		from media_files
+		where id = :id and `+userMedia,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	).Scan(&x.Id, &x.ItemID, &x.URL, &x.Path, &x.MimeType, &x.Size, &x.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// ListMediaFiles returns the media files of the given items, oldest first.
+// If itemIDs is nil, all the media files are returned.
+func (s *SQLiteStorage) ListMediaFiles(itemIDs []int64) ([]model.MediaFile, error) {
+	result := make([]model.MediaFile, 0)
+	if itemIDs != nil && len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	cond := userMedia
+	args := []any{sql.Named("user_id", s.userID)}
+	if itemIDs != nil {
+		qmarks := make([]string, len(itemIDs))
+		for i, id := range itemIDs {
+			name := fmt.Sprintf("id%d", i)
+			qmarks[i] = ":" + name
+			args = append(args, sql.Named(name, id))
+		}
+		cond += " and item_id in (" + strings.Join(qmarks, ",") + ")"
+	}
+	rows, err := s.db.Query(`
+		select id, item_id, url, path, mime_type, size, created_at
+		from media_files
+		where `+cond+`
+		order by created_at, id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var x model.MediaFile
+		if err := rows.Scan(&x.Id, &x.ItemID, &x.URL, &x.Path, &x.MimeType, &x.Size, &x.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, x)
+	}
+	return result, rows.Err()
+}
@@ -23,6 +23,24 @@ var migrations = []func(*sql.Tx) error{
 	m13_consolidate_feed_states,
 	m14_upgrade_fts5,
 	m15_update_item_update_trigger,
+	m16_add_api_keys,
+	m17_add_users,
+	m18_add_api_key_scopes,
+	m19_add_labels,
+	m20_add_rules,
+	m21_add_feed_schedule,
+	m22_add_feed_failures,
+	m23_add_feed_link_history,
+	m24_add_websub_subscriptions,
+	m25_add_webhooks,
+	m26_add_output_feeds,
+	m27_add_full_content,
+	m28_add_feed_scrapers,
+	m29_add_item_authors,
+	m30_add_item_podcast,
+	m31_add_media_files,
+	m32_add_saved_searches,
+	m33_add_user_foreign_keys,
 }
 
 var maxVersion = int64(len(migrations))
@@ -44,7 +62,7 @@ func migrate(db *sql.DB) error {
 		// Must come with `pragma foreign_key_check` at the end. See:
 		// "Making Other Kinds Of Table Schema Changes"
 		// https://www.sqlite.org/lang_altertable.html
-		trickyAlteration := (v == 3)
+		trickyAlteration := (v == 3 || v == 33)
 
 		log.Printf("[migration:%d] starting", v)
 
@@ -432,3 +450,397 @@ func m15_update_item_update_trigger(tx *sql.Tx) error {
 	`)
 	return err
 }
+
+func m16_add_api_keys(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table api_keys (
+			id             integer primary key autoincrement,
+			name           text not null,
+			token_hash     text not null unique,
+			created_at     datetime not null,
+			last_used_at   datetime
+		);
+	`)
+	return err
+}
+
+func m17_add_users(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table users (
+			id             integer primary key autoincrement,
+			username       text not null unique,
+			password_hash  text not null default '',
+			fever_key      text not null default '',
+			is_admin       boolean not null default false,
+			created_at     datetime not null
+		);
+
+		-- the existing data belongs to the default user
+		insert into users (id, username, is_admin, created_at)
+		values (1, 'admin', true, datetime('now'));
+
+		alter table folders add column user_id integer not null default 1;
+		drop index idx_folder_title;
+		create unique index idx_folder_title on folders(user_id, title);
+
+		alter table feeds add column user_id integer not null default 1;
+		drop index idx_feed_feed_link;
+		create unique index idx_feed_feed_link on feeds(user_id, feed_link);
+		create index idx_feed_user_id on feeds(user_id);
+
+		create table new_settings (
+			user_id        integer not null default 1,
+			key            string not null,
+			val            blob,
+			primary key (user_id, key)
+		);
+		insert into new_settings (key, val) select key, val from settings;
+		drop table settings;
+		alter table new_settings rename to settings;
+
+		alter table api_keys add column user_id integer not null default 1;
+	`)
+	return err
+}
+
+func m18_add_api_key_scopes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table api_keys add column read_only boolean not null default false;
+		alter table api_keys add column expires_at datetime;
+	`)
+	return err
+}
+
+func m19_add_labels(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table labels (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1,
+			title          text not null
+		);
+		create unique index idx_label_title on labels(user_id, title);
+
+		create table item_labels (
+			item_id        integer not null references items(id) on delete cascade,
+			label_id       integer not null references labels(id) on delete cascade,
+			primary key (item_id, label_id)
+		);
+		create index idx_item_labels_label_id on item_labels(label_id);
+	`)
+	return err
+}
+
+func m20_add_rules(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table rules (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1,
+			title          text not null default '',
+			feed_id        integer references feeds(id) on delete cascade,
+			conditions     json not null default '[]',
+			action         text not null,
+			is_enabled     boolean not null default true
+		);
+		create index idx_rule_user_id on rules(user_id);
+	`)
+	return err
+}
+
+func m21_add_feed_schedule(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column refresh_interval integer;
+		alter table feed_states add column next_refresh datetime;
+		alter table feed_states add column refresh_interval integer not null default 0;
+		alter table feed_states add column skip_hours text not null default '[]';
+	`)
+	return err
+}
+
+func m22_add_feed_failures(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feed_states add column failure_count integer not null default 0;
+		alter table feed_states add column first_failure datetime;
+		alter table feed_states add column disabled boolean not null default false;
+	`)
+	return err
+}
+
+func m23_add_feed_link_history(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table feed_link_history (
+			id             integer primary key autoincrement,
+			feed_id        integer not null references feeds(id) on delete cascade,
+			old_link       text not null,
+			new_link       text not null,
+			status_code    integer not null,
+			created_at     datetime not null
+		);
+		create index idx_feed_link_history_feed_id on feed_link_history(feed_id);
+	`)
+	return err
+}
+
+func m24_add_websub_subscriptions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table websub_subscriptions (
+			feed_id        integer primary key references feeds(id) on delete cascade,
+			hub            text not null,
+			topic          text not null,
+			secret         text not null,
+			requested_at   datetime not null,
+			expires_at     datetime
+		);
+	`)
+	return err
+}
+
+func m25_add_webhooks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table webhooks (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1,
+			title          text not null default '',
+			url            text not null,
+			folder_id      integer references folders(id) on delete cascade,
+			feed_id        integer references feeds(id) on delete cascade,
+			keyword        text not null default '',
+			format         text not null default 'json',
+			template       text not null default '',
+			secret         text not null default '',
+			is_enabled     boolean not null default true
+		);
+		create index idx_webhook_user_id on webhooks(user_id);
+
+		create table webhook_deliveries (
+			id             integer primary key autoincrement,
+			webhook_id     integer not null references webhooks(id) on delete cascade,
+			item_id        integer references items(id) on delete set null,
+			attempt        integer not null,
+			status_code    integer not null default 0,
+			error          text not null default '',
+			created_at     datetime not null
+		);
+		create index idx_webhook_delivery_webhook_id on webhook_deliveries(webhook_id);
+	`)
+	return err
+}
+
+func m26_add_output_feeds(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table output_feeds (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1,
+			source         text not null,
+			folder_id      integer references folders(id) on delete cascade,
+			label_id       integer references labels(id) on delete cascade,
+			token          text not null unique,
+			created_at     datetime not null
+		);
+		create index idx_output_feed_user_id on output_feeds(user_id);
+	`)
+	return err
+}
+
+func m27_add_full_content(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column fetch_content boolean not null default false;
+		alter table items add column full_content text not null default '';
+	`)
+	return err
+}
+
+func m28_add_feed_scrapers(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table feed_scrapers (
+			feed_id          integer primary key references feeds(id) on delete cascade,
+			item_selector    text not null,
+			title_selector   text not null default '',
+			link_selector    text not null default '',
+			date_selector    text not null default '',
+			content_selector text not null default ''
+		);
+	`)
+	return err
+}
+
+func m29_add_item_authors(tx *sql.Tx) error {
+	// the author column exists since the initial schema, but was never filled
+	_, err := tx.Exec(`
+		update items set author = '' where author is null;
+		alter table items add column categories json;
+		alter table items add column comments_url text not null default '';
+	`)
+	return err
+}
+
+func m30_add_item_podcast(tx *sql.Tx) error {
+	_, err := tx.Exec(`alter table items add column podcast json;`)
+	return err
+}
+
+func m31_add_media_files(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		alter table feeds add column download_media boolean not null default false;
+
+		create table media_files (
+			id         integer primary key autoincrement,
+			item_id    integer not null references items(id) on delete cascade,
+			url        text not null,
+			path       text not null,
+			mime_type  text not null default '',
+			size       integer not null default 0,
+			created_at datetime not null,
+			unique(item_id, url)
+		);
+	`)
+	return err
+}
+
+func m32_add_saved_searches(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		create table saved_searches (
+			id       integer primary key autoincrement,
+			user_id  integer not null default 1,
+			name     text not null,
+			query    text not null,
+			status   integer
+		);
+		create index idx_saved_search_user_id on saved_searches(user_id);
+	`)
+	return err
+}
+
+func m33_add_user_foreign_keys(tx *sql.Tx) error {
+	// the user_id columns were added without a reference to the users,
+	// which SQLite can only introduce by rebuilding the tables
+	sql := `
+		-- 01. create altered tables
+		create table new_folders (
+			id             integer primary key autoincrement,
+			title          text not null,
+			is_expanded    boolean not null default false,
+			user_id        integer not null default 1 references users(id) on delete cascade
+		);
+		create table new_feeds (
+			id               integer primary key autoincrement,
+			folder_id        references folders(id) on delete set null,
+			title            text not null,
+			description      text,
+			link             text,
+			feed_link        text not null,
+			icon             blob,
+			user_id          integer not null default 1 references users(id) on delete cascade,
+			refresh_interval integer,
+			fetch_content    boolean not null default false,
+			download_media   boolean not null default false
+		);
+		create table new_settings (
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			key            string not null,
+			val            blob,
+			primary key (user_id, key)
+		);
+		create table new_api_keys (
+			id             integer primary key autoincrement,
+			name           text not null,
+			token_hash     text not null unique,
+			created_at     datetime not null,
+			last_used_at   datetime,
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			read_only      boolean not null default false,
+			expires_at     datetime
+		);
+		create table new_labels (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			title          text not null
+		);
+		create table new_rules (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			title          text not null default '',
+			feed_id        integer references feeds(id) on delete cascade,
+			conditions     json not null default '[]',
+			action         text not null,
+			is_enabled     boolean not null default true
+		);
+		create table new_webhooks (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			title          text not null default '',
+			url            text not null,
+			folder_id      integer references folders(id) on delete cascade,
+			feed_id        integer references feeds(id) on delete cascade,
+			keyword        text not null default '',
+			format         text not null default 'json',
+			template       text not null default '',
+			secret         text not null default '',
+			is_enabled     boolean not null default true
+		);
+		create table new_output_feeds (
+			id             integer primary key autoincrement,
+			user_id        integer not null default 1 references users(id) on delete cascade,
+			source         text not null,
+			folder_id      integer references folders(id) on delete cascade,
+			label_id       integer references labels(id) on delete cascade,
+			token          text not null unique,
+			created_at     datetime not null
+		);
+		create table new_saved_searches (
+			id       integer primary key autoincrement,
+			user_id  integer not null default 1 references users(id) on delete cascade,
+			name     text not null,
+			query    text not null,
+			status   integer
+		);
+
+		-- 02. transfer data into new tables
+		insert into new_folders select * from folders;
+		insert into new_feeds select * from feeds;
+		insert into new_settings select * from settings;
+		insert into new_api_keys select * from api_keys;
+		insert into new_labels select * from labels;
+		insert into new_rules select * from rules;
+		insert into new_webhooks select * from webhooks;
+		insert into new_output_feeds select * from output_feeds;
+		insert into new_saved_searches select * from saved_searches;
+
+		-- 03. drop old tables
+		drop table folders;
+		drop table feeds;
+		drop table settings;
+		drop table api_keys;
+		drop table labels;
+		drop table rules;
+		drop table webhooks;
+		drop table output_feeds;
+		drop table saved_searches;
+
+		-- 04. rename new tables
+		alter table new_folders rename to folders;
+		alter table new_feeds rename to feeds;
+		alter table new_settings rename to settings;
+		alter table new_api_keys rename to api_keys;
+		alter table new_labels rename to labels;
+		alter table new_rules rename to rules;
+		alter table new_webhooks rename to webhooks;
+		alter table new_output_feeds rename to output_feeds;
+		alter table new_saved_searches rename to saved_searches;
+
+		-- 05. reconstruct indexes
+		create unique index idx_folder_title on folders(user_id, title);
+		create index idx_feed_folder_id on feeds(folder_id);
+		create unique index idx_feed_feed_link on feeds(user_id, feed_link);
+		create index idx_feed_user_id on feeds(user_id);
+		create unique index idx_label_title on labels(user_id, title);
+		create index idx_rule_user_id on rules(user_id);
+		create index idx_webhook_user_id on webhooks(user_id);
+		create index idx_output_feed_user_id on output_feeds(user_id);
+		create index idx_saved_search_user_id on saved_searches(user_id);
+
+		-- 06. check consistency
+		pragma foreign_key_check;
+	`
+	_, err := tx.Exec(sql)
+	return err
+}
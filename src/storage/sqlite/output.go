@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+var errOutputSourceNotFound = errors.New("folder or label not found")
+
+const outputFeedColumns = "id, user_id, source, coalesce(folder_id, label_id), token, created_at"
+
+func scanOutputFeed(row interface{ Scan(...any) error }) (model.OutputFeed, error) {
+	var feed model.OutputFeed
+	err := row.Scan(&feed.Id, &feed.UserId, &feed.Source, &feed.SourceID, &feed.Token, &feed.CreatedAt)
+	return feed, err
+}
+
+// CreateOutputFeed fails if the source is a folder or label of another user.
+func (s *SQLiteStorage) CreateOutputFeed(feed model.OutputFeed) (*model.OutputFeed, error) {
+	var folderID, labelID *int64
+	switch feed.Source {
+	case model.OutputFolder:
+		folderID = feed.SourceID
+	case model.OutputLabel:
+		labelID = feed.SourceID
+	default:
+		feed.SourceID = nil
+	}
+	feed.UserId = s.userID
+	feed.CreatedAt = time.Now().UTC()
+	err := s.db.QueryRow(`
+		insert into output_feeds (user_id, source, folder_id, label_id, token, created_at)
+		select :user_id, :source, :folder_id, :label_id, :token, :created_at
+		where (:folder_id is null or :folder_id in (select id from folders where `+userScope+`))
+		  and (:label_id is null or :label_id in (select id from labels where `+userScope+`))
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("source", feed.Source),
+		sql.Named("folder_id", folderID),
+		sql.Named("label_id", labelID),
+		sql.Named("token", feed.Token),
+		sql.Named("created_at", feed.CreatedAt),
+	).Scan(&feed.Id)
+	if err == sql.ErrNoRows {
+		return nil, errOutputSourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (s *SQLiteStorage) DeleteOutputFeed(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from output_feeds where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+func (s *SQLiteStorage) ListOutputFeeds() ([]model.OutputFeed, error) {
+	rows, err := s.db.Query(
+		`select `+outputFeedColumns+` from output_feeds where `+userScope+` order by id`,
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make([]model.OutputFeed, 0)
+	for rows.Next() {
+		feed, err := scanOutputFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+// GetOutputFeedByToken looks up the output feed of any user.
+// Returns nil if no feed matches.
+func (s *SQLiteStorage) GetOutputFeedByToken(token string) (*model.OutputFeed, error) {
+	feed, err := scanOutputFeed(s.db.QueryRow(
+		`select `+outputFeedColumns+` from output_feeds where token = :token`,
+		sql.Named("token", token),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
@@ -0,0 +1,171 @@
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+type RuleConditions model.RuleConditions
+
+func (c *RuleConditions) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	default:
+		return nil
+	}
+}
+
+func (c RuleConditions) Value() (driver.Value, error) {
+	if c == nil {
+		c = RuleConditions{}
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+var errRuleFeedNotFound = errors.New("feed not found")
+
+const ruleColumns = "id, title, feed_id, conditions, action, is_enabled"
+
+func scanRule(row interface{ Scan(...any) error }) (model.Rule, error) {
+	var rule model.Rule
+	err := row.Scan(
+		&rule.Id, &rule.Title, &rule.FeedID,
+		(*RuleConditions)(&rule.Conditions), &rule.Action, &rule.IsEnabled,
+	)
+	return rule, err
+}
+
+// CreateRule fails if the rule refers to a feed of another user.
+func (s *SQLiteStorage) CreateRule(rule model.Rule) (*model.Rule, error) {
+	err := s.db.QueryRow(`
+		insert into rules (user_id, title, feed_id, conditions, action, is_enabled)
+		select :user_id, :title, :feed_id, :conditions, :action, :is_enabled
+		where :feed_id is null or :feed_id in (select id from feeds where `+userScope+`)
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("title", rule.Title),
+		sql.Named("feed_id", rule.FeedID),
+		sql.Named("conditions", RuleConditions(rule.Conditions)),
+		sql.Named("action", rule.Action),
+		sql.Named("is_enabled", rule.IsEnabled),
+	).Scan(&rule.Id)
+	if err == sql.ErrNoRows {
+		return nil, errRuleFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule replaces all the fields of the rule.
+func (s *SQLiteStorage) UpdateRule(id int64, rule model.Rule) (bool, error) {
+	result, err := s.db.Exec(`
+		update rules set
+			title      = :title,
+			feed_id    = :feed_id,
+			conditions = :conditions,
+			action     = :action,
+			is_enabled = :is_enabled
+		where id = :id and `+userScope+`
+		  and (:feed_id is null or :feed_id in (select id from feeds where `+userScope+`))`,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+		sql.Named("title", rule.Title),
+		sql.Named("feed_id", rule.FeedID),
+		sql.Named("conditions", RuleConditions(rule.Conditions)),
+		sql.Named("action", rule.Action),
+		sql.Named("is_enabled", rule.IsEnabled),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) DeleteRule(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from rules where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// GetRule returns nil if the rule doesn't exist.
+func (s *SQLiteStorage) GetRule(id int64) (*model.Rule, error) {
+	rule, err := scanRule(s.db.QueryRow(
+		`select `+ruleColumns+` from rules where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *SQLiteStorage) ListRules() ([]model.Rule, error) {
+	return s.listRules(
+		`select `+ruleColumns+` from rules where `+userScope+` order by id`,
+		sql.Named("user_id", s.userID),
+	)
+}
+
+// ListFeedRules returns the enabled rules applying to the feed,
+// i.e. the ones of the feed owner targeting either the feed or all feeds.
+func (s *SQLiteStorage) ListFeedRules(feedID int64) ([]model.Rule, error) {
+	return s.listRules(`
+		select `+ruleColumns+`
+		from rules
+		where is_enabled
+		  and user_id = (select user_id from feeds where id = :feed_id and `+userScope+`)
+		  and (feed_id is null or feed_id = :feed_id)
+		order by id`,
+		sql.Named("feed_id", feedID),
+		sql.Named("user_id", s.userID),
+	)
+}
+
+func (s *SQLiteStorage) listRules(query string, args ...any) ([]model.Rule, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]model.Rule, 0)
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
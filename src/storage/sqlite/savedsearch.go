@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+const savedSearchColumns = "id, name, query, status"
+
+func scanSavedSearch(row interface{ Scan(...any) error }) (model.SavedSearch, error) {
+	var search model.SavedSearch
+	err := row.Scan(&search.Id, &search.Name, &search.Query, &search.Status)
+	return search, err
+}
+
+func (s *SQLiteStorage) CreateSavedSearch(search model.SavedSearch) (*model.SavedSearch, error) {
+	err := s.db.QueryRow(`
+		insert into saved_searches (user_id, name, query, status)
+		values (:user_id, :name, :query, :status)
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("name", search.Name),
+		sql.Named("query", search.Query),
+		sql.Named("status", search.Status),
+	).Scan(&search.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// UpdateSavedSearch replaces all the fields of the saved search.
+func (s *SQLiteStorage) UpdateSavedSearch(id int64, search model.SavedSearch) (bool, error) {
+	result, err := s.db.Exec(`
+		update saved_searches set
+			name   = :name,
+			query  = :query,
+			status = :status
+		where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+		sql.Named("name", search.Name),
+		sql.Named("query", search.Query),
+		sql.Named("status", search.Status),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) DeleteSavedSearch(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from saved_searches where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// GetSavedSearch returns nil if the saved search doesn't exist.
+func (s *SQLiteStorage) GetSavedSearch(id int64) (*model.SavedSearch, error) {
+	search, err := scanSavedSearch(s.db.QueryRow(
+		`select `+savedSearchColumns+` from saved_searches where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (s *SQLiteStorage) ListSavedSearches() ([]model.SavedSearch, error) {
+	rows, err := s.db.Query(
+		`select `+savedSearchColumns+` from saved_searches where `+userScope+` order by name collate nocase, id`,
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.SavedSearch, 0)
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, search)
+	}
+	return result, rows.Err()
+}
+
+// SavedSearchStats counts the unread and starred items
+// matching each of the saved searches.
+func (s *SQLiteStorage) SavedSearchStats() ([]model.SavedSearchStat, error) {
+	searches, err := s.ListSavedSearches()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.SavedSearchStat, 0, len(searches))
+	for _, search := range searches {
+		predicate, args := listQueryPredicate(model.ItemFilter{
+			Search: &search.Query,
+			Status: search.Status,
+		}, false, s.userID)
+		stat := model.SavedSearchStat{SearchID: search.Id}
+		err := s.db.QueryRow(fmt.Sprintf(`
+			select
+				coalesce(sum(case status when %d then 1 else 0 end), 0),
+				coalesce(sum(case status when %d then 1 else 0 end), 0)
+			from items i
+			where %s
+		`, model.UNREAD, model.STARRED, predicate), args...).Scan(&stat.UnreadCount, &stat.StarredCount)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, stat)
+	}
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *SQLiteStorage) GetFeedScraper(feedID int64) (*model.FeedScraper, error) {
+	var x model.FeedScraper
+	err := s.db.QueryRow(`
+		select feed_id, item_selector, title_selector, link_selector, date_selector, content_selector
+		from feed_scrapers
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID)).Scan(
+		&x.FeedID, &x.Item, &x.Title, &x.Link, &x.Date, &x.Content,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// UpdateFeedScraper creates or replaces the scraper of the feed.
+func (s *SQLiteStorage) UpdateFeedScraper(x model.FeedScraper) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into feed_scrapers (feed_id, item_selector, title_selector, link_selector, date_selector, content_selector)
+		select :id, :item, :title, :link, :date, :content
+		where exists (select 1 from feeds where id = :id and `+userScope+`)
+		on conflict (feed_id) do update set
+			item_selector    = :item,
+			title_selector   = :title,
+			link_selector    = :link,
+			date_selector    = :date,
+			content_selector = :content
+	`,
+		sql.Named("id", x.FeedID),
+		sql.Named("item", x.Item),
+		sql.Named("title", x.Title),
+		sql.Named("link", x.Link),
+		sql.Named("date", x.Date),
+		sql.Named("content", x.Content),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) DeleteFeedScraper(feedID int64) bool {
+	result, err := s.db.Exec(`
+		delete from feed_scrapers
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID))
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+	"github.com/nkanaev/yarr/src/storage/search"
+)
+
+// searchPredicate compiles the search query, with its params named `q<n>`.
+type searchPredicate struct {
+	args []any
+}
+
+func (p *searchPredicate) param(value any) string {
+	name := fmt.Sprintf("q%d", len(p.args)+1)
+	p.args = append(p.args, sql.Named(name, value))
+	return ":" + name
+}
+
+func (p *searchPredicate) compile(node search.Node) string {
+	switch n := node.(type) {
+	case search.And:
+		return p.join(n.Nodes, " and ")
+	case search.Or:
+		return p.join(n.Nodes, " or ")
+	case search.Not:
+		return "not (" + p.compile(n.Node) + ")"
+	case search.Term:
+		switch n.Field {
+		case search.Feed:
+			return "i.feed_id in (select id from feeds where title like " + p.param(likePattern(n.Value)) + ` escape '\')`
+		case search.Folder:
+			return "i.feed_id in (select f.id from feeds f join folders d on d.id = f.folder_id where d.title like " +
+				p.param(likePattern(n.Value)) + ` escape '\')`
+		case search.Author:
+			return "i.author like " + p.param(likePattern(n.Value)) + ` escape '\'`
+		}
+		return "i.id in (select rowid from search where search match " + p.param(ftsQuery(n)) + ")"
+	case search.Date:
+		if n.Before {
+			return "i.date < strftime('%Y-%m-%d %H:%M:%f', " + p.param(n.Time) + ")"
+		}
+		return "i.date >= strftime('%Y-%m-%d %H:%M:%f', " + p.param(n.Time) + ")"
+	case search.Status:
+		return "i.status = " + p.param(n.Status)
+	}
+	return "true"
+}
+
+func (p *searchPredicate) join(nodes []search.Node, op string) string {
+	conds := make([]string, len(nodes))
+	for i, node := range nodes {
+		conds[i] = p.compile(node)
+	}
+	return "(" + strings.Join(conds, op) + ")"
+}
+
+// ftsQuery returns the FTS5 query of a text term: the words
+// by prefix, or the exact phrase.
+func ftsQuery(term search.Term) string {
+	words := search.Words(term.Value)
+	var query string
+	if term.Phrase {
+		query = `"` + strings.Join(words, " ") + `"`
+	} else {
+		terms := make([]string, len(words))
+		for i, word := range words {
+			terms[i] = `"` + word + `"*`
+		}
+		query = "(" + strings.Join(terms, " ") + ")"
+	}
+	if term.Field == search.Title {
+		query = "title : " + query
+	}
+	return query
+}
+
+// rankQuery returns the FTS5 query the relevance is computed on, or an
+// empty string if the items aren't sorted by relevance.
+func rankQuery(filter model.ItemFilter) string {
+	if !filter.SortByRelevance || filter.Search == nil {
+		return ""
+	}
+	terms := search.Ranked(search.Parse(*filter.Search))
+	queries := make([]string, len(terms))
+	for i, term := range terms {
+		queries[i] = ftsQuery(term)
+	}
+	return strings.Join(queries, " OR ")
+}
+
+// rankExpr returns the relevance of the item with the id, lower is better.
+func rankExpr(id, param string) string {
+	return fmt.Sprintf("coalesce((select rank from search where search match %s and rowid = %s), 0)", param, id)
+}
+
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
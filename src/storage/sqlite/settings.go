@@ -10,7 +10,10 @@ import (
 
 func (s *SQLiteStorage) GetSettings() model.Settings {
 	result := model.SettingsDefault()
-	rows, err := s.db.Query(`select key, val from settings;`)
+	rows, err := s.db.Query(
+		`select key, val from settings where user_id = :user_id`,
+		sql.Named("user_id", s.userID),
+	)
 	if err != nil {
 		log.Print(err)
 		return result
@@ -62,8 +65,9 @@ func (s *SQLiteStorage) UpdateSettings(params model.UpdateSettingsParams) bool {
 			return err
 		}
 		_, err = tx.Exec(`
-			insert into settings (key, val) values (:key, :val)
-			on conflict (key) do update set val=:val`,
+			insert into settings (user_id, key, val) values (:user_id, :key, :val)
+			on conflict (user_id, key) do update set val=:val`,
+			sql.Named("user_id", s.userID),
 			sql.Named("key", key),
 			sql.Named("val", valEncoded),
 		)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/nkanaev/yarr/src/content/htmlutil"
+	"github.com/nkanaev/yarr/src/storage/model"
 )
 
 func init() {
@@ -19,8 +20,14 @@ func init() {
 
 type SQLiteStorage struct {
 	db *sql.DB
+
+	// the user owning the feeds, folders & settings; 0 means all users
+	userID int64
 }
 
+// userScope restricts a query to the rows owned by the `:user_id` param.
+const userScope = "(:user_id = 0 or user_id = :user_id)"
+
 func New(path string) (*SQLiteStorage, error) {
 	if pos := strings.IndexRune(path, '?'); pos == -1 {
 		params := "_journal=WAL&_sync=NORMAL&_busy_timeout=5000&cache=shared"
@@ -36,7 +43,22 @@ func New(path string) (*SQLiteStorage, error) {
 	if err = migrate(db); err != nil {
 		return nil, err
 	}
-	return &SQLiteStorage{db: db}, nil
+	return &SQLiteStorage{db: db, userID: model.DefaultUserID}, nil
+}
+
+// ForUser returns a view of the storage scoped to the given user.
+func (s *SQLiteStorage) ForUser(userID int64) *SQLiteStorage {
+	return &SQLiteStorage{db: s.db, userID: userID}
+}
+
+func (s *SQLiteStorage) UserID() int64 {
+	return s.userID
+}
+
+// DB returns the underlying connection pool, for bulk operations
+// such as copying the data to another database.
+func (s *SQLiteStorage) DB() *sql.DB {
+	return s.db
 }
 
 func (s *SQLiteStorage) Close() error {
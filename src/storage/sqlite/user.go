@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *SQLiteStorage) CreateUser(params model.CreateUserParams) (*model.User, error) {
+	now := time.Now().UTC()
+	var id int64
+	err := s.db.QueryRow(`
+		insert into users (username, password_hash, fever_key, is_admin, created_at)
+		values (:username, :password_hash, :fever_key, :is_admin, :created_at)
+		returning id`,
+		sql.Named("username", params.Username),
+		sql.Named("password_hash", params.PasswordHash),
+		sql.Named("fever_key", params.FeverKey),
+		sql.Named("is_admin", params.IsAdmin),
+		sql.Named("created_at", now),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.User{
+		Id:           id,
+		Username:     params.Username,
+		PasswordHash: params.PasswordHash,
+		FeverKey:     params.FeverKey,
+		IsAdmin:      params.IsAdmin,
+		CreatedAt:    now,
+	}, nil
+}
+
+func (s *SQLiteStorage) getUser(column string, value any) (*model.User, error) {
+	var user model.User
+	err := s.db.QueryRow(`
+		select id, username, password_hash, fever_key, is_admin, created_at
+		from users where `+column+` = :value
+	`, sql.Named("value", value)).Scan(
+		&user.Id, &user.Username, &user.PasswordHash, &user.FeverKey, &user.IsAdmin, &user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser returns nil if the user doesn't exist.
+func (s *SQLiteStorage) GetUser(id int64) (*model.User, error) {
+	return s.getUser("id", id)
+}
+
+// GetUserByName returns nil if the user doesn't exist.
+func (s *SQLiteStorage) GetUserByName(username string) (*model.User, error) {
+	return s.getUser("username", username)
+}
+
+func (s *SQLiteStorage) ListUsers() ([]model.User, error) {
+	rows, err := s.db.Query(`
+		select id, username, password_hash, fever_key, is_admin, created_at
+		from users
+		order by id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]model.User, 0)
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(&user.Id, &user.Username, &user.PasswordHash, &user.FeverKey, &user.IsAdmin, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s *SQLiteStorage) UpdateUser(id int64, params model.UpdateUserParams) (bool, error) {
+	result, err := s.db.Exec(`
+		update users set
+			username      = coalesce(:username, username),
+			password_hash = coalesce(:password_hash, password_hash),
+			fever_key     = coalesce(:fever_key, fever_key),
+			is_admin      = coalesce(:is_admin, is_admin)
+		where id = :id
+	`,
+		sql.Named("id", id),
+		sql.Named("username", params.Username),
+		sql.Named("password_hash", params.PasswordHash),
+		sql.Named("fever_key", params.FeverKey),
+		sql.Named("is_admin", params.IsAdmin),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+// DeleteUser removes the user along with all the data they own.
+func (s *SQLiteStorage) DeleteUser(id int64) bool {
+	result, err := s.db.Exec(`delete from users where id = :id`, sql.Named("id", id))
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
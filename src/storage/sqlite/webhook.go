@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// number of deliveries kept per webhook
+const webhookDeliveryLimit = 100
+
+var errWebhookTargetNotFound = errors.New("folder or feed not found")
+
+const webhookColumns = "id, user_id, title, url, folder_id, feed_id, keyword, format, template, secret, is_enabled"
+
+func scanWebhook(row interface{ Scan(...any) error }) (model.Webhook, error) {
+	var hook model.Webhook
+	err := row.Scan(
+		&hook.Id, &hook.UserId, &hook.Title, &hook.URL, &hook.FolderID, &hook.FeedID,
+		&hook.Keyword, &hook.Format, &hook.Template, &hook.Secret, &hook.IsEnabled,
+	)
+	return hook, err
+}
+
+// webhookTargetOwned restricts the folder & feed of a webhook to the ones of the user.
+const webhookTargetOwned = `
+	(:folder_id is null or :folder_id in (select id from folders where ` + userScope + `))
+	and (:feed_id is null or :feed_id in (select id from feeds where ` + userScope + `))`
+
+// CreateWebhook fails if the webhook refers to a folder or feed of another user.
+func (s *SQLiteStorage) CreateWebhook(hook model.Webhook) (*model.Webhook, error) {
+	err := s.db.QueryRow(`
+		insert into webhooks (user_id, title, url, folder_id, feed_id, keyword, format, template, secret, is_enabled)
+		select :user_id, :title, :url, :folder_id, :feed_id, :keyword, :format, :template, :secret, :is_enabled
+		where `+webhookTargetOwned+`
+		returning id`,
+		sql.Named("user_id", s.userID),
+		sql.Named("title", hook.Title),
+		sql.Named("url", hook.URL),
+		sql.Named("folder_id", hook.FolderID),
+		sql.Named("feed_id", hook.FeedID),
+		sql.Named("keyword", hook.Keyword),
+		sql.Named("format", hook.Format),
+		sql.Named("template", hook.Template),
+		sql.Named("secret", hook.Secret),
+		sql.Named("is_enabled", hook.IsEnabled),
+	).Scan(&hook.Id)
+	if err == sql.ErrNoRows {
+		return nil, errWebhookTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	hook.UserId = s.userID
+	return &hook, nil
+}
+
+// UpdateWebhook replaces all the fields of the webhook.
+func (s *SQLiteStorage) UpdateWebhook(id int64, hook model.Webhook) (bool, error) {
+	result, err := s.db.Exec(`
+		update webhooks set
+			title      = :title,
+			url        = :url,
+			folder_id  = :folder_id,
+			feed_id    = :feed_id,
+			keyword    = :keyword,
+			format     = :format,
+			template   = :template,
+			secret     = :secret,
+			is_enabled = :is_enabled
+		where id = :id and `+userScope+` and `+webhookTargetOwned,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+		sql.Named("title", hook.Title),
+		sql.Named("url", hook.URL),
+		sql.Named("folder_id", hook.FolderID),
+		sql.Named("feed_id", hook.FeedID),
+		sql.Named("keyword", hook.Keyword),
+		sql.Named("format", hook.Format),
+		sql.Named("template", hook.Template),
+		sql.Named("secret", hook.Secret),
+		sql.Named("is_enabled", hook.IsEnabled),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) DeleteWebhook(id int64) bool {
+	result, err := s.db.Exec(
+		`delete from webhooks where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
+
+// GetWebhook returns nil if the webhook doesn't exist.
+func (s *SQLiteStorage) GetWebhook(id int64) (*model.Webhook, error) {
+	hook, err := scanWebhook(s.db.QueryRow(
+		`select `+webhookColumns+` from webhooks where id = :id and `+userScope,
+		sql.Named("id", id),
+		sql.Named("user_id", s.userID),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s *SQLiteStorage) ListWebhooks() ([]model.Webhook, error) {
+	return s.listWebhooks(
+		`select `+webhookColumns+` from webhooks where `+userScope+` order by id`,
+		sql.Named("user_id", s.userID),
+	)
+}
+
+// ListFeedWebhooks returns the enabled webhooks of the feed owner targeting
+// the feed, its folder, or all feeds. Keywords are left to the caller.
+func (s *SQLiteStorage) ListFeedWebhooks(feedID int64) ([]model.Webhook, error) {
+	return s.listWebhooks(`
+		select `+webhookColumns+`
+		from webhooks
+		where is_enabled
+		  and user_id = (select user_id from feeds where id = :feed_id and `+userScope+`)
+		  and (feed_id is null or feed_id = :feed_id)
+		  and (folder_id is null or folder_id = (select folder_id from feeds where id = :feed_id))
+		order by id`,
+		sql.Named("feed_id", feedID),
+		sql.Named("user_id", s.userID),
+	)
+}
+
+func (s *SQLiteStorage) listWebhooks(query string, args ...any) ([]model.Webhook, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]model.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// CreateWebhookDelivery logs a delivery attempt, dropping the oldest
+// ones past the last 100 of the webhook.
+func (s *SQLiteStorage) CreateWebhookDelivery(delivery model.WebhookDelivery) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		insert into webhook_deliveries (webhook_id, item_id, attempt, status_code, error, created_at)
+		select :webhook_id, :item_id, :attempt, :status_code, :error, :created_at
+		where exists (select 1 from webhooks where id = :webhook_id and `+userScope+`)`,
+		sql.Named("webhook_id", delivery.WebhookID),
+		sql.Named("item_id", delivery.ItemID),
+		sql.Named("attempt", delivery.Attempt),
+		sql.Named("status_code", delivery.StatusCode),
+		sql.Named("error", delivery.Error),
+		sql.Named("created_at", delivery.CreatedAt),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil || nrows == 0 {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		delete from webhook_deliveries
+		where webhook_id = :webhook_id and id not in (
+			select id from webhook_deliveries
+			where webhook_id = :webhook_id
+			order by id desc
+			limit :limit
+		)`,
+		sql.Named("webhook_id", delivery.WebhookID),
+		sql.Named("limit", webhookDeliveryLimit),
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ListWebhookDeliveries returns the most recent deliveries first.
+func (s *SQLiteStorage) ListWebhookDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		select id, webhook_id, item_id, attempt, status_code, error, created_at
+		from webhook_deliveries
+		where webhook_id = :webhook_id
+		  and webhook_id in (select id from webhooks where `+userScope+`)
+		order by id desc
+		limit :limit`,
+		sql.Named("webhook_id", webhookID),
+		sql.Named("user_id", s.userID),
+		sql.Named("limit", limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		err := rows.Scan(&d.Id, &d.WebhookID, &d.ItemID, &d.Attempt, &d.StatusCode, &d.Error, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func (s *SQLiteStorage) GetWebSubSubscription(feedID int64) (*model.WebSubSubscription, error) {
+	var sub model.WebSubSubscription
+	err := s.db.QueryRow(`
+		select feed_id, hub, topic, secret, requested_at, expires_at
+		from websub_subscriptions
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID)).Scan(
+		&sub.FeedID, &sub.Hub, &sub.Topic, &sub.Secret, &sub.RequestedAt, &sub.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateWebSubSubscription creates or replaces the subscription of the feed.
+func (s *SQLiteStorage) UpdateWebSubSubscription(sub model.WebSubSubscription) (bool, error) {
+	result, err := s.db.Exec(`
+		insert into websub_subscriptions (feed_id, hub, topic, secret, requested_at, expires_at)
+		select :id, :hub, :topic, :secret, :requested_at, :expires_at
+		where exists (select 1 from feeds where id = :id and `+userScope+`)
+		on conflict (feed_id) do update set
+			hub          = :hub,
+			topic        = :topic,
+			secret       = :secret,
+			requested_at = :requested_at,
+			expires_at   = :expires_at
+	`,
+		sql.Named("id", sub.FeedID),
+		sql.Named("hub", sub.Hub),
+		sql.Named("topic", sub.Topic),
+		sql.Named("secret", sub.Secret),
+		sql.Named("requested_at", sub.RequestedAt),
+		sql.Named("expires_at", sub.ExpiresAt),
+		sql.Named("user_id", s.userID),
+	)
+	if err != nil {
+		return false, err
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return nrows == 1, nil
+}
+
+func (s *SQLiteStorage) DeleteWebSubSubscription(feedID int64) bool {
+	result, err := s.db.Exec(`
+		delete from websub_subscriptions
+		where feed_id = :id and feed_id in (select id from feeds where `+userScope+`)
+	`, sql.Named("id", feedID), sql.Named("user_id", s.userID))
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	nrows, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return nrows == 1
+}
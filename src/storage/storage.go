@@ -9,31 +9,86 @@ import (
 )
 
 type Storage interface {
+	AddItemLabel(itemID, labelID int64) bool
 	Close() error
-	CountItems() int
+	CountItems(filter model.ItemFilter) int
+	CreateAPIKey(params model.CreateAPIKeyParams) (*model.APIKey, error)
 	CreateFeed(params model.CreateFeedParams) *model.Feed
+	CreateFeedLinkChange(feedID int64, change model.FeedLinkChange) (bool, error)
 	CreateFolder(title string) *model.Folder
-	CreateItems(items []model.Item) bool
+	CreateItems(items []model.Item) ([]model.Item, bool)
+	CreateLabel(title string) *model.Label
+	CreateMediaFile(file model.MediaFile) (*model.MediaFile, error)
+	CreateOutputFeed(feed model.OutputFeed) (*model.OutputFeed, error)
+	CreateRule(rule model.Rule) (*model.Rule, error)
+	CreateSavedSearch(search model.SavedSearch) (*model.SavedSearch, error)
+	CreateUser(params model.CreateUserParams) (*model.User, error)
+	CreateWebhook(hook model.Webhook) (*model.Webhook, error)
+	CreateWebhookDelivery(delivery model.WebhookDelivery) (bool, error)
+	DeleteAPIKey(id int64) bool
 	DeleteFeed(feedId int64) bool
+	DeleteFeedScraper(feedID int64) bool
 	DeleteItem(id int64) bool
 	DeleteFolder(folderId int64) bool
+	DeleteLabel(id int64) bool
+	DeleteMediaFile(id int64) bool
 	DeleteOldItems()
+	DeleteOutputFeed(id int64) bool
+	DeleteRule(id int64) bool
+	DeleteSavedSearch(id int64) bool
+	DeleteUser(id int64) bool
+	DeleteWebSubSubscription(feedID int64) bool
+	DeleteWebhook(id int64) bool
 	FeedStats() []model.FeedStat
+	GetAPIKeyByHash(tokenHash string) (*model.APIKey, error)
 	GetFeed(id int64) *model.Feed
+	GetFeedScraper(feedID int64) (*model.FeedScraper, error)
 	GetFeedState(feedID int64) (*model.FeedState, error)
 	GetItem(id int64) *model.Item
+	GetMediaFile(id int64) (*model.MediaFile, error)
+	GetOutputFeedByToken(token string) (*model.OutputFeed, error)
+	GetRule(id int64) (*model.Rule, error)
+	GetSavedSearch(id int64) (*model.SavedSearch, error)
 	GetSettings() model.Settings
+	GetUser(id int64) (*model.User, error)
+	GetUserByName(username string) (*model.User, error)
+	GetWebSubSubscription(feedID int64) (*model.WebSubSubscription, error)
+	GetWebhook(id int64) (*model.Webhook, error)
+	ListAPIKeys() ([]model.APIKey, error)
+	ListFeedLinkChanges(feedID int64) ([]model.FeedLinkChange, error)
+	ListFeedRules(feedID int64) ([]model.Rule, error)
 	ListFeedStates() ([]model.FeedState, error)
+	ListFeedWebhooks(feedID int64) ([]model.Webhook, error)
 	ListFeeds() []model.Feed
 	ListFolders() []model.Folder
+	ListItemFullContents(itemIDs []int64) map[int64]string
+	ListItemLabels(itemIDs []int64) map[int64][]int64
 	ListItems(filter model.ItemFilter, limit int, newestFirst bool, withContent bool) []model.Item
+	ListLabels() []model.Label
+	ListMediaFiles(itemIDs []int64) ([]model.MediaFile, error)
+	ListOutputFeeds() ([]model.OutputFeed, error)
+	ListRules() ([]model.Rule, error)
+	ListSavedSearches() ([]model.SavedSearch, error)
+	ListUsers() ([]model.User, error)
+	ListWebhookDeliveries(webhookID int64, limit int) ([]model.WebhookDelivery, error)
+	ListWebhooks() ([]model.Webhook, error)
 	MarkItemsRead(filter model.MarkFilter) bool
+	RemoveItemLabel(itemID, labelID int64) bool
+	SavedSearchStats() ([]model.SavedSearchStat, error)
 	UpdateFeed(feedId int64, params model.UpdateFeedParams) (bool, error)
+	UpdateFeedScraper(scraper model.FeedScraper) (bool, error)
 	UpdateFeedState(feedID int64, params model.UpdateFeedStateParams) (bool, error)
 	UpdateFolder(folderId int64, params model.UpdateFolderParams) (bool, error)
 	UpdateItem(id int64, params model.UpdateItemParams) bool
 	UpdateItemStatus(item_id int64, status model.ItemStatus) bool
+	UpdateLabel(id int64, params model.UpdateLabelParams) (bool, error)
+	UpdateRule(id int64, rule model.Rule) (bool, error)
+	UpdateSavedSearch(id int64, search model.SavedSearch) (bool, error)
 	UpdateSettings(params model.UpdateSettingsParams) bool
+	UpdateUser(id int64, params model.UpdateUserParams) (bool, error)
+	UpdateWebSubSubscription(sub model.WebSubSubscription) (bool, error)
+	UpdateWebhook(id int64, hook model.Webhook) (bool, error)
+	UserID() int64
 }
 
 func New(path string) (Storage, error) {
@@ -42,3 +97,16 @@ func New(path string) (Storage, error) {
 	}
 	return sqlite.New(path)
 }
+
+// ForUser returns a view of the storage restricted to the feeds, folders,
+// items and settings owned by the given user. Users themselves aren't scoped.
+// The user id 0 gives access to the data of all users.
+func ForUser(s Storage, userID int64) Storage {
+	switch db := s.(type) {
+	case *sqlite.SQLiteStorage:
+		return db.ForUser(userID)
+	case *postgres.PostgresStorage:
+		return db.ForUser(userID)
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestAPIKeys(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		key, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "reader", TokenHash: "hash1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Id == 0 || key.Name != "reader" || key.LastUsedAt != nil {
+			t.Fatalf("unexpected key: %#v", key)
+		}
+
+		// duplicate hashes are rejected
+		if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "other", TokenHash: "hash1"}); err == nil {
+			t.Error("expected error on duplicate token hash")
+		}
+
+		found, err := db.GetAPIKeyByHash("hash1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Id != key.Id || found.LastUsedAt == nil {
+			t.Fatalf("expected key with last used date, got %#v", found)
+		}
+
+		missing, err := db.GetAPIKeyByHash("nope")
+		if err != nil || missing != nil {
+			t.Errorf("expected no key, got %#v, %v", missing, err)
+		}
+
+		keys, err := db.ListAPIKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0].Id != key.Id {
+			t.Errorf("unexpected keys: %#v", keys)
+		}
+
+		if !db.DeleteAPIKey(key.Id) {
+			t.Fatal("delete failed")
+		}
+		if db.DeleteAPIKey(key.Id) {
+			t.Error("expected false when deleting already-deleted key")
+		}
+		if found, _ := db.GetAPIKeyByHash("hash1"); found != nil {
+			t.Error("expected deleted key to be gone")
+		}
+	})
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		future := time.Now().Add(time.Hour)
+		key, err := db.CreateAPIKey(model.CreateAPIKeyParams{
+			Name:      "read-only",
+			TokenHash: "hash1",
+			ReadOnly:  true,
+			ExpiresAt: &future,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.ReadOnly || key.ExpiresAt == nil {
+			t.Fatalf("unexpected key: %#v", key)
+		}
+
+		found, err := db.GetAPIKeyByHash("hash1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || !found.ReadOnly || found.ExpiresAt == nil || found.ExpiresAt.Sub(future).Abs() > time.Millisecond {
+			t.Fatalf("unexpected key: %#v", found)
+		}
+
+		past := time.Now().Add(-time.Hour)
+		if _, err := db.CreateAPIKey(model.CreateAPIKeyParams{Name: "expired", TokenHash: "hash2", ExpiresAt: &past}); err != nil {
+			t.Fatal(err)
+		}
+		if expired, err := db.GetAPIKeyByHash("hash2"); err != nil || expired != nil {
+			t.Errorf("expected expired key to be rejected, got %#v, %v", expired, err)
+		}
+
+		keys, err := db.ListAPIKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 || !keys[0].ReadOnly || keys[1].ReadOnly {
+			t.Errorf("unexpected keys: %#v", keys)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestBackup(t *testing.T) {
+	dbtest(t, func(t *testing.T, src storage.Storage) {
+		folder := src.CreateFolder("News")
+		feed := src.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed", FolderID: &folder.Id})
+		icon := model.Icon("\x89PNG")
+		src.UpdateFeed(feed.Id, model.UpdateFeedParams{Icon: model.SetNullable(&icon)})
+		date := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+		items, _ := src.CreateItems([]model.Item{
+			{GUID: "a", FeedId: feed.Id, Title: "a", Date: date, Content: "<p>alpha</p>",
+				MediaLinks: model.MediaLinks{{URL: "http://example.com/a.mp3", Type: "audio"}}},
+			{GUID: "b", FeedId: feed.Id, Title: "b", Date: date.Add(time.Hour)},
+		})
+		src.UpdateItemStatus(items[0].Id, model.STARRED)
+
+		dir := t.TempDir()
+		backup := filepath.Join(dir, "backup"+storage.BackupExt(src))
+		if err := storage.Backup(src, backup); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(backup); err != nil {
+			t.Fatal(err)
+		} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+			t.Errorf("expected the backup to be private, got %v", info.Mode())
+		}
+		if err := storage.Backup(src, backup); err == nil {
+			t.Error("expected an existing backup not to be overwritten")
+		}
+		dump := filepath.Join(dir, "backup.json")
+		f, err := os.Create(dump)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Dump(src, f); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		// a dump missing rows of its tables is rejected
+		data, err := os.ReadFile(dump)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		var truncated []string
+		for _, line := range lines {
+			if !strings.HasPrefix(line, `["b"`) && !strings.Contains(line, `,"b",`) {
+				truncated = append(truncated, line)
+			}
+		}
+		if len(truncated) == len(lines) {
+			t.Fatal("expected the dump to have item b")
+		}
+		broken := filepath.Join(dir, "broken.json")
+		if err := os.WriteFile(broken, []byte(strings.Join(truncated, "\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if dst, err := storage.New(":memory:"); err != nil {
+			t.Fatal(err)
+		} else if _, err := storage.Restore(dst, broken, false); err == nil {
+			t.Error("expected a dump with missing rows to be rejected")
+		}
+
+		want := src.ListItems(model.ItemFilter{}, 10, true, true)
+		for _, path := range []string{backup, dump} {
+			dst, err := storage.New(filepath.Join(t.TempDir(), "restore.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+			if _, err := storage.Restore(dst, path, false); err != nil {
+				t.Fatalf("%s: %s", path, err)
+			}
+
+			have := dst.ListItems(model.ItemFilter{}, 10, true, true)
+			if len(have) != len(want) {
+				t.Fatalf("%s: unexpected items: %#v", path, have)
+			}
+			for i := range want {
+				if !want[i].Date.Equal(have[i].Date) {
+					t.Errorf("%s: unexpected date: %s != %s", path, have[i].Date, want[i].Date)
+				}
+				have[i].Date = want[i].Date
+			}
+			if !reflect.DeepEqual(want, have) {
+				t.Errorf("%s: items differ:\nwant: %#v\nhave: %#v", path, want, have)
+			}
+			if f := dst.GetFeed(feed.Id); f == nil || string(*f.Icon) != string(icon) || *f.FolderId != folder.Id {
+				t.Errorf("%s: unexpected feed: %#v", path, f)
+			}
+
+			if _, err := storage.Restore(dst, path, false); err == nil {
+				t.Errorf("%s: expected restoring into a non-empty database to fail", path)
+			}
+			dst.CreateFolder("Extra")
+			if _, err := storage.Restore(dst, path, true); err != nil {
+				t.Errorf("%s: %s", path, err)
+			}
+			if folders := dst.ListFolders(); len(folders) != 1 {
+				t.Errorf("%s: expected the data to be replaced, got %#v", path, folders)
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestCopy(t *testing.T) {
+	dbtest(t, func(t *testing.T, src storage.Storage) {
+		folder := src.CreateFolder("News")
+		feed := src.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed", FolderID: &folder.Id})
+		icon := model.Icon("\x89PNG")
+		src.UpdateFeed(feed.Id, model.UpdateFeedParams{Icon: model.SetNullable(&icon)})
+
+		date := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+		items, _ := src.CreateItems([]model.Item{
+			{GUID: "a", FeedId: feed.Id, Title: "a", Date: date, Content: "<p>alpha</p>",
+				MediaLinks: model.MediaLinks{{URL: "http://example.com/a.mp3", Type: "audio"}},
+				Categories: []string{"go"}},
+			{GUID: "b", FeedId: feed.Id, Title: "b", Date: date.Add(time.Hour)},
+		})
+		src.UpdateItemStatus(items[0].Id, model.STARRED)
+		label := src.CreateLabel("later")
+		src.AddItemLabel(items[1].Id, label.Id)
+
+		next := date.Add(24 * time.Hour)
+		skip := []int{1, 2}
+		src.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{NextRefresh: &next, SkipHours: &skip})
+		rate := int64(42)
+		src.UpdateSettings(model.UpdateSettingsParams{RefreshRate: &rate})
+
+		dst, err := storage.New(filepath.Join(t.TempDir(), "copy.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dst.Close()
+		counts, err := storage.Copy(dst, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range counts {
+			if c.Table == "items" && c.Rows != 2 {
+				t.Errorf("unexpected items count: %d", c.Rows)
+			}
+		}
+
+		want := src.ListItems(model.ItemFilter{}, 10, true, true)
+		have := dst.ListItems(model.ItemFilter{}, 10, true, true)
+		if len(have) != 2 {
+			t.Fatalf("unexpected items: %#v", have)
+		}
+		for i := range want {
+			if !want[i].Date.Equal(have[i].Date) {
+				t.Errorf("unexpected date: %s != %s", have[i].Date, want[i].Date)
+			}
+			want[i].Date, have[i].Date = time.Time{}, time.Time{}
+		}
+		if !reflect.DeepEqual(want, have) {
+			t.Errorf("items differ:\nwant: %#v\nhave: %#v", want, have)
+		}
+		search := "alpha"
+		if found := dst.ListItems(model.ItemFilter{Search: &search}, 10, true, false); len(found) != 1 {
+			t.Errorf("expected the copied items to be searchable, got %#v", found)
+		}
+		if labels := dst.ListItemLabels(nil); !reflect.DeepEqual(labels, map[int64][]int64{items[1].Id: {label.Id}}) {
+			t.Errorf("unexpected labels: %v", labels)
+		}
+		if f := dst.GetFeed(feed.Id); f == nil || string(*f.Icon) != string(icon) || *f.FolderId != folder.Id {
+			t.Errorf("unexpected feed: %#v", f)
+		}
+		if state, _ := dst.GetFeedState(feed.Id); state == nil || !state.NextRefresh.Equal(next) || !reflect.DeepEqual(state.SkipHours, skip) {
+			t.Errorf("unexpected feed state: %#v", state)
+		}
+		if s := dst.GetSettings(); s.RefreshRate != rate {
+			t.Errorf("unexpected settings: %#v", s)
+		}
+
+		// new rows don't reuse the copied ids
+		more, _ := dst.CreateItems([]model.Item{{GUID: "c", FeedId: feed.Id, Title: "c"}})
+		if len(more) != 1 || more[0].Id <= items[1].Id {
+			t.Errorf("unexpected new item: %#v", more)
+		}
+
+		if _, err := storage.Copy(dst, src); err == nil {
+			t.Error("expected copying into a non-empty database to fail")
+		}
+	})
+}
@@ -122,3 +122,86 @@ func TestListFeedStates(t *testing.T) {
 		}
 	})
 }
+
+func TestUpdateFeedState_Schedule(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		f := s.CreateFeed(model.CreateFeedParams{Title: "Test", FeedLink: "http://example.com"})
+
+		next := time.Now().UTC().Truncate(time.Second).Add(time.Hour)
+		interval := 90 * time.Minute
+		hours := []int{1, 2}
+		_, err := s.UpdateFeedState(f.Id, model.UpdateFeedStateParams{
+			NextRefresh:     &next,
+			RefreshInterval: &interval,
+			SkipHours:       &hours,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		state, err := s.GetFeedState(f.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.NextRefresh == nil || !state.NextRefresh.Equal(next) {
+			t.Errorf("expected %v, got %v", next, state.NextRefresh)
+		}
+		if state.RefreshInterval != interval {
+			t.Errorf("expected %v, got %v", interval, state.RefreshInterval)
+		}
+		if len(state.SkipHours) != 2 || state.SkipHours[0] != 1 || state.SkipHours[1] != 2 {
+			t.Errorf("expected %v, got %v", hours, state.SkipHours)
+		}
+
+		// overriding the feed's interval reschedules it
+		minutes := int64(15)
+		s.UpdateFeed(f.Id, model.UpdateFeedParams{RefreshInterval: model.SetNullable(&minutes)})
+		if feed := s.GetFeed(f.Id); feed.RefreshInterval == nil || *feed.RefreshInterval != minutes {
+			t.Errorf("expected override %d, got %v", minutes, feed.RefreshInterval)
+		}
+		state, _ = s.GetFeedState(f.Id)
+		if state.NextRefresh != nil {
+			t.Errorf("expected next refresh to be reset, got %v", state.NextRefresh)
+		}
+		if state.RefreshInterval != interval {
+			t.Errorf("interval should be unchanged, got %v", state.RefreshInterval)
+		}
+
+		s.UpdateFeed(f.Id, model.UpdateFeedParams{RefreshInterval: model.SetNullable[int64](nil)})
+		if feed := s.GetFeed(f.Id); feed.RefreshInterval != nil {
+			t.Errorf("expected no override, got %v", *feed.RefreshInterval)
+		}
+	})
+}
+
+func TestFeedLinkChanges(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		f := s.CreateFeed(model.CreateFeedParams{Title: "Test", FeedLink: "http://example.com/a"})
+
+		now := time.Now().UTC().Truncate(time.Second)
+		for _, link := range []string{"http://example.com/b", "http://example.com/c"} {
+			ok, err := s.CreateFeedLinkChange(f.Id, model.FeedLinkChange{
+				OldLink: "http://example.com/a", NewLink: link, StatusCode: 301, CreatedAt: now,
+			})
+			if err != nil || !ok {
+				t.Fatalf("expected change to be created, got %v (%v)", ok, err)
+			}
+		}
+		if ok, _ := s.CreateFeedLinkChange(f.Id+1, model.FeedLinkChange{CreatedAt: now}); ok {
+			t.Error("expected no change for unknown feed")
+		}
+
+		changes, err := s.ListFeedLinkChanges(f.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 2 || changes[1].NewLink != "http://example.com/c" || !changes[1].CreatedAt.Equal(now) {
+			t.Errorf("unexpected changes: %#v", changes)
+		}
+
+		s.DeleteFeed(f.Id)
+		if changes, _ := s.ListFeedLinkChanges(f.Id); len(changes) != 0 {
+			t.Errorf("expected history to be deleted with the feed, got %#v", changes)
+		}
+	})
+}
@@ -281,6 +281,22 @@ func TestListItemsPaginated(t *testing.T) {
 	})
 }
 
+func TestListItemsSince(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		scope := testItemsSetup(db)
+
+		item212 := MustGet(scope.items, "item212")
+		since := item212.Date.UTC()
+		have := getItemGuids(db.ListItems(model.ItemFilter{Since: &since}, 10, false, false))
+		want := []string{"item212", "item011", "item012", "item013"}
+		if !reflect.DeepEqual(have, want) {
+			t.Logf("want: %#v", want)
+			t.Logf("have: %#v", have)
+			t.Fail()
+		}
+	})
+}
+
 func TestMarkAllItemsRead(t *testing.T) {
 	var read model.ItemStatus = model.READ
 	dbtest(t, func(t *testing.T, db storage.Storage) {
@@ -335,6 +351,25 @@ func TestMarkItemsReadByFeed(t *testing.T) {
 	})
 }
 
+func TestMarkItemsReadByMaxID(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		testItemsSetup(db)
+		items := db.ListItems(model.ItemFilter{SinceID: new(int64)}, 10, false, false)
+		maxID := items[5].Id
+		db.MarkItemsRead(model.MarkFilter{MaxID: &maxID})
+		for _, item := range items {
+			have := db.GetItem(item.Id).Status
+			want := item.Status
+			if item.Id < maxID && want == model.UNREAD {
+				want = model.READ
+			}
+			if have != want {
+				t.Errorf("%s: want status %v, have %v", item.GUID, want, have)
+			}
+		}
+	})
+}
+
 func TestDeleteOldItems(t *testing.T) {
 	t.Run("keeps at least 50 items", func(t *testing.T) {
 		dbtest(t, func(t *testing.T, db storage.Storage) {
@@ -451,7 +486,7 @@ func TestDeleteItem(t *testing.T) {
 
 func TestCountItems(t *testing.T) {
 	dbtest(t, func(t *testing.T, db storage.Storage) {
-		if count := db.CountItems(); count != 0 {
+		if count := db.CountItems(model.ItemFilter{}); count != 0 {
 			t.Errorf("expected 0, got %d", count)
 		}
 
@@ -462,16 +497,25 @@ func TestCountItems(t *testing.T) {
 			{GUID: "i3", FeedId: feed.Id},
 		})
 
-		if count := db.CountItems(); count != 3 {
+		if count := db.CountItems(model.ItemFilter{}); count != 3 {
 			t.Errorf("expected 3, got %d", count)
 		}
 
 		items := db.ListItems(model.ItemFilter{}, 10, false, false)
 		db.DeleteItem(items[0].Id)
 
-		if count := db.CountItems(); count != 2 {
+		if count := db.CountItems(model.ItemFilter{}); count != 2 {
 			t.Errorf("expected 2, got %d", count)
 		}
+
+		db.UpdateItemStatus(items[1].Id, model.READ)
+		unread := model.UNREAD
+		if count := db.CountItems(model.ItemFilter{Status: &unread}); count != 1 {
+			t.Errorf("expected 1 unread, got %d", count)
+		}
+		if items := db.ListItems(model.ItemFilter{Offset: 1}, 10, false, false); len(items) != 1 || items[0].GUID != "i3" {
+			t.Errorf("expected the offset to skip the first item, got %#v", items)
+		}
 	})
 }
 
@@ -562,3 +606,115 @@ func TestSearch(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateItemsReturnsNew(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		feed := s.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+		now := time.Now()
+		items := []model.Item{
+			{GUID: "a", FeedId: feed.Id, Title: "a", Date: now},
+			{GUID: "b", FeedId: feed.Id, Title: "b", Date: now},
+		}
+		created, ok := s.CreateItems(items)
+		if !ok || len(created) != 2 || created[0].Id == 0 || created[1].Id == 0 {
+			t.Fatalf("expected 2 new items, got %#v (%v)", created, ok)
+		}
+
+		items = append(items, model.Item{GUID: "c", FeedId: feed.Id, Title: "c", Date: now})
+		created, ok = s.CreateItems(items)
+		if !ok || len(created) != 1 || created[0].GUID != "c" {
+			t.Fatalf("expected 1 new item, got %#v (%v)", created, ok)
+		}
+	})
+}
+
+func TestUpdateItemFullContent(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		feed := s.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+		fetch := true
+		s.UpdateFeed(feed.Id, model.UpdateFeedParams{FetchContent: &fetch})
+		if f := s.GetFeed(feed.Id); !f.FetchContent {
+			t.Error("expected fetch_content to be set")
+		}
+
+		created, _ := s.CreateItems([]model.Item{{GUID: "a", FeedId: feed.Id, Title: "a", Content: "summary"}})
+		content := "<p>article</p>"
+		if !s.UpdateItem(created[0].Id, model.UpdateItemParams{FullContent: &content}) {
+			t.Fatal("update failed")
+		}
+		if item := s.GetItem(created[0].Id); item.FullContent != content || item.Content != "summary" {
+			t.Errorf("unexpected item: %#v", item)
+		}
+		items := s.ListItems(model.ItemFilter{}, 10, false, true)
+		if len(items) != 1 || items[0].FullContent != "" {
+			t.Errorf("expected lists to skip the full content, got %#v", items)
+		}
+
+		other, _ := s.CreateItems([]model.Item{{GUID: "b", FeedId: feed.Id, Title: "b"}})
+		contents := s.ListItemFullContents([]int64{created[0].Id, other[0].Id})
+		if len(contents) != 1 || contents[created[0].Id] != content {
+			t.Errorf("unexpected full contents: %#v", contents)
+		}
+	})
+}
+
+func TestListItemsByAuthorAndCategory(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		feed := s.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+		now := time.Now()
+		s.CreateItems([]model.Item{
+			{GUID: "a", FeedId: feed.Id, Title: "a", Date: now, Author: "Jane Doe", Categories: []string{"Go", "News"}, CommentsURL: "http://example.com/a#comments"},
+			{GUID: "b", FeedId: feed.Id, Title: "b", Date: now, Author: "John Roe", Categories: []string{"news"}},
+			{GUID: "c", FeedId: feed.Id, Title: "c", Date: now},
+		})
+
+		author := "jane doe"
+		items := s.ListItems(model.ItemFilter{Author: &author}, 10, false, false)
+		if len(items) != 1 || items[0].GUID != "a" {
+			t.Fatalf("unexpected items by author: %#v", items)
+		}
+		if !reflect.DeepEqual(items[0].Categories, []string{"Go", "News"}) || items[0].CommentsURL != "http://example.com/a#comments" {
+			t.Errorf("unexpected item: %#v", items[0])
+		}
+
+		category := "NEWS"
+		items = s.ListItems(model.ItemFilter{Category: &category}, 10, false, false)
+		if have := getItemGuids(items); !reflect.DeepEqual(have, []string{"a", "b"}) {
+			t.Errorf("unexpected items by category: %v", have)
+		}
+
+		category = "go"
+		items = s.ListItems(model.ItemFilter{Category: &category, Author: &author}, 10, false, false)
+		if len(items) != 1 {
+			t.Errorf("expected 1 item, got %d", len(items))
+		}
+
+		if item := s.GetItem(items[0].Id); item.Author != "Jane Doe" || len(item.Categories) != 2 {
+			t.Errorf("unexpected item: %#v", item)
+		}
+	})
+}
+
+func TestItemPodcast(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		feed := s.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed"})
+		podcast := &model.Podcast{
+			Image:       "http://example.com/ep.jpg",
+			Episode:     3,
+			Chapters:    &model.PodcastLink{URL: "http://example.com/chapters.json", Type: "application/json+chapters"},
+			Transcripts: []model.PodcastLink{{URL: "http://example.com/ep.vtt", Type: "text/vtt"}},
+			Persons:     []model.PodcastPerson{{Name: "Jane", Role: "host"}},
+		}
+		created, _ := s.CreateItems([]model.Item{
+			{GUID: "a", FeedId: feed.Id, Title: "a", Podcast: podcast},
+			{GUID: "b", FeedId: feed.Id, Title: "b"},
+		})
+
+		if item := s.GetItem(created[0].Id); !reflect.DeepEqual(item.Podcast, podcast) {
+			t.Errorf("unexpected podcast: %#v", item.Podcast)
+		}
+		if item := s.GetItem(created[1].Id); item.Podcast != nil {
+			t.Errorf("expected no podcast, got %#v", item.Podcast)
+		}
+	})
+}
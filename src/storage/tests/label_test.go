@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestLabels(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+		db.CreateItems([]model.Item{
+			{GUID: "1", FeedId: feed.Id, Title: "one"},
+			{GUID: "2", FeedId: feed.Id, Title: "two"},
+		})
+		items := db.ListItems(model.ItemFilter{}, 10, false, false)
+
+		review := db.CreateLabel("to-review")
+		security := db.CreateLabel("security")
+		if review == nil || security == nil {
+			t.Fatal("failed to create labels")
+		}
+		if labels := db.ListLabels(); len(labels) != 2 || labels[0].Title != "security" {
+			t.Fatalf("unexpected labels: %#v", labels)
+		}
+
+		if !db.AddItemLabel(items[0].Id, review.Id) || !db.AddItemLabel(items[0].Id, security.Id) {
+			t.Fatal("failed to label item")
+		}
+		if !db.AddItemLabel(items[0].Id, review.Id) {
+			t.Error("expected adding an existing label to succeed")
+		}
+		if !db.AddItemLabel(items[1].Id, review.Id) {
+			t.Fatal("failed to label item")
+		}
+		if db.AddItemLabel(items[1].Id, -1) {
+			t.Error("expected unknown label to be rejected")
+		}
+
+		labels := db.ListItemLabels([]int64{items[0].Id})
+		if len(labels) != 1 || len(labels[items[0].Id]) != 2 {
+			t.Errorf("unexpected item labels: %#v", labels)
+		}
+		if all := db.ListItemLabels(nil); len(all) != 2 {
+			t.Errorf("unexpected item labels: %#v", all)
+		}
+
+		filtered := db.ListItems(model.ItemFilter{Label: &security.Id}, 10, false, false)
+		if len(filtered) != 1 || filtered[0].Id != items[0].Id {
+			t.Errorf("unexpected items with label: %#v", filtered)
+		}
+		db.MarkItemsRead(model.MarkFilter{Label: &security.Id})
+		if db.GetItem(items[0].Id).Status != model.READ || db.GetItem(items[1].Id).Status != model.UNREAD {
+			t.Error("expected only the labelled item to be marked read")
+		}
+
+		title := "review"
+		db.UpdateLabel(review.Id, model.UpdateLabelParams{Title: &title})
+		if labels := db.ListLabels(); labels[0].Title != "review" {
+			t.Errorf("expected renamed label, got %#v", labels)
+		}
+
+		if !db.RemoveItemLabel(items[1].Id, review.Id) {
+			t.Error("failed to remove label")
+		}
+		if db.RemoveItemLabel(items[1].Id, review.Id) {
+			t.Error("expected false when removing a missing label")
+		}
+
+		db.DeleteLabel(security.Id)
+		if labels := db.ListItemLabels([]int64{items[0].Id}); len(labels[items[0].Id]) != 1 {
+			t.Errorf("expected deleted label to be detached, got %#v", labels)
+		}
+	})
+}
+
+func TestLabelsPerUser(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+
+		feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+		db.CreateItems([]model.Item{{GUID: "1", FeedId: feed.Id, Title: "one"}})
+		item := db.ListItems(model.ItemFilter{}, 1, false, false)[0]
+
+		label := alice.CreateLabel("mine")
+		if len(db.ListLabels()) != 0 {
+			t.Error("expected labels to be per user")
+		}
+		if alice.AddItemLabel(item.Id, label.Id) || db.AddItemLabel(item.Id, label.Id) {
+			t.Error("expected labels of other users to be rejected")
+		}
+	})
+}
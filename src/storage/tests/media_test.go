@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestMediaFiles(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		f := s.CreateFeed(model.CreateFeedParams{Title: "Podcast", FeedLink: "http://example.com/podcast"})
+		items, _ := s.CreateItems([]model.Item{
+			{GUID: "1", FeedId: f.Id, Title: "one"},
+			{GUID: "2", FeedId: f.Id, Title: "two"},
+		})
+
+		a, err := s.CreateMediaFile(model.MediaFile{ItemID: items[0].Id, URL: "http://example.com/1.mp3", Path: "1.mp3", Size: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.CreateMediaFile(model.MediaFile{ItemID: items[1].Id, URL: "http://example.com/2.mp3", Path: "2.mp3", MimeType: "audio/mpeg", Size: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateMediaFile(model.MediaFile{ItemID: items[1].Id + 100, URL: "x", Path: "x"}); err == nil {
+			t.Error("expected no media file for unknown item")
+		}
+
+		if x, err := s.GetMediaFile(b.Id); err != nil || x == nil || x.Path != "2.mp3" || x.MimeType != "audio/mpeg" || x.Size != 20 {
+			t.Errorf("unexpected media file: %#v (%v)", x, err)
+		}
+		if files, err := s.ListMediaFiles(nil); err != nil || len(files) != 2 || files[0].Id != a.Id {
+			t.Errorf("unexpected media files: %#v (%v)", files, err)
+		}
+		if files, _ := s.ListMediaFiles([]int64{items[1].Id}); len(files) != 1 || files[0].Id != b.Id {
+			t.Errorf("unexpected media files of item: %#v", files)
+		}
+		if files, _ := s.ListMediaFiles([]int64{}); len(files) != 0 {
+			t.Errorf("expected no media files, got %#v", files)
+		}
+
+		if !s.DeleteMediaFile(a.Id) {
+			t.Error("expected media file to be deleted")
+		}
+		if x, _ := s.GetMediaFile(a.Id); x != nil {
+			t.Errorf("expected media file to be gone, got %#v", x)
+		}
+
+		// files go away with their items
+		s.DeleteFeed(f.Id)
+		if files, _ := s.ListMediaFiles(nil); len(files) != 0 {
+			t.Errorf("expected media files to be deleted with the feed, got %#v", files)
+		}
+	})
+}
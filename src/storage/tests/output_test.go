@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestOutputFeeds(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		folder := db.CreateFolder("news")
+		label := db.CreateLabel("later")
+
+		starred, err := db.CreateOutputFeed(model.OutputFeed{Source: model.OutputStarred, Token: "t1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byFolder, err := db.CreateOutputFeed(model.OutputFeed{Source: model.OutputFolder, SourceID: &folder.Id, Token: "t2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateOutputFeed(model.OutputFeed{Source: model.OutputLabel, SourceID: &label.Id, Token: "t3"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateOutputFeed(model.OutputFeed{Source: model.OutputLabel, SourceID: new(int64), Token: "t4"}); err == nil {
+			t.Error("expected error for unknown label")
+		}
+		if _, err := db.CreateOutputFeed(model.OutputFeed{Source: model.OutputStarred, Token: "t1"}); err == nil {
+			t.Error("expected tokens to be unique")
+		}
+
+		feed, err := storage.ForUser(db, 0).GetOutputFeedByToken("t2")
+		if err != nil || feed == nil || feed.Id != byFolder.Id || feed.Source != model.OutputFolder ||
+			feed.SourceID == nil || *feed.SourceID != folder.Id || feed.UserId != db.UserID() {
+			t.Fatalf("unexpected output feed: %#v (%v)", feed, err)
+		}
+		if feed, _ := db.GetOutputFeedByToken("unknown"); feed != nil {
+			t.Errorf("expected no feed, got %#v", feed)
+		}
+
+		db.DeleteLabel(label.Id)
+		if feed, _ := db.GetOutputFeedByToken("t3"); feed != nil {
+			t.Error("expected output feed to be deleted with its label")
+		}
+		if !db.DeleteOutputFeed(starred.Id) {
+			t.Error("delete failed")
+		}
+		if feeds, _ := db.ListOutputFeeds(); len(feeds) != 1 || feeds[0].Id != byFolder.Id {
+			t.Errorf("unexpected output feeds: %#v", feeds)
+		}
+
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+		if _, err := alice.CreateOutputFeed(model.OutputFeed{Source: model.OutputFolder, SourceID: &folder.Id, Token: "t5"}); err == nil {
+			t.Error("expected folders of other users to be rejected")
+		}
+		if feeds, _ := alice.ListOutputFeeds(); len(feeds) != 0 {
+			t.Errorf("expected output feeds of other users to be hidden, got %#v", feeds)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestRules(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		feed1 := db.CreateFeed(model.CreateFeedParams{Title: "feed1", FeedLink: "http://example.com/feed1.xml"})
+		feed2 := db.CreateFeed(model.CreateFeedParams{Title: "feed2", FeedLink: "http://example.com/feed2.xml"})
+
+		global, err := db.CreateRule(model.Rule{
+			Title:      "sponsored",
+			Conditions: model.RuleConditions{{Field: "title", Op: "contains", Value: "sponsored"}},
+			Action:     model.RuleMarkRead,
+			IsEnabled:  true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scoped, err := db.CreateRule(model.Rule{
+			FeedID:    &feed1.Id,
+			Action:    model.RuleStar,
+			IsEnabled: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateRule(model.Rule{FeedID: new(int64), Action: model.RuleStar}); err == nil {
+			t.Error("expected error for unknown feed")
+		}
+
+		rule, err := db.GetRule(global.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule == nil || rule.Title != "sponsored" || len(rule.Conditions) != 1 || rule.Conditions[0].Value != "sponsored" {
+			t.Fatalf("unexpected rule: %#v", rule)
+		}
+		if rules, _ := db.ListRules(); len(rules) != 2 {
+			t.Errorf("expected 2 rules, got %#v", rules)
+		}
+
+		if rules, _ := db.ListFeedRules(feed1.Id); len(rules) != 2 {
+			t.Errorf("expected 2 rules for feed1, got %#v", rules)
+		}
+		if rules, _ := db.ListFeedRules(feed2.Id); len(rules) != 1 || rules[0].Id != global.Id {
+			t.Errorf("expected the global rule for feed2, got %#v", rules)
+		}
+
+		scoped.IsEnabled = false
+		if ok, err := db.UpdateRule(scoped.Id, *scoped); !ok || err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if rules, _ := db.ListFeedRules(feed1.Id); len(rules) != 1 {
+			t.Errorf("expected disabled rule to be skipped, got %#v", rules)
+		}
+
+		db.DeleteFeed(feed1.Id)
+		if rule, _ := db.GetRule(scoped.Id); rule != nil {
+			t.Error("expected rule to be deleted with its feed")
+		}
+		if !db.DeleteRule(global.Id) {
+			t.Error("delete failed")
+		}
+		if rules, _ := db.ListRules(); len(rules) != 0 {
+			t.Errorf("expected no rules, got %#v", rules)
+		}
+	})
+}
+
+func TestRulesPerUser(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+		feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+
+		if _, err := alice.CreateRule(model.Rule{FeedID: &feed.Id, Action: model.RuleDrop}); err == nil {
+			t.Error("expected feeds of other users to be rejected")
+		}
+		if _, err := alice.CreateRule(model.Rule{Action: model.RuleDrop, IsEnabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		if rules, _ := storage.ForUser(db, 0).ListFeedRules(feed.Id); len(rules) != 0 {
+			t.Errorf("expected rules of other users to be skipped, got %#v", rules)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestSavedSearches(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		feed := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+		now := time.Now()
+		db.CreateItems([]model.Item{
+			{GUID: "1", FeedId: feed.Id, Title: "go generics", Date: now, Status: model.UNREAD},
+			{GUID: "2", FeedId: feed.Id, Title: "go modules", Date: now, Status: model.STARRED},
+			{GUID: "3", FeedId: feed.Id, Title: "go tooling", Date: now, Status: model.READ},
+			{GUID: "4", FeedId: feed.Id, Title: "rust traits", Date: now, Status: model.UNREAD},
+		})
+
+		all, err := db.CreateSavedSearch(model.SavedSearch{Name: "go", Query: "title:go"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		starred := model.STARRED
+		scoped, err := db.CreateSavedSearch(model.SavedSearch{Name: "Best", Query: "go OR rust", Status: &starred})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		search, err := db.GetSavedSearch(scoped.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if search == nil || search.Query != "go OR rust" || search.Status == nil || *search.Status != model.STARRED {
+			t.Fatalf("unexpected search: %#v", search)
+		}
+		if search, _ := db.GetSavedSearch(all.Id); search == nil || search.Status != nil {
+			t.Fatalf("expected search without status, got %#v", search)
+		}
+		if search, _ := db.GetSavedSearch(-1); search != nil {
+			t.Errorf("expected missing search, got %#v", search)
+		}
+		searches, _ := db.ListSavedSearches()
+		if len(searches) != 2 || searches[0].Name != "Best" || searches[1].Name != "go" {
+			t.Errorf("expected searches ordered by name, got %#v", searches)
+		}
+
+		stats, err := db.SavedSearchStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []model.SavedSearchStat{
+			{SearchID: scoped.Id, UnreadCount: 0, StarredCount: 1},
+			{SearchID: all.Id, UnreadCount: 1, StarredCount: 1},
+		}
+		if len(stats) != len(want) || stats[0] != want[0] || stats[1] != want[1] {
+			t.Errorf("unexpected stats: %#v", stats)
+		}
+
+		scoped.Status = nil
+		if ok, err := db.UpdateSavedSearch(scoped.Id, *scoped); !ok || err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		stats, _ = db.SavedSearchStats()
+		if len(stats) != 2 || stats[0] != (model.SavedSearchStat{SearchID: scoped.Id, UnreadCount: 2, StarredCount: 1}) {
+			t.Errorf("unexpected stats after update: %#v", stats)
+		}
+		if ok, _ := db.UpdateSavedSearch(-1, *scoped); ok {
+			t.Error("expected update of a missing search to fail")
+		}
+
+		if !db.DeleteSavedSearch(all.Id) {
+			t.Error("delete failed")
+		}
+		if searches, _ := db.ListSavedSearches(); len(searches) != 1 {
+			t.Errorf("expected 1 search, got %#v", searches)
+		}
+	})
+}
+
+func TestSavedSearchesPerUser(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+		search, err := db.CreateSavedSearch(model.SavedSearch{Name: "go", Query: "go"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found, _ := alice.GetSavedSearch(search.Id); found != nil {
+			t.Errorf("expected searches of other users to be hidden, got %#v", found)
+		}
+		if searches, _ := alice.ListSavedSearches(); len(searches) != 0 {
+			t.Errorf("expected no searches, got %#v", searches)
+		}
+		if alice.DeleteSavedSearch(search.Id) {
+			t.Error("expected searches of other users to be kept")
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestFeedScrapers(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		f := s.CreateFeed(model.CreateFeedParams{Title: "Test", FeedLink: "http://example.com/changelog"})
+		if x, err := s.GetFeedScraper(f.Id); err != nil || x != nil {
+			t.Fatalf("expected no scraper, got %v (%v)", x, err)
+		}
+
+		scraper := model.FeedScraper{FeedID: f.Id, Item: "article", Title: "h2"}
+		if ok, err := s.UpdateFeedScraper(scraper); err != nil || !ok {
+			t.Fatalf("expected scraper to be created, got %v (%v)", ok, err)
+		}
+		scraper.Date = "time"
+		if ok, err := s.UpdateFeedScraper(scraper); err != nil || !ok {
+			t.Fatalf("expected scraper to be updated, got %v (%v)", ok, err)
+		}
+		if found, err := s.GetFeedScraper(f.Id); err != nil || found == nil || *found != scraper {
+			t.Errorf("unexpected scraper: %#v (%v)", found, err)
+		}
+
+		if ok, _ := s.UpdateFeedScraper(model.FeedScraper{FeedID: f.Id + 1, Item: "li"}); ok {
+			t.Error("expected no scraper for unknown feed")
+		}
+		if !s.DeleteFeedScraper(f.Id) {
+			t.Error("expected scraper to be deleted")
+		}
+		if s.DeleteFeedScraper(f.Id) {
+			t.Error("expected nothing to delete")
+		}
+
+		// scrapers go away with their feed
+		s.UpdateFeedScraper(scraper)
+		s.DeleteFeed(f.Id)
+		if x, _ := s.GetFeedScraper(f.Id); x != nil {
+			t.Error("expected scraper to be deleted with the feed")
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestSearchQuery(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		tech := db.CreateFolder("Tech News")
+		blog := db.CreateFeed(model.CreateFeedParams{Title: "Go Blog", FeedLink: "http://blog.xml", FolderID: &tech.Id})
+		sec := db.CreateFeed(model.CreateFeedParams{Title: "Security Advisories", FeedLink: "http://sec.xml"})
+
+		date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		items, _ := db.CreateItems([]model.Item{
+			{GUID: "i1", FeedId: blog.Id, Title: "Go release notes", Content: "The new release of go", Author: "Rob Pike", Date: date},
+			{GUID: "i2", FeedId: blog.Id, Title: "Generics", Content: "Notes on the release of generics", Author: "Ian", Date: date.AddDate(0, 0, 1)},
+			{GUID: "i3", FeedId: sec.Id, Title: "Kubernetes CVE", Content: "A CVE in kubernetes 100%", Author: "Team", Date: date.AddDate(0, 0, 2)},
+			{GUID: "i4", FeedId: sec.Id, Title: "Draft: kubernetes hardening", Content: "Hardening guide", Date: date.AddDate(0, 0, 3)},
+		})
+		for _, item := range items {
+			if item.GUID == "i3" {
+				db.UpdateItemStatus(item.Id, model.STARRED)
+			}
+		}
+
+		testcases := []struct {
+			query string
+			want  []string
+		}{
+			{"release", []string{"i1", "i2"}},
+			{`"release notes"`, []string{"i1"}},
+			{"release -generics", []string{"i1"}},
+			{"generics OR cve", []string{"i2", "i3"}},
+			{"title:release", []string{"i1"}},
+			{"title:notes", []string{"i1"}},
+			{"feed:blog", []string{"i1", "i2"}},
+			{"folder:tech", []string{"i1", "i2"}},
+			{"-folder:tech", []string{"i3", "i4"}},
+			{"author:pike", []string{"i1"}},
+			{"kubernetes -title:draft", []string{"i3"}},
+			{"before:2024-05-02", []string{"i1"}},
+			{"after:2024-05-03 feed:security", []string{"i3", "i4"}},
+			{"is:starred", []string{"i3"}},
+			{"kubernetes is:unread", []string{"i4"}},
+			{"author:%", nil},
+			{"100%", []string{"i3"}},
+			{"- OR", []string{"i1", "i2", "i3", "i4"}},
+		}
+		for _, tc := range testcases {
+			query := tc.query
+			have := getItemGuids(db.ListItems(model.ItemFilter{Search: &query}, 10, false, false))
+			sort.Strings(have)
+			if len(have) == 0 {
+				have = nil
+			}
+			if !reflect.DeepEqual(have, tc.want) {
+				t.Errorf("%q: want %v, have %v", tc.query, tc.want, have)
+			}
+		}
+	})
+}
+
+func TestSearchRelevance(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		feed := db.CreateFeed(model.CreateFeedParams{Title: "f", FeedLink: "http://f.xml"})
+		date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		db.CreateItems([]model.Item{
+			{GUID: "once", FeedId: feed.Id, Title: "Weekly digest", Content: "one mention of kubernetes among many other words here", Date: date.AddDate(0, 0, 2)},
+			{GUID: "many", FeedId: feed.Id, Title: "Kubernetes", Content: "kubernetes kubernetes kubernetes", Date: date},
+			{GUID: "none", FeedId: feed.Id, Title: "Other", Content: "nothing", Date: date.AddDate(0, 0, 1)},
+			{GUID: "some", FeedId: feed.Id, Title: "Kubernetes tips", Content: "a few words", Date: date.AddDate(0, 0, 3)},
+		})
+
+		query := "kubernetes"
+		filter := model.ItemFilter{Search: &query, SortByRelevance: true}
+		have := getItemGuids(db.ListItems(filter, 10, true, false))
+		if want := []string{"many", "some", "once"}; !reflect.DeepEqual(have, want) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+
+		// paginated
+		var pages []string
+		for {
+			items := db.ListItems(filter, 1, true, false)
+			if len(items) == 0 {
+				break
+			}
+			pages = append(pages, items[0].GUID)
+			filter.After = &items[0].Id
+		}
+		if !reflect.DeepEqual(pages, have) {
+			t.Errorf("want %v, have %v", have, pages)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestUsers(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		// the default user is created by the migrations
+		admin, err := db.GetUser(model.DefaultUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if admin == nil || !admin.IsAdmin {
+			t.Fatalf("unexpected default user: %#v", admin)
+		}
+
+		user, err := db.CreateUser(model.CreateUserParams{
+			Username:     "alice",
+			PasswordHash: "hash",
+			FeverKey:     "key",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Id == 0 || user.Username != "alice" || user.IsAdmin {
+			t.Fatalf("unexpected user: %#v", user)
+		}
+		if _, err := db.CreateUser(model.CreateUserParams{Username: "alice"}); err == nil {
+			t.Error("expected error on duplicate username")
+		}
+
+		found, err := db.GetUserByName("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Id != user.Id || found.PasswordHash != "hash" || found.FeverKey != "key" {
+			t.Fatalf("unexpected user: %#v", found)
+		}
+		if missing, err := db.GetUserByName("bob"); err != nil || missing != nil {
+			t.Errorf("expected no user, got %#v, %v", missing, err)
+		}
+
+		isAdmin := true
+		if ok, err := db.UpdateUser(user.Id, model.UpdateUserParams{IsAdmin: &isAdmin}); !ok || err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if found, _ := db.GetUser(user.Id); found == nil || !found.IsAdmin || found.PasswordHash != "hash" {
+			t.Errorf("unexpected user after update: %#v", found)
+		}
+
+		users, err := db.ListUsers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 2 || users[0].Id != model.DefaultUserID || users[1].Id != user.Id {
+			t.Errorf("unexpected users: %#v", users)
+		}
+
+		if !db.DeleteUser(user.Id) {
+			t.Fatal("delete failed")
+		}
+		if db.DeleteUser(user.Id) {
+			t.Error("expected false when deleting already-deleted user")
+		}
+		if found, _ := db.GetUser(user.Id); found != nil {
+			t.Error("expected deleted user to be gone")
+		}
+	})
+}
+
+func TestUserIsolation(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+
+		// both users may subscribe to the same feed
+		feed1 := db.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+		feed2 := alice.CreateFeed(model.CreateFeedParams{Title: "feed", FeedLink: "http://example.com/feed.xml"})
+		if feed1 == nil || feed2 == nil || feed1.Id == feed2.Id {
+			t.Fatalf("expected separate feeds, got %#v and %#v", feed1, feed2)
+		}
+		folder := alice.CreateFolder("folder")
+		if folder == nil {
+			t.Fatal("failed to create folder")
+		}
+		db.CreateItems([]model.Item{
+			{GUID: "1", FeedId: feed1.Id, Title: "one"},
+			{GUID: "1", FeedId: feed2.Id, Title: "one"},
+		})
+		theme := "night"
+		alice.UpdateSettings(model.UpdateSettingsParams{ThemeName: &theme})
+
+		if feeds := db.ListFeeds(); len(feeds) != 1 || feeds[0].Id != feed1.Id {
+			t.Errorf("unexpected feeds of the default user: %#v", feeds)
+		}
+		if feeds := alice.ListFeeds(); len(feeds) != 1 || feeds[0].Id != feed2.Id {
+			t.Errorf("unexpected feeds of alice: %#v", feeds)
+		}
+		if feeds := storage.ForUser(db, 0).ListFeeds(); len(feeds) != 2 {
+			t.Errorf("expected feeds of all users, got %#v", feeds)
+		}
+		if folders := db.ListFolders(); len(folders) != 0 {
+			t.Errorf("unexpected folders of the default user: %#v", folders)
+		}
+		if db.CreateFeed(model.CreateFeedParams{FeedLink: "http://example.com/other.xml", FolderID: &folder.Id}) != nil {
+			t.Error("expected the folder of alice to be rejected for new feeds")
+		}
+		if _, err := db.UpdateFeed(feed1.Id, model.UpdateFeedParams{FolderID: model.SetNullable(&folder.Id)}); err == nil {
+			t.Error("expected the folder of alice to be rejected for updates")
+		}
+		if _, err := alice.UpdateFeed(feed2.Id, model.UpdateFeedParams{FolderID: model.SetNullable(&folder.Id)}); err != nil {
+			t.Errorf("expected alice to use their own folder: %s", err)
+		}
+		if db.GetFeed(feed2.Id) != nil {
+			t.Error("expected feed of alice to be hidden")
+		}
+		if db.DeleteFeed(feed2.Id); alice.GetFeed(feed2.Id) == nil {
+			t.Error("expected feed of alice to survive deletion by another user")
+		}
+
+		items := alice.ListItems(model.ItemFilter{}, 10, false, false)
+		if len(items) != 1 || items[0].FeedId != feed2.Id {
+			t.Fatalf("unexpected items of alice: %#v", items)
+		}
+		if db.GetItem(items[0].Id) != nil {
+			t.Error("expected item of alice to be hidden")
+		}
+		db.UpdateItemStatus(items[0].Id, model.READ)
+		if item := alice.GetItem(items[0].Id); item.Status != model.UNREAD {
+			t.Error("expected item of alice to be untouched")
+		}
+		if db.CountItems(model.ItemFilter{}) != 1 || alice.CountItems(model.ItemFilter{}) != 1 {
+			t.Errorf("unexpected item counts: %d, %d", db.CountItems(model.ItemFilter{}), alice.CountItems(model.ItemFilter{}))
+		}
+
+		if db.GetSettings().ThemeName == "night" || alice.GetSettings().ThemeName != "night" {
+			t.Error("expected settings to be per user")
+		}
+
+		alice.CreateLabel("label")
+		alice.CreateRule(model.Rule{Action: model.RuleMarkRead})
+		alice.CreateSavedSearch(model.SavedSearch{Name: "search", Query: "go"})
+		alice.CreateWebhook(model.Webhook{URL: "http://example.com/hook"})
+		alice.CreateOutputFeed(model.OutputFeed{Source: model.OutputStarred, Token: "token"})
+		alice.CreateAPIKey(model.CreateAPIKeyParams{Name: "key", TokenHash: "hash"})
+
+		if !db.DeleteUser(user.Id) {
+			t.Fatal("delete failed")
+		}
+		all := storage.ForUser(db, 0)
+		if feeds := all.ListFeeds(); len(feeds) != 1 || feeds[0].Id != feed1.Id {
+			t.Errorf("expected feeds of the deleted user to be gone, got %#v", feeds)
+		}
+		if db.CountItems(model.ItemFilter{}) != 1 {
+			t.Errorf("expected items of the deleted user to be gone")
+		}
+		rules, _ := all.ListRules()
+		searches, _ := all.ListSavedSearches()
+		hooks, _ := all.ListWebhooks()
+		outputs, _ := all.ListOutputFeeds()
+		keys, _ := all.ListAPIKeys()
+		if len(all.ListFolders())+len(all.ListLabels())+len(rules)+len(searches)+len(hooks)+len(outputs)+len(keys) != 0 {
+			t.Error("expected the data of the deleted user to be gone")
+		}
+		if alice.GetSettings().ThemeName == "night" {
+			t.Error("expected the settings of the deleted user to be gone")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestWebhooks(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		folder := db.CreateFolder("news")
+		feed1 := db.CreateFeed(model.CreateFeedParams{Title: "feed1", FeedLink: "http://example.com/feed1.xml", FolderID: &folder.Id})
+		feed2 := db.CreateFeed(model.CreateFeedParams{Title: "feed2", FeedLink: "http://example.com/feed2.xml"})
+
+		global, err := db.CreateWebhook(model.Webhook{URL: "http://example.com/all", Format: model.WebhookJSON, IsEnabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byFolder, err := db.CreateWebhook(model.Webhook{URL: "http://example.com/folder", FolderID: &folder.Id, Format: model.WebhookForm, IsEnabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateWebhook(model.Webhook{URL: "http://example.com/x", FeedID: new(int64)}); err == nil {
+			t.Error("expected error for unknown feed")
+		}
+		if _, err := db.CreateWebhook(model.Webhook{URL: "http://example.com/x", FolderID: new(int64)}); err == nil {
+			t.Error("expected error for unknown folder")
+		}
+
+		hook, err := db.GetWebhook(byFolder.Id)
+		if err != nil || hook == nil || hook.URL != "http://example.com/folder" || hook.Format != model.WebhookForm {
+			t.Fatalf("unexpected webhook: %#v (%v)", hook, err)
+		}
+		if hooks, _ := db.ListFeedWebhooks(feed1.Id); len(hooks) != 2 {
+			t.Errorf("expected 2 webhooks for feed1, got %#v", hooks)
+		}
+		if hooks, _ := db.ListFeedWebhooks(feed2.Id); len(hooks) != 1 || hooks[0].Id != global.Id {
+			t.Errorf("expected the global webhook for feed2, got %#v", hooks)
+		}
+
+		byFolder.IsEnabled = false
+		if ok, err := db.UpdateWebhook(byFolder.Id, *byFolder); !ok || err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if hooks, _ := db.ListFeedWebhooks(feed1.Id); len(hooks) != 1 {
+			t.Errorf("expected disabled webhook to be skipped, got %#v", hooks)
+		}
+
+		db.DeleteFolder(folder.Id)
+		if hook, _ := db.GetWebhook(byFolder.Id); hook != nil {
+			t.Error("expected webhook to be deleted with its folder")
+		}
+		if !db.DeleteWebhook(global.Id) {
+			t.Error("delete failed")
+		}
+		if hooks, _ := db.ListWebhooks(); len(hooks) != 0 {
+			t.Errorf("expected no webhooks, got %#v", hooks)
+		}
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	dbtest(t, func(t *testing.T, db storage.Storage) {
+		hook, err := db.CreateWebhook(model.Webhook{URL: "http://example.com/hook", IsEnabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		for i := 1; i <= 105; i++ {
+			ok, err := db.CreateWebhookDelivery(model.WebhookDelivery{WebhookID: hook.Id, Attempt: i, CreatedAt: now})
+			if !ok || err != nil {
+				t.Fatalf("failed to log delivery: %v", err)
+			}
+		}
+		deliveries, err := db.ListWebhookDeliveries(hook.Id, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 100 || deliveries[0].Attempt != 105 || deliveries[99].Attempt != 6 {
+			t.Errorf("expected the last 100 deliveries, got %d", len(deliveries))
+		}
+
+		user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := storage.ForUser(db, user.Id)
+		if ok, _ := alice.CreateWebhookDelivery(model.WebhookDelivery{WebhookID: hook.Id, CreatedAt: now}); ok {
+			t.Error("expected webhooks of other users to be rejected")
+		}
+		if deliveries, _ := alice.ListWebhookDeliveries(hook.Id, 10); len(deliveries) != 0 {
+			t.Errorf("expected deliveries of other users to be hidden, got %d", len(deliveries))
+		}
+	})
+}
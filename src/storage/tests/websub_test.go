@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestWebSubSubscriptions(t *testing.T) {
+	dbtest(t, func(t *testing.T, s storage.Storage) {
+		f := s.CreateFeed(model.CreateFeedParams{Title: "Test", FeedLink: "http://example.com/feed"})
+		if sub, err := s.GetWebSubSubscription(f.Id); err != nil || sub != nil {
+			t.Fatalf("expected no subscription, got %v (%v)", sub, err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		sub := model.WebSubSubscription{
+			FeedID:      f.Id,
+			Hub:         "http://hub.example.com",
+			Topic:       "http://example.com/feed",
+			Secret:      "secret",
+			RequestedAt: now,
+		}
+		if ok, err := s.UpdateWebSubSubscription(sub); err != nil || !ok {
+			t.Fatalf("expected subscription to be created, got %v (%v)", ok, err)
+		}
+		expires := now.Add(time.Hour)
+		sub.ExpiresAt = &expires
+		if ok, err := s.UpdateWebSubSubscription(sub); err != nil || !ok {
+			t.Fatalf("expected subscription to be updated, got %v (%v)", ok, err)
+		}
+		found, err := s.GetWebSubSubscription(f.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Secret != "secret" || !found.RequestedAt.Equal(now) || found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) {
+			t.Errorf("unexpected subscription: %#v", found)
+		}
+
+		if ok, _ := s.UpdateWebSubSubscription(model.WebSubSubscription{FeedID: f.Id + 1, RequestedAt: now}); ok {
+			t.Error("expected no subscription for unknown feed")
+		}
+		if !s.DeleteWebSubSubscription(f.Id) {
+			t.Error("expected subscription to be deleted")
+		}
+		if s.DeleteWebSubSubscription(f.Id) {
+			t.Error("expected nothing to delete")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+)
+
+// BackupConfig describes the periodic backups of the database.
+// The Keep most recent backups are kept, zero means all of them.
+type BackupConfig struct {
+	Dir      string
+	Keep     int
+	Interval time.Duration
+}
+
+// names of the files written by storage.BackupName
+var backupFileName = regexp.MustCompile(`^yarr-backup-\d{8}-\d{6}\.(db|json)$`)
+
+// StartBackups backs up the database every interval, then deletes
+// the oldest backups.
+func (w *Worker) StartBackups(cfg BackupConfig) {
+	if cfg.Dir == "" || cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	go func() {
+		for {
+			<-ticker.C
+			if err := backup(w.db, cfg); err != nil {
+				log.Printf("Failed to back up the database: %s", err)
+			}
+		}
+	}()
+}
+
+func backup(db storage.Storage, cfg BackupConfig) error {
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(cfg.Dir, storage.BackupName(db, time.Now()))
+	if err := storage.Backup(db, path); err != nil {
+		return err
+	}
+	log.Printf("Database backed up to %s", path)
+	return rotateBackups(cfg.Dir, cfg.Keep)
+}
+
+// rotateBackups deletes all but the keep most recent backups.
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && backupFileName.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	// the names sort by date
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names[min(keep, len(names)):] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"yarr-backup-20240101-000000.db",
+		"yarr-backup-20240102-000000.db",
+		"yarr-backup-20240103-000000.json",
+		"yarr-backup-20240104-000000.db",
+		"notes.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rotateBackups(dir, 2); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	var have []string
+	for _, entry := range entries {
+		have = append(have, entry.Name())
+	}
+	sort.Strings(have)
+	want := []string{"notes.txt", "yarr-backup-20240103-000000.json", "yarr-backup-20240104-000000.db"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("unexpected files: %v", have)
+	}
+}
@@ -1,8 +1,13 @@
 package worker
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -30,10 +35,47 @@ func (c *Client) getConditional(url, lastModified, etag string) (*http.Response,
 	return c.httpClient.Do(req)
 }
 
+func (c *Client) postForm(url string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.httpClient.Do(req)
+}
+
 var client *Client
 
+// mediaClient downloads enclosures, which take longer than feeds and pages.
+var mediaClient *Client
+
+// publicClient only connects to public addresses, for requests to urls
+// picked by non-admin users (webhooks).
+var publicClient *Client
+
+var errNotPublic = errors.New("address is not public")
+
+// dialPublic refuses connections to loopback, private, link-local (which
+// includes cloud metadata services) and unspecified addresses. Done once the
+// name is resolved, the check also covers redirects and DNS rebinding.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%s: %w", host, errNotPublic)
+	}
+	return nil
+}
+
 func SetVersion(num string) {
 	client.userAgent = "Yarr/" + num
+	mediaClient.userAgent = "Yarr/" + num
+	publicClient.userAgent = "Yarr/" + num
 }
 
 func init() {
@@ -54,4 +96,26 @@ func init() {
 		httpClient: httpClient,
 		userAgent:  "Yarr/1.0",
 	}
+
+	// a transport of its own, keeping connections alive between the
+	// enclosures of a feed, which are often on the same host
+	mediaTransport := transport.Clone()
+	mediaTransport.DisableKeepAlives = false
+	mediaTransport.ResponseHeaderTimeout = 30 * time.Second
+	mediaClient = &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Minute, Transport: mediaTransport},
+		userAgent:  "Yarr/1.0",
+	}
+
+	// no proxy: it would connect to any address on our behalf
+	publicTransport := transport.Clone()
+	publicTransport.Proxy = nil
+	publicTransport.DialContext = (&net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: dialPublic,
+	}).DialContext
+	publicClient = &Client{
+		httpClient: &http.Client{Timeout: time.Second * 30, Transport: publicTransport},
+		userAgent:  "Yarr/1.0",
+	}
 }
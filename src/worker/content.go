@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nkanaev/yarr/src/content/readability"
+	"github.com/nkanaev/yarr/src/content/silo"
+	"github.com/nkanaev/yarr/src/storage/model"
+	"golang.org/x/net/html/charset"
+)
+
+// minimum delay between two article downloads from the same host
+const contentFetchInterval = 2 * time.Second
+
+// hostLimiter spaces out requests to the same host.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// wait blocks until a request to the host is allowed.
+func (l *hostLimiter) wait(host string) {
+	l.mu.Lock()
+	now := time.Now()
+	for h, t := range l.next {
+		if t.Before(now) {
+			delete(l.next, h)
+		}
+	}
+	at := now
+	if next, ok := l.next[host]; ok && next.After(now) {
+		at = next
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+var contentLimiter = newHostLimiter(contentFetchInterval)
+
+// fetchContent downloads the page and extracts the article from it.
+func fetchContent(link string) (string, error) {
+	link = silo.RedirectURL(link)
+	if content := silo.VideoIFrame(link); content != "" {
+		return content, nil
+	}
+	if u, err := url.Parse(link); err == nil {
+		contentLimiter.wait(u.Host)
+	}
+
+	res, err := client.get(link)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", &statusError{StatusCode: res.StatusCode}
+	}
+
+	var r io.Reader = res.Body
+	if ctype := res.Header.Get("Content-Type"); strings.Contains(ctype, "charset") {
+		if r, err = charset.NewReader(res.Body, ctype); err != nil {
+			return "", err
+		}
+	}
+	return readability.ExtractContent(r)
+}
+
+// fetchContents stores the full article of each item. Items whose page
+// can't be fetched keep only the content from the feed.
+func (w *Worker) fetchContents(items []model.Item) {
+	for _, item := range items {
+		if item.Link == "" {
+			continue
+		}
+		content, err := fetchContent(item.Link)
+		if err != nil {
+			log.Printf("Failed to fetch content of %s: %s", item.Link, err)
+			continue
+		}
+		if content == "" {
+			continue
+		}
+		w.db.UpdateItem(item.Id, model.UpdateItemParams{FullContent: &content})
+	}
+}
@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter(50 * time.Millisecond)
+	start := time.Now()
+	l.wait("a.com")
+	l.wait("b.com")
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("different hosts shouldn't wait, took %s", elapsed)
+	}
+	l.wait("a.com")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("same host should wait, took %s", elapsed)
+	}
+}
+
+func TestFetchContents(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	defer func(l *hostLimiter) { contentLimiter = l }(contentLimiter)
+	contentLimiter = newHostLimiter(0)
+
+	article := "<p>" + strings.Repeat("The full text of the article, with commas, and more. ", 20) + "</p>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/article" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><body><nav>menu</nav><article>"+article+"</article></body></html>")
+	}))
+	defer srv.Close()
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "yarr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Feed", FeedLink: srv.URL + "/feed.xml"})
+	created, _ := db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, Link: srv.URL + "/article", Content: "summary"},
+		{GUID: "2", FeedId: feed.Id, Link: srv.URL + "/missing", Content: "summary"},
+	})
+
+	w := NewWorker(db, nil)
+	w.fetchContents(created)
+
+	if item := db.GetItem(created[0].Id); !strings.Contains(item.FullContent, "full text of the article") {
+		t.Errorf("expected full content, got %q", item.FullContent)
+	}
+	// failed downloads fall back to the summary
+	if item := db.GetItem(created[1].Id); item.FullContent != "" || item.Content != "summary" {
+		t.Errorf("unexpected item: %#v", item)
+	}
+}
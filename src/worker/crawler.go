@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -74,6 +76,44 @@ func DiscoverFeed(candidateUrl string) (*DiscoverResult, error) {
 	return result, nil
 }
 
+// ScrapeFeed fetches a web page without a feed and extracts its items.
+func ScrapeFeed(pageURL string, sel parser.Selectors) (*DiscoverResult, error) {
+	if err := sel.Validate(); err != nil {
+		return nil, err
+	}
+	res, err := client.get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("status code %d", res.StatusCode)
+	}
+	feed, err := scrapePage(res, pageURL, sel)
+	if err != nil {
+		return nil, err
+	}
+	return &DiscoverResult{Feed: feed, FeedLink: pageURL}, nil
+}
+
+func scrapePage(res *http.Response, pageURL string, sel parser.Selectors) (*parser.Feed, error) {
+	r, err := charset.NewReader(res.Body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	return parser.Scrape(r, pageURL, sel)
+}
+
+func ScraperSelectors(s model.FeedScraper) parser.Selectors {
+	return parser.Selectors{
+		Item:    s.Item,
+		Title:   s.Title,
+		Link:    s.Link,
+		Date:    s.Date,
+		Content: s.Content,
+	}
+}
+
 var emptyIcon = make(model.Icon, 0)
 var imageTypes = map[string]bool{
 	"image/x-icon": true,
@@ -141,20 +181,48 @@ func ConvertItems(items []parser.Item, feed model.Feed) []model.Item {
 			mediaLinks = append(mediaLinks, model.MediaLink(link))
 		}
 		result[i] = model.Item{
-			GUID:       item.GUID,
-			FeedId:     feed.Id,
-			Title:      item.Title,
-			Link:       item.URL,
-			Content:    item.Content,
-			Date:       item.Date,
-			Status:     model.UNREAD,
-			MediaLinks: mediaLinks,
+			GUID:        item.GUID,
+			FeedId:      feed.Id,
+			Title:       item.Title,
+			Link:        item.URL,
+			Content:     item.Content,
+			Date:        item.Date,
+			Status:      model.UNREAD,
+			MediaLinks:  mediaLinks,
+			Author:      item.Author,
+			Categories:  item.Categories,
+			CommentsURL: item.CommentsURL,
+			Podcast:     convertPodcast(item.Podcast),
 		}
 	}
 	return result
 }
 
-func listItems(f model.Feed, db storage.Storage) ([]model.Item, error) {
+func convertPodcast(p *parser.Podcast) *model.Podcast {
+	if p == nil {
+		return nil
+	}
+	out := &model.Podcast{
+		Image:   p.Image,
+		Season:  p.Season,
+		Episode: p.Episode,
+	}
+	if p.Chapters != nil {
+		chapters := model.PodcastLink(*p.Chapters)
+		out.Chapters = &chapters
+	}
+	for _, t := range p.Transcripts {
+		out.Transcripts = append(out.Transcripts, model.PodcastLink(t))
+	}
+	for _, person := range p.Persons {
+		out.Persons = append(out.Persons, model.PodcastPerson(person))
+	}
+	return out
+}
+
+// listItems fetches the feed and returns its items along with the parsed
+// feed (nil if the feed hasn't changed since the last refresh).
+func listItems(f model.Feed, db storage.Storage) ([]model.Item, *parser.Feed, error) {
 	lmod := ""
 	etag := ""
 	if state, _ := db.GetFeedState(f.Id); state != nil {
@@ -164,23 +232,40 @@ func listItems(f model.Feed, db storage.Storage) ([]model.Item, error) {
 
 	res, err := client.getConditional(f.FeedLink, lmod, etag)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode < 400 {
+		if link, status := permanentRedirect(res); link != "" && link != f.FeedLink {
+			moveFeed(db, f, link, status)
+		}
+	}
+
 	switch {
 	case res.StatusCode < 200 || res.StatusCode > 399:
-		if res.StatusCode == 404 {
-			return nil, fmt.Errorf("feed not found")
+		err := &statusError{StatusCode: res.StatusCode}
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+			err.RetryAfter = retryAfter(res.Header.Get("Retry-After"), time.Now())
 		}
-		return nil, fmt.Errorf("status code %d", res.StatusCode)
+		return nil, nil, err
 	case res.StatusCode == http.StatusNotModified:
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	feed, err := parser.ParseAndFix(res.Body, f.FeedLink, getCharset(res))
-	if err != nil {
-		return nil, err
+	var feed *parser.Feed
+	if scraper, err := db.GetFeedScraper(f.Id); err != nil {
+		return nil, nil, err
+	} else if scraper != nil {
+		feed, err = scrapePage(res, f.FeedLink, ScraperSelectors(*scraper))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		feed, err = parser.ParseAndFix(res.Body, f.FeedLink, getCharset(res))
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	lmod = res.Header.Get("Last-Modified")
@@ -193,11 +278,80 @@ func listItems(f model.Feed, db storage.Storage) ([]model.Item, error) {
 			LastRefreshed:    &now,
 		})
 	}
-	return ConvertItems(feed.Items, f), nil
+	return ConvertItems(feed.Items, f), feed, nil
+}
+
+// permanentRedirect returns where the feed has permanently moved to, following
+// the leading 301/308 hops of the redirect chain. Temporary redirects
+// (302/303/307) stop the walk, so their targets are never persisted.
+func permanentRedirect(res *http.Response) (string, int) {
+	hops := make([]*http.Request, 0)
+	for req := res.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hops = append(hops, req)
+	}
+	link, status := "", 0
+	for i := len(hops) - 1; i >= 0; i-- {
+		code := hops[i].Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			break
+		}
+		link, status = hops[i].URL.String(), code
+	}
+	return link, status
+}
+
+// moveFeed updates the feed link and records the change in the feed's history.
+func moveFeed(db storage.Storage, f model.Feed, link string, status int) {
+	if _, err := db.UpdateFeed(f.Id, model.UpdateFeedParams{FeedLink: &link}); err != nil {
+		log.Printf("Failed to move feed %s to %s: %s", f.FeedLink, link, err)
+		return
+	}
+	log.Printf("Feed %s moved permanently to %s", f.FeedLink, link)
+	db.CreateFeedLinkChange(f.Id, model.FeedLinkChange{
+		OldLink:    f.FeedLink,
+		NewLink:    link,
+		StatusCode: status,
+		CreatedAt:  time.Now().UTC(),
+	})
+}
+
+// statusError is returned when the feed server responds with an error.
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return "feed not found"
+	case http.StatusGone:
+		return "feed is gone"
+	}
+	return fmt.Sprintf("status code %d", e.StatusCode)
+}
+
+// retryAfter parses the Retry-After header (delay-seconds or HTTP date),
+// capped at maxBackoff so that a feed can't put itself off indefinitely.
+func retryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(min(max(seconds, 0), int64(maxBackoff/time.Second))) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return min(max(date.Sub(now), 0), maxBackoff)
+	}
+	return 0
 }
 
 func getCharset(res *http.Response) string {
-	contentType := res.Header.Get("Content-Type")
+	return charsetOf(res.Header.Get("Content-Type"))
+}
+
+func charsetOf(contentType string) string {
 	if _, params, err := mime.ParseMediaType(contentType); err == nil {
 		if cs, ok := params["charset"]; ok {
 			if e, _ := charset.Lookup(cs); e != nil {
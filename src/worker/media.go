@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// MediaConfig describes where enclosures of feeds with DownloadMedia
+// are stored and how much of them is kept. Zero limits mean no limit.
+type MediaConfig struct {
+	Dir       string
+	MaxSize   int64 // bytes, in total
+	MaxFiles  int
+	Retention time.Duration
+}
+
+// names of the downloaded files: `<item id>-<hash of the url><ext>`
+var (
+	mediaFileName = regexp.MustCompile(`^\d+-[0-9a-f]{16}(\.[0-9a-z]{1,5})?$`)
+	mediaFileExt  = regexp.MustCompile(`^\.[0-9a-z]{1,5}$`)
+)
+
+func (w *Worker) SetMediaConfig(cfg MediaConfig) {
+	w.media = cfg
+}
+
+// downloadMedia stores local copies of the audio and video enclosures
+// of the items, then drops the files exceeding the quotas.
+func (w *Worker) downloadMedia(items []model.Item) {
+	if w.media.Dir == "" {
+		return
+	}
+	w.mediaLock.Lock()
+	defer w.mediaLock.Unlock()
+
+	for _, item := range items {
+		for _, link := range item.MediaLinks {
+			if link.Type != "audio" && link.Type != "video" {
+				continue
+			}
+			if err := w.downloadMediaLink(item.Id, link); err != nil {
+				log.Printf("Failed to download %s: %s", link.URL, err)
+			}
+		}
+	}
+	w.pruneMedia()
+}
+
+func (w *Worker) downloadMediaLink(itemID int64, link model.MediaLink) error {
+	if err := os.MkdirAll(w.media.Dir, 0755); err != nil {
+		return err
+	}
+
+	res, err := mediaClient.get(link.URL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &statusError{StatusCode: res.StatusCode}
+	}
+	maxSize := w.media.MaxSize
+	if maxSize > 0 && res.ContentLength > maxSize {
+		return fmt.Errorf("file size (%d bytes) exceeds the quota", res.ContentLength)
+	}
+
+	// incomplete downloads are kept under a name the cleaner won't touch
+	tmp, err := os.CreateTemp(w.media.Dir, "*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var body io.Reader = res.Body
+	if maxSize > 0 {
+		body = io.LimitReader(res.Body, maxSize+1)
+	}
+	size, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("file size exceeds the quota")
+	}
+
+	hash := sha256.Sum256([]byte(link.URL))
+	name := fmt.Sprintf("%d-%x%s", itemID, hash[:8], mediaExt(link.URL))
+	if err := os.Rename(tmp.Name(), filepath.Join(w.media.Dir, name)); err != nil {
+		return err
+	}
+
+	mimeType := link.MimeType
+	if mimeType == "" {
+		mimeType, _, _ = mime.ParseMediaType(res.Header.Get("Content-Type"))
+	}
+	_, err = w.db.CreateMediaFile(model.MediaFile{
+		ItemID:   itemID,
+		URL:      link.URL,
+		Path:     name,
+		MimeType: mimeType,
+		Size:     size,
+	})
+	if err != nil {
+		os.Remove(filepath.Join(w.media.Dir, name))
+	}
+	return err
+}
+
+// mediaExt returns the extension of the file the url points to, if sane.
+func mediaExt(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if !mediaFileExt.MatchString(ext) {
+		return ""
+	}
+	return ext
+}
+
+// pruneMedia deletes the oldest files past the retention period or
+// exceeding the quotas, as well as the files of deleted items.
+func (w *Worker) pruneMedia() {
+	if w.media.Dir == "" {
+		return
+	}
+	files, err := w.db.ListMediaFiles(nil)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	count := len(files)
+
+	now := time.Now()
+	known := make(map[string]bool, len(files))
+	for _, file := range files {
+		expired := w.media.Retention > 0 && now.Sub(file.CreatedAt) > w.media.Retention
+		overQuota := (w.media.MaxSize > 0 && total > w.media.MaxSize) ||
+			(w.media.MaxFiles > 0 && count > w.media.MaxFiles)
+		if !expired && !overQuota {
+			known[file.Path] = true
+			continue
+		}
+		if !w.db.DeleteMediaFile(file.Id) {
+			known[file.Path] = true
+			continue
+		}
+		total -= file.Size
+		count--
+	}
+
+	entries, err := os.ReadDir(w.media.Dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Print(err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && mediaFileName.MatchString(entry.Name()) && !known[entry.Name()] {
+			if err := os.Remove(filepath.Join(w.media.Dir, entry.Name())); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestDownloadMedia(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.mp3", "/2.mp3", "/3.mp3":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			io.WriteString(w, strings.Repeat("a", 100))
+		case "/big.mp4":
+			io.WriteString(w, strings.Repeat("v", 1000))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "yarr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Feed", FeedLink: srv.URL + "/feed.xml"})
+	created, _ := db.CreateItems([]model.Item{
+		{GUID: "1", FeedId: feed.Id, MediaLinks: model.MediaLinks{{URL: srv.URL + "/1.mp3", Type: "audio"}}},
+		{GUID: "2", FeedId: feed.Id, MediaLinks: model.MediaLinks{
+			{URL: srv.URL + "/2.mp3", Type: "audio"},
+			{URL: srv.URL + "/cover.jpg", Type: "image"},
+		}},
+		{GUID: "3", FeedId: feed.Id, MediaLinks: model.MediaLinks{
+			{URL: srv.URL + "/big.mp4", Type: "video"},
+			{URL: srv.URL + "/missing.mp3", Type: "audio"},
+		}},
+	})
+
+	dir := filepath.Join(t.TempDir(), "media")
+	w := NewWorker(db, nil)
+	w.SetMediaConfig(MediaConfig{Dir: dir, MaxSize: 500})
+	w.downloadMedia(created)
+
+	files, err := db.ListMediaFiles(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// images are skipped, as well as files exceeding the quota or failing to download
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %#v", files)
+	}
+	for _, file := range files {
+		if file.Size != 100 || file.MimeType != "audio/mpeg" || !strings.HasSuffix(file.Path, ".mp3") {
+			t.Errorf("unexpected file: %#v", file)
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, file.Path)); err != nil || len(data) != 100 {
+			t.Errorf("unexpected file content: %d bytes, %v", len(data), err)
+		}
+	}
+
+	// the oldest files go first once the quota is exceeded
+	w.SetMediaConfig(MediaConfig{Dir: dir, MaxFiles: 1})
+	more, _ := db.CreateItems([]model.Item{
+		{GUID: "4", FeedId: feed.Id, MediaLinks: model.MediaLinks{{URL: srv.URL + "/3.mp3", Type: "audio"}}},
+	})
+	w.downloadMedia(more)
+
+	files, _ = db.ListMediaFiles(nil)
+	if len(files) != 1 || files[0].ItemID != more[0].Id {
+		t.Fatalf("expected only the newest file, got %#v", files)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != files[0].Path {
+		t.Errorf("unexpected files on disk: %v", entries)
+	}
+}
@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/nkanaev/yarr/src/content/htmlutil"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// Rule is a model.Rule compiled for matching items.
+type Rule struct {
+	model.Rule
+	matchers []func(model.Item) bool
+}
+
+// CompileRule validates the rule. All the conditions of a rule must match.
+//
+// Fields:
+//   - title, content (as text), link
+//   - domain: the host of the link, including its subdomains for `equals`
+//
+// Operators: contains, not_contains, equals (all case-insensitive), matches (regexp).
+func CompileRule(rule model.Rule) (*Rule, error) {
+	switch rule.Action {
+	case model.RuleMarkRead, model.RuleStar, model.RuleDrop:
+	default:
+		return nil, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	if len(rule.Conditions) == 0 && rule.FeedID == nil {
+		return nil, fmt.Errorf("rule without conditions must target a feed")
+	}
+
+	compiled := &Rule{Rule: rule}
+	for _, cond := range rule.Conditions {
+		var field func(model.Item) string
+		switch cond.Field {
+		case "title":
+			field = func(item model.Item) string { return item.Title }
+		case "content":
+			field = func(item model.Item) string { return htmlutil.ExtractText(item.Content) }
+		case "link":
+			field = func(item model.Item) string { return item.Link }
+		case "domain":
+			field = func(item model.Item) string { return htmlutil.URLDomain(item.Link) }
+		default:
+			return nil, fmt.Errorf("unknown field %q", cond.Field)
+		}
+
+		value := strings.ToLower(cond.Value)
+		var match func(string) bool
+		switch cond.Op {
+		case "contains":
+			match = func(s string) bool { return strings.Contains(strings.ToLower(s), value) }
+		case "not_contains":
+			match = func(s string) bool { return !strings.Contains(strings.ToLower(s), value) }
+		case "equals":
+			if cond.Field == "domain" {
+				match = func(s string) bool {
+					s = strings.ToLower(s)
+					return s == value || strings.HasSuffix(s, "."+value)
+				}
+			} else {
+				match = func(s string) bool { return strings.ToLower(s) == value }
+			}
+		case "matches":
+			// case-insensitive like the other operators, unless the pattern says otherwise with (?-i)
+			re, err := regexp.Compile("(?i)" + cond.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", cond.Value, err)
+			}
+			match = re.MatchString
+		default:
+			return nil, fmt.Errorf("unknown operator %q", cond.Op)
+		}
+		compiled.matchers = append(compiled.matchers, func(item model.Item) bool {
+			return match(field(item))
+		})
+	}
+	return compiled, nil
+}
+
+func (r *Rule) Match(item model.Item) bool {
+	if r.FeedID != nil && *r.FeedID != item.FeedId {
+		return false
+	}
+	for _, match := range r.matchers {
+		if !match(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileRules skips invalid rules.
+func CompileRules(rules []model.Rule) []*Rule {
+	result := make([]*Rule, 0, len(rules))
+	for _, rule := range rules {
+		compiled, err := CompileRule(rule)
+		if err != nil {
+			log.Printf("Skipping rule %d: %s", rule.Id, err)
+			continue
+		}
+		result = append(result, compiled)
+	}
+	return result
+}
+
+// ApplyRules drops the matching items or sets their status.
+// Starring takes precedence over marking as read.
+func ApplyRules(rules []*Rule, items []model.Item) []model.Item {
+	if len(rules) == 0 {
+		return items
+	}
+	result := make([]model.Item, 0, len(items))
+	for _, item := range items {
+		drop := false
+		for _, rule := range rules {
+			if !rule.Match(item) {
+				continue
+			}
+			switch rule.Action {
+			case model.RuleDrop:
+				drop = true
+			case model.RuleStar:
+				item.Status = model.STARRED
+			case model.RuleMarkRead:
+				if item.Status == model.UNREAD {
+					item.Status = model.READ
+				}
+			}
+		}
+		if !drop {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// ApplyFeedRules applies the rules of the feed owner before the items are stored.
+func ApplyFeedRules(db storage.Storage, feed model.Feed, items []model.Item) []model.Item {
+	if len(items) == 0 {
+		return items
+	}
+	rules, err := db.ListFeedRules(feed.Id)
+	if err != nil {
+		log.Print(err)
+		return items
+	}
+	return ApplyRules(CompileRules(rules), items)
+}
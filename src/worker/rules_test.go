@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestCompileRuleErrors(t *testing.T) {
+	feedID := int64(1)
+	invalid := []model.Rule{
+		{Action: "delete", Conditions: model.RuleConditions{{Field: "title", Op: "contains", Value: "x"}}},
+		{Action: model.RuleDrop, Conditions: model.RuleConditions{{Field: "author", Op: "contains", Value: "x"}}},
+		{Action: model.RuleDrop, Conditions: model.RuleConditions{{Field: "title", Op: "starts", Value: "x"}}},
+		{Action: model.RuleDrop, Conditions: model.RuleConditions{{Field: "title", Op: "matches", Value: "("}}},
+		{Action: model.RuleDrop},
+	}
+	for _, rule := range invalid {
+		if _, err := CompileRule(rule); err == nil {
+			t.Errorf("expected error for %#v", rule)
+		}
+	}
+	if _, err := CompileRule(model.Rule{Action: model.RuleMarkRead, FeedID: &feedID}); err != nil {
+		t.Errorf("expected feed-wide rule to be valid, got %s", err)
+	}
+}
+
+func TestRuleMatchesIgnoresCase(t *testing.T) {
+	item := model.Item{Title: "SPONSORED post"}
+	tests := []struct {
+		pattern string
+		match   bool
+	}{
+		{`^sponsored\b`, true},
+		{`(?-i)^sponsored\b`, false},
+		{`(?-i)^SPONSORED\b`, true},
+	}
+	for _, test := range tests {
+		rule, err := CompileRule(model.Rule{Action: model.RuleMarkRead, Conditions: model.RuleConditions{
+			{Field: "title", Op: "matches", Value: test.pattern},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.Match(item) != test.match {
+			t.Errorf("expected %q matching %q to be %v", test.pattern, item.Title, test.match)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	feedID := int64(2)
+	rules := CompileRules([]model.Rule{
+		{Action: model.RuleMarkRead, Conditions: model.RuleConditions{
+			{Field: "title", Op: "matches", Value: `\bsponsored\b`},
+		}},
+		{Action: model.RuleStar, FeedID: &feedID, Conditions: model.RuleConditions{
+			{Field: "content", Op: "contains", Value: "cve"},
+		}},
+		{Action: model.RuleDrop, Conditions: model.RuleConditions{
+			{Field: "domain", Op: "equals", Value: "spam.example"},
+		}},
+	})
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+
+	items := []model.Item{
+		{GUID: "1", FeedId: 1, Title: "Sponsored: buy now", Link: "https://example.com/1"},
+		{GUID: "2", FeedId: 1, Title: "Advisory", Content: "<p>Fixes CVE-2024-1234</p>", Link: "https://example.com/2"},
+		{GUID: "3", FeedId: 2, Title: "Advisory", Content: "<p>Fixes CVE-2024-1234</p>", Link: "https://example.com/3"},
+		{GUID: "4", FeedId: 2, Title: "Hello", Link: "https://www.spam.example/4"},
+		{GUID: "5", FeedId: 2, Title: "Hello", Link: "https://notspam.example/5"},
+	}
+	result := ApplyRules(rules, items)
+
+	statuses := make(map[string]model.ItemStatus)
+	for _, item := range result {
+		statuses[item.GUID] = item.Status
+	}
+	want := map[string]model.ItemStatus{
+		"1": model.READ,
+		"2": model.UNREAD,
+		"3": model.STARRED,
+		"5": model.UNREAD,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("unexpected items: %#v", result)
+	}
+	for guid, status := range want {
+		if got, ok := statuses[guid]; !ok || got != status {
+			t.Errorf("item %s: expected %v, got %v (present: %v)", guid, status, got, ok)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"slices"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+// Bounds for the interval adapted to the observed posting frequency.
+const (
+	minAdaptiveInterval = 5 * time.Minute
+	maxAdaptiveInterval = 24 * time.Hour
+)
+
+// refreshInterval picks how long to wait before refreshing a feed again.
+// A per-feed override (minutes) always wins. Otherwise the interval starts
+// at the global refresh rate, shrinks or grows with how often the feed
+// publishes (half the average gap between item dates, within 1/4x..8x of the
+// global rate), and is never shorter than the publisher's own hint.
+func refreshInterval(base time.Duration, override *int64, hint time.Duration, items []model.Item) time.Duration {
+	if override != nil && *override > 0 {
+		return time.Duration(*override) * time.Minute
+	}
+
+	interval := base
+	if gap := averageGap(items); gap > 0 {
+		lo := max(minAdaptiveInterval, base/4)
+		hi := min(maxAdaptiveInterval, base*8)
+		interval = min(max(gap/2, lo), hi)
+	}
+	return max(interval, hint)
+}
+
+// averageGap returns the average time between consecutive item dates,
+// or 0 if there are not enough distinct dates to tell.
+func averageGap(items []model.Item) time.Duration {
+	dates := make([]time.Time, 0, len(items))
+	for _, item := range items {
+		if !item.Date.IsZero() {
+			dates = append(dates, item.Date)
+		}
+	}
+	if len(dates) < 2 {
+		return 0
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return dates[len(dates)-1].Sub(dates[0]) / time.Duration(len(dates)-1)
+}
+
+// nextRefresh returns now+interval moved forward past any skip hours (GMT).
+func nextRefresh(now time.Time, interval time.Duration, skipHours []int) time.Time {
+	next := now.Add(interval)
+	if len(skipHours) == 0 || len(skipHours) >= 24 {
+		return next
+	}
+	for slices.Contains(skipHours, next.UTC().Hour()) {
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+// isDue reports whether a feed with the given state should be refreshed.
+func isDue(state *model.FeedState, now time.Time) bool {
+	if state != nil && state.Disabled {
+		return false
+	}
+	return state == nil || state.NextRefresh == nil || !state.NextRefresh.After(now)
+}
+
+const (
+	// upper bound for the delay between retries of a failing feed
+	maxBackoff = 24 * time.Hour
+	// feeds failing for longer than this are disabled
+	maxFailurePeriod = 7 * 24 * time.Hour
+)
+
+// backoff doubles the interval with each consecutive failure, up to
+// maxBackoff (or the interval itself, if that is longer).
+func backoff(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return max(min(delay, maxBackoff), interval)
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func itemsEvery(gap time.Duration, n int) []model.Item {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := make([]model.Item, n)
+	for i := range items {
+		items[i].Date = start.Add(time.Duration(i) * gap)
+	}
+	return items
+}
+
+func TestRefreshInterval(t *testing.T) {
+	base := time.Hour
+	override := int64(10)
+	testcases := []struct {
+		name     string
+		override *int64
+		hint     time.Duration
+		items    []model.Item
+		want     time.Duration
+	}{
+		{"no data", nil, 0, nil, base},
+		{"single item", nil, 0, itemsEvery(time.Minute, 1), base},
+		{"frequent posts", nil, 0, itemsEvery(time.Minute, 10), 15 * time.Minute},
+		{"moderate posts", nil, 0, itemsEvery(3*time.Hour, 5), 90 * time.Minute},
+		{"rare posts", nil, 0, itemsEvery(30*24*time.Hour, 3), 8 * time.Hour},
+		{"publisher hint", nil, 2 * time.Hour, itemsEvery(time.Minute, 10), 2 * time.Hour},
+		{"override", &override, 2 * time.Hour, itemsEvery(time.Minute, 10), 10 * time.Minute},
+	}
+	for _, tc := range testcases {
+		have := refreshInterval(base, tc.override, tc.hint, tc.items)
+		if have != tc.want {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, have)
+		}
+	}
+}
+
+func TestNextRefresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 21, 30, 0, 0, time.UTC)
+
+	if have := nextRefresh(now, time.Hour, nil); !have.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected %v", have)
+	}
+
+	// 22:30 falls into skipped 22 and 23, so the refresh moves to midnight
+	want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if have := nextRefresh(now, time.Hour, []int{22, 23}); !have.Equal(want) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// skip hours are in GMT regardless of the local zone
+	local := now.In(time.FixedZone("", 3*3600))
+	if have := nextRefresh(local, time.Hour, []int{22, 23}); !have.Equal(want) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	if !isDue(nil, now) || !isDue(&model.FeedState{}, now) {
+		t.Error("feeds without a schedule should be due")
+	}
+	if !isDue(&model.FeedState{NextRefresh: &past}, now) {
+		t.Error("expected overdue feed to be due")
+	}
+	if isDue(&model.FeedState{NextRefresh: &future}, now) {
+		t.Error("expected future feed not to be due")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	testcases := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{time.Hour, 1, time.Hour},
+		{time.Hour, 2, 2 * time.Hour},
+		{time.Hour, 4, 8 * time.Hour},
+		{time.Hour, 10, maxBackoff},
+		{48 * time.Hour, 3, 48 * time.Hour},
+	}
+	for _, tc := range testcases {
+		if have := backoff(tc.interval, tc.failures); have != tc.want {
+			t.Errorf("backoff(%v, %d): want %v, have %v", tc.interval, tc.failures, tc.want, have)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package worker
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nkanaev/yarr/src/content/htmlutil"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+const (
+	// number of delivery attempts per item
+	webhookAttempts = 3
+	// deliveries waiting to be sent, more are dropped
+	webhookQueueSize = 1000
+	// deliveries sent at the same time
+	webhookSenders = 4
+)
+
+// delay before the first retry, doubled after each failed attempt
+var webhookRetryDelay = time.Minute
+
+// Webhook is a model.Webhook compiled for delivery.
+type Webhook struct {
+	model.Webhook
+	// refuse to deliver to loopback, private and link-local addresses;
+	// set for the webhooks of non-admin users
+	PublicOnly bool
+	template   *template.Template
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// CompileWebhook validates the webhook. The template has access to
+// `.Feed` and `.Item` (see webhookPayload), and to the `json` function
+// for quoting values in JSON bodies, besides the text/template builtins.
+func CompileWebhook(hook model.Webhook) (*Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", hook.URL)
+	}
+	switch hook.Format {
+	case model.WebhookJSON, model.WebhookForm:
+	default:
+		return nil, fmt.Errorf("unknown format %q", hook.Format)
+	}
+
+	compiled := &Webhook{Webhook: hook}
+	if hook.Template != "" {
+		compiled.template, err = template.New("webhook").Funcs(webhookFuncs).Parse(hook.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// Match checks the keyword against the title and text of the item (case-insensitive).
+func (h *Webhook) Match(item model.Item) bool {
+	if h.Keyword == "" {
+		return true
+	}
+	keyword := strings.ToLower(h.Keyword)
+	return strings.Contains(strings.ToLower(item.Title), keyword) ||
+		strings.Contains(strings.ToLower(htmlutil.ExtractText(item.Content)), keyword)
+}
+
+type webhookFeed struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Link     string `json:"link"`
+	FeedLink string `json:"feed_link"`
+}
+
+type webhookItem struct {
+	ID      int64     `json:"id"`
+	GUID    string    `json:"guid"`
+	Title   string    `json:"title"`
+	Link    string    `json:"link"`
+	Date    time.Time `json:"date"`
+	Content string    `json:"content"`
+}
+
+// webhookPayload is the default JSON body, and the data passed to templates.
+type webhookPayload struct {
+	Event string      `json:"event"`
+	Feed  webhookFeed `json:"feed"`
+	Item  webhookItem `json:"item"`
+}
+
+func (h *Webhook) body(feed model.Feed, item model.Item) ([]byte, error) {
+	payload := webhookPayload{
+		Event: "item_created",
+		Feed:  webhookFeed{ID: feed.Id, Title: feed.Title, Link: feed.Link, FeedLink: feed.FeedLink},
+		Item: webhookItem{
+			ID: item.Id, GUID: item.GUID, Title: item.Title,
+			Link: item.Link, Date: item.Date, Content: item.Content,
+		},
+	}
+	if h.template != nil {
+		var buf bytes.Buffer
+		if err := h.template.Execute(&buf, payload); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if h.Format == model.WebhookForm {
+		form := url.Values{
+			"feed":    {feed.Title},
+			"title":   {item.Title},
+			"link":    {item.Link},
+			"date":    {item.Date.Format(time.RFC3339)},
+			"content": {item.Content},
+		}
+		return []byte(form.Encode()), nil
+	}
+	return json.Marshal(payload)
+}
+
+// Fire makes a single delivery attempt of the item, and returns
+// the response status code (0 if there was no response).
+func (h *Webhook) Fire(feed model.Feed, item model.Item) (int, error) {
+	body, err := h.body(feed, item)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", client.userAgent)
+	if h.Format == model.WebhookForm {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Yarr-Event", "item_created")
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Yarr-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	c := client
+	if h.PublicOnly {
+		c = publicClient
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, &statusError{StatusCode: res.StatusCode}
+	}
+	return res.StatusCode, nil
+}
+
+// webhookRetryable tells apart failures that may go away (no response,
+// server errors, rate limiting) from the ones that won't.
+func webhookRetryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// webhookDelivery is an attempt at delivering an item, waiting in the queue.
+type webhookDelivery struct {
+	hook    *Webhook
+	feed    model.Feed
+	item    model.Item
+	attempt int
+}
+
+// queueWebhook adds the delivery to the queue, which is emptied by a fixed
+// number of senders started along with it. The delivery is dropped if the
+// queue is full.
+func (w *Worker) queueWebhook(d webhookDelivery) {
+	w.webhookOnce.Do(func() {
+		w.webhookQueue = make(chan webhookDelivery, webhookQueueSize)
+		for range webhookSenders {
+			go func() {
+				for d := range w.webhookQueue {
+					w.deliverWebhook(d)
+				}
+			}()
+		}
+	})
+	select {
+	case w.webhookQueue <- d:
+	default:
+		log.Printf("Dropped item %d for webhook %d: too many pending deliveries", d.item.Id, d.hook.Id)
+	}
+}
+
+// deliverWebhook fires the webhook and logs the attempt. Failed attempts
+// that may succeed later are queued again after a delay, doubled each time,
+// until the attempts run out.
+func (w *Worker) deliverWebhook(d webhookDelivery) {
+	status, err := d.hook.Fire(d.feed, d.item)
+	delivery := model.WebhookDelivery{
+		WebhookID:  d.hook.Id,
+		ItemID:     &d.item.Id,
+		Attempt:    d.attempt,
+		StatusCode: status,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if _, err := w.db.CreateWebhookDelivery(delivery); err != nil {
+		log.Print(err)
+	}
+	if err == nil {
+		return
+	}
+	if !webhookRetryable(status) || d.attempt == webhookAttempts {
+		log.Printf("Failed to deliver item %d to webhook %d: %s", d.item.Id, d.hook.Id, err)
+		return
+	}
+	delay := webhookRetryDelay << (d.attempt - 1)
+	d.attempt++
+	time.AfterFunc(delay, func() { w.queueWebhook(d) })
+}
+
+// fireWebhooks queues the new items of the feed for the matching webhooks
+// of its owner. Items are queued in order, retries may deliver them out of it.
+func (w *Worker) fireWebhooks(feed model.Feed, items []model.Item) {
+	if len(items) == 0 {
+		return
+	}
+	hooks, err := w.db.ListFeedWebhooks(feed.Id)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	// the webhooks all belong to the owner of the feed
+	owner, err := w.db.GetUser(hooks[0].UserId)
+	if err != nil {
+		log.Print(err)
+	}
+	for _, hook := range hooks {
+		compiled, err := CompileWebhook(hook)
+		if err != nil {
+			log.Printf("Skipping webhook %d: %s", hook.Id, err)
+			continue
+		}
+		compiled.PublicOnly = owner == nil || !owner.IsAdmin
+		for _, item := range items {
+			if compiled.Match(item) {
+				w.queueWebhook(webhookDelivery{hook: compiled, feed: feed, item: item, attempt: 1})
+			}
+		}
+	}
+}
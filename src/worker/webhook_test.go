@@ -0,0 +1,176 @@
+package worker
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestCompileWebhook(t *testing.T) {
+	valid := model.Webhook{URL: "https://example.com/hook", Format: model.WebhookJSON}
+	if _, err := CompileWebhook(valid); err != nil {
+		t.Fatal(err)
+	}
+	for _, hook := range []model.Webhook{
+		{URL: "ftp://example.com/hook", Format: model.WebhookJSON},
+		{URL: "/hook", Format: model.WebhookJSON},
+		{URL: "https://example.com/hook", Format: "xml"},
+		{URL: "https://example.com/hook", Format: model.WebhookJSON, Template: "{{.Item.Title"},
+	} {
+		if _, err := CompileWebhook(hook); err == nil {
+			t.Errorf("expected error for %#v", hook)
+		}
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	feed := model.Feed{Id: 1, Title: "Feed"}
+	item := model.Item{Id: 2, Title: `say "hi"`, Link: "https://example.com/1?a=b"}
+
+	hook, _ := CompileWebhook(model.Webhook{
+		URL:      "https://example.com/hook",
+		Format:   model.WebhookJSON,
+		Template: `{"text": {{json .Item.Title}}, "feed": {{json .Feed.Title}}}`,
+	})
+	if body, _ := hook.body(feed, item); string(body) != `{"text": "say \"hi\"", "feed": "Feed"}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	hook, _ = CompileWebhook(model.Webhook{
+		URL:      "https://example.com/hook",
+		Format:   model.WebhookForm,
+		Template: `url={{urlquery .Item.Link}}`,
+	})
+	if body, _ := hook.body(feed, item); string(body) != `url=https%3A%2F%2Fexample.com%2F1%3Fa%3Db` {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	defer func(delay time.Duration) { webhookRetryDelay = delay }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	var calls atomic.Int32
+	received := make(chan string, 10)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get("X-Yarr-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("invalid signature: %q", r.Header.Get("X-Yarr-Signature"))
+		}
+		// the first attempt fails
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		received <- string(body)
+	}))
+	defer hookSrv.Close()
+
+	// deliveries are logged from other goroutines, i.e. other connections,
+	// which would each get a database of their own with ":memory:"
+	db, err := storage.New(filepath.Join(t.TempDir(), "yarr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Feed", FeedLink: "http://example.com/feed.xml"})
+	hook, err := db.CreateWebhook(model.Webhook{
+		URL:       hookSrv.URL,
+		Keyword:   "golang",
+		Format:    model.WebhookJSON,
+		Template:  `{{.Item.Title}}`,
+		Secret:    "secret",
+		IsEnabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWorker(db, nil)
+	w.createItems(feed.Id, []model.Item{
+		{GUID: "1", FeedId: feed.Id, Title: "Golang 2.0 released", Date: time.Now()},
+		{GUID: "2", FeedId: feed.Id, Title: "Something else", Date: time.Now()},
+	})
+	select {
+	case body := <-received:
+		if body != "Golang 2.0 released" {
+			t.Errorf("unexpected body: %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+
+	// existing items aren't delivered again
+	w.createItems(feed.Id, []model.Item{{GUID: "1", FeedId: feed.Id, Title: "Golang 2.0 released"}})
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+
+	// the last attempt is logged after the response
+	var deliveries []model.WebhookDelivery
+	for range 50 {
+		if deliveries, _ = db.ListWebhookDeliveries(hook.Id, 10); len(deliveries) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(deliveries) != 2 || deliveries[1].StatusCode != http.StatusBadGateway || deliveries[0].Attempt != 2 || deliveries[0].Error != "" {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+}
+
+func TestWebhookPublicOnly(t *testing.T) {
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hookSrv.Close()
+
+	hook, err := CompileWebhook(model.Webhook{URL: hookSrv.URL, Format: model.WebhookJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := hook.Fire(model.Feed{}, model.Item{}); err != nil || status != http.StatusNoContent {
+		t.Fatalf("expected delivery, got %d (%v)", status, err)
+	}
+	hook.PublicOnly = true
+	if status, err := hook.Fire(model.Feed{}, model.Item{}); !errors.Is(err, errNotPublic) || status != 0 {
+		t.Errorf("expected loopback address to be refused, got %d (%v)", status, err)
+	}
+
+	for address, public := range map[string]bool{
+		"93.184.216.34:443":     true,
+		"[2606:4700::1111]:443": true,
+		"127.0.0.1:80":          false,
+		"[::1]:80":              false,
+		"10.1.2.3:80":           false,
+		"172.16.0.1:80":         false,
+		"192.168.1.1:443":       false,
+		"169.254.169.254:80":    false,
+		"[fd00:ec2::254]:80":    false,
+		"[fe80::1]:80":          false,
+		"[::ffff:127.0.0.1]:80": false,
+		"0.0.0.0:80":            false,
+	} {
+		if err := dialPublic("tcp", address, nil); (err == nil) != public {
+			t.Errorf("%s: expected public %v, got %v", address, public, err)
+		}
+	}
+}
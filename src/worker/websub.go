@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkanaev/yarr/src/parser"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+const (
+	// lease requested from hubs, longer leases aren't accepted
+	WebSubLease = 10 * 24 * time.Hour
+	// subscriptions are renewed when they expire within this period
+	websubRenewBefore = 2 * time.Hour
+	// how long to wait for the hub to verify a subscription before retrying
+	websubRetry = 24 * time.Hour
+)
+
+// SetWebSubCallback enables WebSub push subscriptions for feeds that
+// advertise a hub. Hubs notify `{callback}/{feed_id}`.
+// Must be called before feeds are refreshed.
+func (w *Worker) SetWebSubCallback(callback string) {
+	w.websubCallback = callback
+}
+
+// WebSubPending reports whether the hub may verify (or deny) a request of
+// the mode: a subscription requested within the retry window and not yet
+// verified. Unsubscriptions never are, subscriptions go with their feeds.
+func WebSubPending(sub *model.WebSubSubscription, mode string, now time.Time) bool {
+	switch mode {
+	case "subscribe", "denied":
+		return !sub.RequestedAt.IsZero() && now.Sub(sub.RequestedAt) < websubRetry
+	}
+	return false
+}
+
+func websubActive(sub *model.WebSubSubscription, now time.Time) bool {
+	return sub != nil && sub.ExpiresAt != nil && sub.ExpiresAt.After(now)
+}
+
+// subscribeWebSub (re)subscribes the feed at its hub unless an active or
+// recently requested subscription exists, and returns the subscription.
+func (w *Worker) subscribeWebSub(feed model.Feed, parsed *parser.Feed) *model.WebSubSubscription {
+	sub, err := w.db.GetWebSubSubscription(feed.Id)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	if w.websubCallback == "" || parsed.HubURL == "" {
+		return sub
+	}
+
+	now := time.Now()
+	topic := parsed.SelfURL
+	if topic == "" {
+		topic = feed.FeedLink
+	}
+	if sub != nil && sub.Hub == parsed.HubURL && sub.Topic == topic {
+		if websubActive(sub, now.Add(websubRenewBefore)) {
+			return sub
+		}
+		// still waiting for the hub, be it a first subscription or a renewal
+		if WebSubPending(sub, "subscribe", now) {
+			return sub
+		}
+	} else {
+		sub = &model.WebSubSubscription{
+			FeedID: feed.Id,
+			Hub:    parsed.HubURL,
+			Topic:  topic,
+		}
+	}
+	// the secret would travel in plain text to hubs without TLS (WebSub §5.1),
+	// so their notifications aren't signed and only trigger a refresh
+	if !strings.HasPrefix(strings.ToLower(sub.Hub), "https://") {
+		sub.Secret = ""
+	} else if sub.Secret == "" {
+		sub.Secret = websubSecret()
+	}
+
+	// stored beforehand, hubs may verify the intent before responding
+	sub.RequestedAt = now
+	if _, err := w.db.UpdateWebSubSubscription(*sub); err != nil {
+		log.Print(err)
+		return nil
+	}
+	if err := websubRequest(sub, "subscribe", fmt.Sprintf("%s/%d", w.websubCallback, feed.Id)); err != nil {
+		log.Printf("Failed to subscribe to %s at %s: %s", topic, sub.Hub, err)
+	}
+	return sub
+}
+
+func websubRequest(sub *model.WebSubSubscription, mode, callback string) error {
+	form := url.Values{
+		"hub.mode":          {mode},
+		"hub.topic":         {sub.Topic},
+		"hub.callback":      {callback},
+		"hub.lease_seconds": {strconv.Itoa(int(WebSubLease / time.Second))},
+	}
+	if sub.Secret != "" {
+		form.Set("hub.secret", sub.Secret)
+	}
+	res, err := client.postForm(sub.Hub, form)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &statusError{StatusCode: res.StatusCode}
+	}
+	return nil
+}
+
+func websubSecret() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// IngestPush stores the items of a feed document pushed by a WebSub hub.
+func (w *Worker) IngestPush(feed model.Feed, body io.Reader, contentType string) error {
+	parsed, err := parser.ParseAndFix(body, feed.FeedLink, charsetOf(contentType))
+	if err != nil {
+		return err
+	}
+	items := ApplyFeedRules(w.db, feed, ConvertItems(parsed.Items, feed))
+	if len(items) > 0 {
+		w.createItems(feed.Id, items)
+	}
+	now := time.Now().UTC()
+	w.db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{LastRefreshed: &now})
+	return nil
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/parser"
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestSubscribeWebSub(t *testing.T) {
+	var requests atomic.Int32
+	var secrets atomic.Int32
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests.Add(1)
+		if _, ok := r.Form["hub.secret"]; ok {
+			secrets.Add(1)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorker(db, nil)
+	w.SetWebSubCallback("http://yarr.example.com/websub")
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Feed", FeedLink: "http://example.com/feed"})
+	parsed := &parser.Feed{HubURL: hub.URL}
+
+	sub := w.subscribeWebSub(*feed, parsed)
+	if sub == nil || requests.Load() != 1 {
+		t.Fatalf("expected a subscription request, got %d", requests.Load())
+	}
+	if sub.Secret != "" || secrets.Load() != 0 {
+		t.Error("expected no secret to be sent to a hub without TLS")
+	}
+
+	// a renewal awaiting verification isn't requested again
+	expired := time.Now().Add(-time.Hour)
+	sub.ExpiresAt = &expired
+	sub.RequestedAt = time.Now().Add(-time.Hour)
+	sub.Secret = "leftover"
+	db.UpdateWebSubSubscription(*sub)
+	w.subscribeWebSub(*feed, parsed)
+	if requests.Load() != 1 {
+		t.Errorf("expected pending renewal to be throttled, got %d requests", requests.Load())
+	}
+
+	sub.RequestedAt = time.Now().Add(-websubRetry - time.Minute)
+	db.UpdateWebSubSubscription(*sub)
+	sub = w.subscribeWebSub(*feed, parsed)
+	if requests.Load() != 2 {
+		t.Errorf("expected renewal after the retry window, got %d requests", requests.Load())
+	}
+	if sub.Secret != "" || secrets.Load() != 0 {
+		t.Error("expected stored secret to be dropped for a hub without TLS")
+	}
+}
@@ -1,11 +1,15 @@
 package worker
 
 import (
+	"errors"
 	"log"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/nkanaev/yarr/src/events"
+	"github.com/nkanaev/yarr/src/parser"
 	"github.com/nkanaev/yarr/src/storage"
 	"github.com/nkanaev/yarr/src/storage/model"
 )
@@ -18,11 +22,30 @@ type Worker struct {
 	refresh *time.Ticker
 	reflock sync.Mutex
 	stopper chan bool
+
+	// global refresh rate, the baseline for per-feed schedules
+	rate atomic.Int64
+
+	// base url of the WebSub callback; empty if push is disabled
+	websubCallback string
+
+	media     MediaConfig
+	mediaLock sync.Mutex
+
+	// webhook deliveries, see queueWebhook
+	webhookQueue chan webhookDelivery
+	webhookOnce  sync.Once
+
+	events *events.Bus
 }
 
-func NewWorker(db storage.Storage) *Worker {
+// used for scheduling when auto-refresh is disabled
+const defaultRefreshRate = time.Hour
+
+func NewWorker(db storage.Storage, bus *events.Bus) *Worker {
 	pending := int32(0)
-	return &Worker{db: db, pending: &pending}
+	// the worker refreshes the feeds of all users
+	return &Worker{db: storage.ForUser(db, 0), pending: &pending, events: bus}
 }
 
 func (w *Worker) FeedsPending() int32 {
@@ -30,16 +53,24 @@ func (w *Worker) FeedsPending() int32 {
 }
 
 func (w *Worker) StartFeedCleaner() {
-	go w.db.DeleteOldItems()
+	go w.cleanup()
 	ticker := time.NewTicker(time.Hour * 24)
 	go func() {
 		for {
 			<-ticker.C
-			w.db.DeleteOldItems()
+			w.cleanup()
 		}
 	}()
 }
 
+func (w *Worker) cleanup() {
+	w.db.DeleteOldItems()
+
+	w.mediaLock.Lock()
+	w.pruneMedia()
+	w.mediaLock.Unlock()
+}
+
 func (w *Worker) FindFeedFavicon(feed model.Feed) {
 	icon, err := findFavicon(feed.Link, feed.FeedLink)
 	if err != nil {
@@ -58,20 +89,21 @@ func (w *Worker) SetRefreshRate(minute int64) {
 		w.stopper = nil
 	}
 
+	w.rate.Store(int64(time.Minute * time.Duration(minute)))
 	if minute == 0 {
 		return
 	}
 
+	// feeds are scheduled individually; check for due ones every minute
 	w.stopper = make(chan bool)
-	w.refresh = time.NewTicker(time.Minute * time.Duration(minute))
+	w.refresh = time.NewTicker(time.Minute)
 
 	go func(fire <-chan time.Time, stop <-chan bool, m int64) {
 		log.Printf("auto-refresh %dm: starting", m)
 		for {
 			select {
 			case <-fire:
-				log.Printf("auto-refresh %dm: firing", m)
-				w.RefreshFeeds()
+				w.RefreshDueFeeds()
 			case <-stop:
 				log.Printf("auto-refresh %dm: stopping", m)
 				return
@@ -80,7 +112,8 @@ func (w *Worker) SetRefreshRate(minute int64) {
 	}(w.refresh.C, w.stopper, minute)
 }
 
-func (w *Worker) RefreshFeeds() {
+// RefreshFeeds refreshes the feeds of the user, or of all the users if 0.
+func (w *Worker) RefreshFeeds(userID int64) {
 	w.reflock.Lock()
 	defer w.reflock.Unlock()
 
@@ -89,7 +122,7 @@ func (w *Worker) RefreshFeeds() {
 		return
 	}
 
-	feeds := w.db.ListFeeds()
+	feeds := w.listFeeds(storage.ForUser(w.db, userID), false)
 	if len(feeds) == 0 {
 		log.Print("Nothing to refresh")
 		return
@@ -100,6 +133,67 @@ func (w *Worker) RefreshFeeds() {
 	go w.refresher(feeds)
 }
 
+// RefreshDueFeeds refreshes feeds whose scheduled refresh time has come.
+func (w *Worker) RefreshDueFeeds() {
+	w.reflock.Lock()
+	defer w.reflock.Unlock()
+
+	if *w.pending > 0 {
+		return
+	}
+
+	feeds := w.listFeeds(w.db, true)
+	if len(feeds) == 0 {
+		return
+	}
+
+	log.Printf("Refreshing %d due feeds", len(feeds))
+	atomic.StoreInt32(w.pending, int32(len(feeds)))
+	go w.refresher(feeds)
+}
+
+// listFeeds returns the feeds of the storage to refresh, skipping disabled
+// ones, and those not yet due if dueOnly is set.
+func (w *Worker) listFeeds(db storage.Storage, dueOnly bool) []model.Feed {
+	states, err := db.ListFeedStates()
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	statesByFeed := make(map[int64]*model.FeedState, len(states))
+	for i := range states {
+		statesByFeed[states[i].FeedID] = &states[i]
+	}
+
+	now := time.Now()
+	feeds := make([]model.Feed, 0)
+	for _, feed := range db.ListFeeds() {
+		state := statesByFeed[feed.Id]
+		if state != nil && state.Disabled {
+			continue
+		}
+		if dueOnly && !isDue(state, now) {
+			continue
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+func (w *Worker) RefreshFeed(feed model.Feed) {
+	w.reflock.Lock()
+	defer w.reflock.Unlock()
+
+	if *w.pending > 0 {
+		log.Print("Refreshing already in progress")
+		return
+	}
+
+	log.Printf("Refreshing feed %s", feed.FeedLink)
+	atomic.StoreInt32(w.pending, 1)
+	go w.refresher([]model.Feed{feed})
+}
+
 func (w *Worker) refresher(feeds []model.Feed) {
 	// w.db.ResetFeedErrors()
 
@@ -110,13 +204,17 @@ func (w *Worker) refresher(feeds []model.Feed) {
 		go w.worker(srcqueue, dstqueue)
 	}
 
+	w.events.Publish(events.Event{
+		Type: events.RefreshStarted,
+		Data: map[string]any{"feeds": len(feeds)},
+	})
 	for _, feed := range feeds {
 		srcqueue <- feed
 	}
 	for range feeds {
 		items := <-dstqueue
 		if len(items) > 0 {
-			w.db.CreateItems(items)
+			w.createItems(items[0].FeedId, items)
 		}
 		atomic.AddInt32(w.pending, -1)
 	}
@@ -124,6 +222,33 @@ func (w *Worker) refresher(feeds []model.Feed) {
 	close(dstqueue)
 
 	log.Printf("Finished refreshing %d feeds", len(feeds))
+	w.events.Publish(events.Event{
+		Type: events.RefreshFinished,
+		Data: map[string]any{"feeds": len(feeds)},
+	})
+}
+
+// createItems stores the items of the feed and announces the new ones.
+func (w *Worker) createItems(feedID int64, items []model.Item) {
+	created, _ := w.db.CreateItems(items)
+	if len(created) > 0 {
+		w.events.Publish(events.Event{
+			Type:   events.ItemsCreated,
+			FeedID: feedID,
+			Data:   map[string]any{"feed_id": feedID, "count": len(created)},
+		})
+		feed := w.db.GetFeed(feedID)
+		if feed == nil {
+			return
+		}
+		w.fireWebhooks(*feed, created)
+		if feed.FetchContent {
+			go w.fetchContents(created)
+		}
+		if feed.DownloadMedia {
+			go w.downloadMedia(created)
+		}
+	}
 }
 
 func (w *Worker) worker(srcqueue <-chan model.Feed, dstqueue chan<- []model.Item) {
@@ -131,14 +256,111 @@ func (w *Worker) worker(srcqueue <-chan model.Feed, dstqueue chan<- []model.Item
 		empty := ""
 		w.db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{LastError: &empty})
 
-		items, err := listItems(feed, w.db)
+		items, parsed, err := listItems(feed, w.db)
 		if err != nil {
 			errMsg := err.Error()
 			w.db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{LastError: &errMsg})
+			w.recordFailure(feed, err)
+			w.events.Publish(events.Event{
+				Type:   events.FeedError,
+				FeedID: feed.Id,
+				Data:   map[string]any{"feed_id": feed.Id, "error": errMsg},
+			})
+		} else {
+			w.scheduleFeed(feed, parsed, items)
 		}
+		items = ApplyFeedRules(w.db, feed, items)
 		if len(items) > 0 && feed.Icon == nil {
 			w.FindFeedFavicon(feed)
 		}
 		dstqueue <- items
 	}
 }
+
+// scheduleFeed stores when the feed is due next. Feeds that haven't changed
+// (or failed to load) keep the previously computed interval and skip hours.
+func (w *Worker) scheduleFeed(feed model.Feed, parsed *parser.Feed, items []model.Item) {
+	base := w.baseRate()
+
+	var interval time.Duration
+	var skipHours []int
+	var sub *model.WebSubSubscription
+	if parsed != nil {
+		interval = refreshInterval(base, feed.RefreshInterval, parsed.UpdateInterval, items)
+		skipHours = parsed.SkipHours
+		sub = w.subscribeWebSub(feed, parsed)
+	} else {
+		sub, _ = w.db.GetWebSubSubscription(feed.Id)
+		interval = refreshInterval(base, feed.RefreshInterval, 0, nil)
+		if state, _ := w.db.GetFeedState(feed.Id); state != nil {
+			if state.RefreshInterval > 0 && feed.RefreshInterval == nil {
+				interval = state.RefreshInterval
+			}
+			skipHours = state.SkipHours
+		}
+	}
+
+	// with push working, poll rarely, but soon enough to renew the lease
+	now := time.Now()
+	delay := interval
+	if websubActive(sub, now) {
+		untilRenewal := sub.ExpiresAt.Sub(now) - websubRenewBefore/2
+		delay = max(interval, min(maxAdaptiveInterval, untilRenewal))
+	}
+
+	next := nextRefresh(now, delay, skipHours)
+	failures, disabled := 0, false
+	w.db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{
+		NextRefresh:     &next,
+		RefreshInterval: &interval,
+		SkipHours:       &skipHours,
+		FailureCount:    &failures,
+		FirstFailure:    model.SetNullable[time.Time](nil),
+		Disabled:        &disabled,
+	})
+}
+
+// recordFailure backs off exponentially from the feed's usual interval,
+// waiting at least as long as the server asked to (Retry-After). Feeds that
+// are gone (HTTP 410) or keep failing for too long get disabled.
+func (w *Worker) recordFailure(feed model.Feed, err error) {
+	now := time.Now()
+	failures := 1
+	firstFailure := now
+	interval := refreshInterval(w.baseRate(), feed.RefreshInterval, 0, nil)
+	if state, _ := w.db.GetFeedState(feed.Id); state != nil {
+		failures = state.FailureCount + 1
+		if state.FirstFailure != nil {
+			firstFailure = *state.FirstFailure
+		}
+		if state.RefreshInterval > 0 && feed.RefreshInterval == nil {
+			interval = state.RefreshInterval
+		}
+	}
+
+	delay := backoff(interval, failures)
+	disabled := now.Sub(firstFailure) >= maxFailurePeriod
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		delay = max(delay, statusErr.RetryAfter)
+		disabled = disabled || statusErr.StatusCode == http.StatusGone
+	}
+	if disabled {
+		log.Printf("Disabling feed %s after %d failures: %s", feed.FeedLink, failures, err)
+	}
+
+	next := now.Add(delay)
+	w.db.UpdateFeedState(feed.Id, model.UpdateFeedStateParams{
+		NextRefresh:  &next,
+		FailureCount: &failures,
+		FirstFailure: model.SetNullable(&firstFailure),
+		Disabled:     &disabled,
+	})
+}
+
+func (w *Worker) baseRate() time.Duration {
+	if rate := time.Duration(w.rate.Load()); rate > 0 {
+		return rate
+	}
+	return defaultRefreshRate
+}
@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nkanaev/yarr/src/storage"
+	"github.com/nkanaev/yarr/src/storage/model"
+)
+
+func TestRefreshFailures(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var status atomic.Int32
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch code := int(status.Load()); code {
+		case http.StatusOK:
+			w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+		case http.StatusServiceUnavailable:
+			w.Header().Set("Retry-After", "7200")
+			w.WriteHeader(code)
+		default:
+			w.WriteHeader(code)
+		}
+	}))
+	defer feedSrv.Close()
+
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorker(db, nil)
+	w.SetRefreshRate(0)
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "Feed", FeedLink: feedSrv.URL})
+	refresh := func(code int) *model.FeedState {
+		status.Store(int32(code))
+		atomic.StoreInt32(w.pending, 1)
+		w.refresher([]model.Feed{*feed})
+		state, err := db.GetFeedState(feed.Id)
+		if err != nil || state == nil {
+			t.Fatalf("expected state, got %v (%v)", state, err)
+		}
+		return state
+	}
+
+	state := refresh(http.StatusInternalServerError)
+	if state.FailureCount != 1 || state.FirstFailure == nil || state.Disabled {
+		t.Fatalf("unexpected state after first failure: %#v", state)
+	}
+	firstFailure := *state.FirstFailure
+
+	state = refresh(http.StatusInternalServerError)
+	if state.FailureCount != 2 || !state.FirstFailure.Equal(firstFailure) {
+		t.Fatalf("unexpected state after second failure: %#v", state)
+	}
+	// default rate doubled
+	if delay := time.Until(*state.NextRefresh); delay < 110*time.Minute || delay > 2*time.Hour {
+		t.Errorf("expected backoff of 2h, got %v", delay)
+	}
+
+	state = refresh(http.StatusServiceUnavailable)
+	if state.FailureCount != 3 || state.LastError != "status code 503" {
+		t.Fatalf("unexpected state: %#v", state)
+	}
+	// backoff (4h) is longer than Retry-After (2h)
+	if delay := time.Until(*state.NextRefresh); delay < 230*time.Minute || delay > 4*time.Hour {
+		t.Errorf("expected backoff of 4h, got %v", delay)
+	}
+
+	state = refresh(http.StatusOK)
+	if state.FailureCount != 0 || state.FirstFailure != nil || state.LastError != "" {
+		t.Fatalf("expected failures to be reset: %#v", state)
+	}
+
+	state = refresh(http.StatusGone)
+	if !state.Disabled {
+		t.Fatalf("expected feed to be disabled: %#v", state)
+	}
+	if feeds := w.listFeeds(w.db, false); len(feeds) != 0 {
+		t.Errorf("expected disabled feed to be skipped, got %v", feeds)
+	}
+}
+
+func TestListFeedsPerUser(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := db.CreateUser(model.CreateUserParams{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := storage.ForUser(db, user.Id)
+	db.CreateFeed(model.CreateFeedParams{Title: "default", FeedLink: "http://example.com/default.xml"})
+	alice.CreateFeed(model.CreateFeedParams{Title: "alice", FeedLink: "http://example.com/alice.xml"})
+
+	w := NewWorker(db, nil)
+	if feeds := w.listFeeds(storage.ForUser(w.db, user.Id), false); len(feeds) != 1 || feeds[0].Title != "alice" {
+		t.Errorf("expected the feeds of the user only, got %v", feeds)
+	}
+	if feeds := w.listFeeds(w.db, false); len(feeds) != 2 {
+		t.Errorf("expected the feeds of all the users, got %v", feeds)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 01:00:00 GMT", time.Hour},
+		{"Sun, 31 Dec 2023 23:00:00 GMT", 0},
+		{"999999999", maxBackoff},
+		{"99999999999999999", maxBackoff},
+		{"Fri, 01 Jan 2100 00:00:00 GMT", maxBackoff},
+		{"soon", 0},
+	}
+	for _, tc := range testcases {
+		if have := retryAfter(tc.value, now); have != tc.want {
+			t.Errorf("%q: want %v, have %v", tc.value, tc.want, have)
+		}
+	}
+}
+
+func TestPermanentRedirects(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/older", http.StatusMovedPermanently))
+	mux.Handle("/older", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/temp", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/mixed", http.RedirectHandler("/temp", http.StatusMovedPermanently))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>Feed</title></channel></rss>`))
+	})
+	feedSrv := httptest.NewServer(mux)
+	defer feedSrv.Close()
+
+	testcases := []struct {
+		path   string
+		want   string
+		status int
+	}{
+		{"/old", "/feed", http.StatusPermanentRedirect},
+		{"/temp", "/temp", 0},
+		{"/mixed", "/temp", http.StatusMovedPermanently},
+		{"/feed", "/feed", 0},
+	}
+	for _, tc := range testcases {
+		db, err := storage.New(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		feed := db.CreateFeed(model.CreateFeedParams{Title: tc.path, FeedLink: feedSrv.URL + tc.path})
+		if _, _, err := listItems(*feed, db); err != nil {
+			t.Fatalf("%s: %s", tc.path, err)
+		}
+		if have := db.GetFeed(feed.Id).FeedLink; have != feedSrv.URL+tc.want {
+			t.Errorf("%s: want %s, have %s", tc.path, feedSrv.URL+tc.want, have)
+		}
+		changes, _ := db.ListFeedLinkChanges(feed.Id)
+		if tc.status == 0 {
+			if len(changes) != 0 {
+				t.Errorf("%s: expected no history, got %v", tc.path, changes)
+			}
+			continue
+		}
+		if len(changes) != 1 || changes[0].OldLink != feed.FeedLink ||
+			changes[0].NewLink != feedSrv.URL+tc.want || changes[0].StatusCode != tc.status {
+			t.Errorf("%s: unexpected history %v", tc.path, changes)
+		}
+	}
+}
+
+func TestListItemsScraped(t *testing.T) {
+	pageSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=windows-1252")
+		w.Write([]byte("<ul><li><a href=\"/a\">Caf\xe9</a></li><li><a href=\"/b\">Bar</a></li></ul>"))
+	}))
+	defer pageSrv.Close()
+
+	db, err := storage.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := db.CreateFeed(model.CreateFeedParams{Title: "page", FeedLink: pageSrv.URL})
+	db.UpdateFeedScraper(model.FeedScraper{FeedID: feed.Id, Item: "li"})
+
+	items, _, err := listItems(*feed, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "Café" || items[0].GUID != pageSrv.URL+"/a" || items[1].Link != pageSrv.URL+"/b" {
+		t.Errorf("unexpected items: %#v", items)
+	}
+}